@@ -2,6 +2,7 @@ package codf // import "go.spiff.io/codf"
 
 import (
 	"math/big"
+	"net/netip"
 	"sort"
 	"strings"
 	"time"
@@ -64,6 +65,8 @@ type Document struct {
 	Name string
 
 	Children []Node // Sections and Statements that make up the Document.
+
+	Comments Comments
 }
 
 func (*Document) astnode() {}
@@ -83,11 +86,19 @@ func (d *Document) String() string {
 }
 
 func (d *Document) format(prefix string) string {
-	strs := make([]string, len(d.Children))
-	for i, n := range d.Children {
-		strs[i] = n.format("")
+	strs := make([]string, 0, len(d.Children))
+	for _, n := range d.Children {
+		// Children are formatted with the prefix so that documents included in sections are
+		// indented. Empty included documents are skipped.
+		if str := n.format(prefix); str != "" {
+			strs = append(strs, str)
+		}
+	}
+	dangling := d.Comments.formatDangling(prefix)
+	if len(strs) == 0 {
+		dangling = strings.TrimPrefix(dangling, "\n"+prefix)
 	}
-	return prefix + strings.Join(strs, "\n"+prefix)
+	return strings.Join(strs, "\n") + dangling
 }
 
 // addChild adds a section or statement to the Document's children.
@@ -103,6 +114,8 @@ type Statement struct {
 	Params  []ExprNode
 
 	EndTok Token
+
+	Comments Comments
 }
 
 // Parameters returns the parameters the statement holds.
@@ -116,11 +129,13 @@ func (s *Statement) String() string {
 
 func (s *Statement) format(prefix string) string {
 	pieces := make([]string, len(s.Params)+1)
-	pieces[0] = s.Name()
+	pieces[0] = nameText(s.NameTok)
 	for i, p := range s.Params {
 		pieces[i+1] = p.format(prefix)
 	}
-	return prefix + strings.Join(pieces, " ") + ";"
+	return s.Comments.formatLeading(prefix) +
+		prefix + strings.Join(pieces, " ") + ";" +
+		s.Comments.formatTrailing()
 }
 
 func (*Statement) astparse() {}
@@ -130,6 +145,20 @@ func (s *Statement) addExpr(node ExprNode) error {
 	return nil
 }
 
+// nameText returns the text of a statement or section name. Names parsed with ParseQuotedNames are
+// written as quoted strings so that they can be parsed again.
+func nameText(name *Literal) string {
+	str, _ := String(name)
+	switch name.Tok.Kind {
+	case TString, TRawString:
+		if len(name.Tok.Raw) > 0 {
+			return string(name.Tok.Raw)
+		}
+		return string(NewString(str).Tok.Raw)
+	}
+	return str
+}
+
 // Name returns the name of the Statement.
 // For example, the statement "enable-gophers yes;" has the name "enable-gophers".
 func (s *Statement) Name() string {
@@ -151,6 +180,7 @@ func (s *Statement) promote() *Section {
 		NameTok:  s.NameTok,
 		Params:   s.Params,
 		Children: []Node{},
+		Comments: s.Comments,
 	}
 }
 
@@ -163,6 +193,8 @@ type Section struct {
 
 	StartTok Token
 	EndTok   Token
+
+	Comments Comments
 }
 
 // Nodes returns the child nodes of the section.
@@ -181,21 +213,22 @@ func (s *Section) String() string {
 
 func (s *Section) format(prefix string) string {
 	pieces := make([]string, len(s.Params)+2)
-	pieces[0] = s.Name()
+	pieces[0] = nameText(s.NameTok)
 	for i, p := range s.Params {
 		pieces[i+1] = p.format("")
 	}
 	pieces[len(pieces)-1] = "{"
-	lead := prefix + strings.Join(pieces, " ")
-	if len(s.Children) == 0 {
-		return lead + "}"
+	lead := s.Comments.formatLeading(prefix) + prefix + strings.Join(pieces, " ")
+	if len(s.Children) == 0 && len(s.Comments.Dangling) == 0 {
+		return lead + "}" + s.Comments.formatTrailing()
 	}
 	inner := prefix + "\t"
 	for _, ch := range s.Children {
 		lead += "\n" + ch.format(inner)
 	}
+	lead += s.Comments.formatDangling(inner)
 
-	return lead + "\n" + prefix + "}"
+	return lead + "\n" + prefix + "}" + s.Comments.formatTrailing()
 }
 
 func (*Section) astparse() {}
@@ -225,6 +258,9 @@ type Map struct {
 
 	// Elems is a map of the string keys to their key-value pairs.
 	Elems map[string]*MapEntry
+
+	// Comments holds any comments inside of the map as dangling comments.
+	Comments Comments
 }
 
 func (m *Map) String() string {
@@ -233,15 +269,24 @@ func (m *Map) String() string {
 
 func (m *Map) format(prefix string) string {
 	pairs := m.Pairs()
-	if len(pairs) == 0 {
+	if len(pairs) == 0 && len(m.Comments.Dangling) == 0 {
 		return "#{}"
 	}
-	pieces := make([]string, len(pairs))
+	// Comments are written before or after the entries they were next to in the source.
+	var b strings.Builder
+	b.WriteString("#{")
 	indent := prefix + "\t"
-	for i, p := range pairs {
-		pieces[i] = p.format(indent)
+	comments := m.Comments.Dangling
+	var prev ExprNode
+	for _, p := range pairs {
+		var between string
+		between, comments = formatBetween(comments, prev, p.Key, indent)
+		b.WriteString(between + "\n" + p.format(indent))
+		prev = p.Val
 	}
-	return "#{\n" + strings.Join(pieces, "\n") + "\n" + prefix + "}"
+	between, comments := formatBetween(comments, prev, nil, indent)
+	b.WriteString(between + (&Comments{Dangling: comments}).formatDangling(indent))
+	return b.String() + "\n" + prefix + "}"
 }
 
 func (m *Map) astnode() {}
@@ -276,6 +321,9 @@ type Array struct {
 	StartTok Token
 	EndTok   Token
 	Elems    []ExprNode
+
+	// Comments holds any comments inside of the array as dangling comments.
+	Comments Comments
 }
 
 func (a *Array) String() string {
@@ -283,7 +331,7 @@ func (a *Array) String() string {
 }
 
 func (a *Array) format(prefix string) string {
-	if len(a.Elems) == 0 {
+	if len(a.Elems) == 0 && len(a.Comments.Dangling) == 0 {
 		return "[]"
 	}
 	pieces := make([]string, len(a.Elems))
@@ -291,7 +339,25 @@ func (a *Array) format(prefix string) string {
 	for i, p := range a.Elems {
 		pieces[i] = p.format(indent)
 	}
-	return "[" + strings.Join(pieces, " ") + "]"
+	if len(a.Comments.Dangling) == 0 {
+		return "[" + strings.Join(pieces, " ") + "]"
+	}
+
+	// Comments run to the end of the line, so each element and the closing bracket are written on
+	// their own lines, with comments before or after the elements they were next to in the source.
+	var b strings.Builder
+	b.WriteString("[")
+	comments := a.Comments.Dangling
+	var prev ExprNode
+	for i, elem := range a.Elems {
+		var between string
+		between, comments = formatBetween(comments, prev, elem, indent)
+		b.WriteString(between + "\n" + indent + pieces[i])
+		prev = elem
+	}
+	between, comments := formatBetween(comments, prev, nil, indent)
+	b.WriteString(between + (&Comments{Dangling: comments}).formatDangling(indent))
+	return b.String() + "\n" + prefix + "]"
 }
 
 func (*Array) astparse() {}
@@ -375,7 +441,8 @@ func (l *Literal) Token() Token {
 
 // Value returns the literal's value.
 // Depending on the token, this can be a value of type string, boolean, *big.Int, *big.Float,
-// *big.Rat, time.Duration, or *regexp.Regexp.
+// *big.Rat, time.Duration, time.Time, Period, netip.Addr, netip.Prefix, netip.AddrPort, or
+// *regexp.Regexp. Byte sizes are *big.Int values.
 // The entire AST is invalid if this returns nil.
 func (l *Literal) Value() any {
 	return l.Tok.Value
@@ -401,10 +468,68 @@ func Regexp(node Node) (v *regexp.Regexp) {
 	return
 }
 
+// Addr returns the value held by node as a netip.Addr and true.
+// If the node doesn't hold an IP address, it returns the zero netip.Addr and false.
+func Addr(node Node) (v netip.Addr, ok bool) {
+	v, ok = Value(node).(netip.Addr)
+	return
+}
+
+// Prefix returns the value held by node as a netip.Prefix and true.
+// If the node doesn't hold an IP prefix, it returns the zero netip.Prefix and false.
+func Prefix(node Node) (v netip.Prefix, ok bool) {
+	v, ok = Value(node).(netip.Prefix)
+	return
+}
+
+// AddrPort returns the value held by node as a netip.AddrPort and true.
+// If the node doesn't hold an address and port pair, it returns the zero netip.AddrPort and false.
+func AddrPort(node Node) (v netip.AddrPort, ok bool) {
+	v, ok = Value(node).(netip.AddrPort)
+	return
+}
+
 // Duration returns the value held by node as a time.Duration and true.
-// If the node doesn't hold a duration, it returns 0 and false.
+// If the node holds a period without years or months, such as P30D, it returns the period's
+// Duration. If the node doesn't hold a duration, it returns 0 and false.
 func Duration(node Node) (v time.Duration, ok bool) {
-	v, ok = Value(node).(time.Duration)
+	switch x := Value(node).(type) {
+	case time.Duration:
+		return x, true
+	case Period:
+		return x.Duration()
+	}
+	return 0, false
+}
+
+// PeriodOf returns the value held by node as a Period and true. If the node holds a duration, it
+// returns a Period of that duration's Time. If the node doesn't hold a period or duration, it
+// returns the zero Period and false.
+func PeriodOf(node Node) (v Period, ok bool) {
+	switch x := Value(node).(type) {
+	case Period:
+		return x, true
+	case time.Duration:
+		return Period{Time: x}, true
+	}
+	return Period{}, false
+}
+
+// Time returns the value held by node as a time.Time and true.
+// If the node doesn't hold a date or timestamp, it returns the zero time.Time and false. Dates are
+// returned as midnight UTC on that date.
+func Time(node Node) (v time.Time, ok bool) {
+	v, ok = Value(node).(time.Time)
+	return
+}
+
+// ByteSize returns the number of bytes held by node if node is a byte size, such as 64mib.
+// If the node isn't a byte size, it returns nil. BigInt also returns the number of bytes of a byte
+// size, but does not distinguish byte sizes from integers.
+func ByteSize(node Node) (v *big.Int) {
+	if lit, isLit := node.(*Literal); isLit && lit.Tok.Kind == TByteSize {
+		v, _ = lit.Value().(*big.Int)
+	}
 	return
 }
 
@@ -424,8 +549,11 @@ func String(node Node) (str string, ok bool) {
 
 // Quote returns the string value of node if and only if node is a quoted string.
 func Quote(node Node) (str string, ok bool) {
-	if lit, isLit := node.(*Literal); isLit && (lit.Tok.Kind == TString || lit.Tok.Kind == TRawString) {
-		str, ok = lit.Value().(string)
+	if lit, isLit := node.(*Literal); isLit {
+		switch lit.Tok.Kind {
+		case TString, TRawString, THeredoc:
+			str, ok = lit.Value().(string)
+		}
 	}
 	return
 }
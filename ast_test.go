@@ -1,6 +1,12 @@
 package codf
 
-import "testing"
+import (
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestStringConversion(t *testing.T) {
 	exprs := mkexprs(
@@ -114,3 +120,93 @@ func TestInt64Conversion(t *testing.T) {
 		}
 	}
 }
+
+func TestByteSizeConversion(t *testing.T) {
+	doc := mustParse(t, "input 64mib 1024 64m;")
+	params := doc.Children[0].(*Statement).Params
+
+	if got, want := ByteSize(params[0]), big.NewInt(64<<20); got == nil || got.Cmp(want) != 0 {
+		t.Errorf("ByteSize(%v) = %v; want %v", params[0], got, want)
+	}
+	for _, p := range params[1:] {
+		if got := ByteSize(p); got != nil {
+			t.Errorf("ByteSize(%v) = %v; want nil", p, got)
+		}
+	}
+	if got := BigInt(params[0]); got == nil || got.Int64() != 64<<20 {
+		t.Errorf("BigInt(%v) = %v; want %d", params[0], got, 64<<20)
+	}
+}
+
+func TestTimeConversion(t *testing.T) {
+	doc := mustParse(t, "input 2026-10-16 2026-10-16T04:00:00Z 1h;")
+	params := doc.Children[0].(*Statement).Params
+
+	wants := []time.Time{
+		time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 16, 4, 0, 0, 0, time.UTC),
+	}
+	for i, want := range wants {
+		if got, ok := Time(params[i]); !ok || !got.Equal(want) {
+			t.Errorf("Time(%v) = %v, %t; want %v, true", params[i], got, ok, want)
+		}
+	}
+	if got, ok := Time(params[2]); ok {
+		t.Errorf("Time(%v) = %v, %t; want zero, false", params[2], got, ok)
+	}
+}
+
+func TestPeriodConversion(t *testing.T) {
+	doc := mustParse(t, "input P1Y2M P30D 1w 2026-10-16;")
+	params := doc.Children[0].(*Statement).Params
+
+	day := 24 * time.Hour
+	periods := []Period{{Years: 1, Months: 2}, {Days: 30}, {Time: 7 * day}}
+	for i, want := range periods {
+		if got, ok := PeriodOf(params[i]); !ok || got != want {
+			t.Errorf("PeriodOf(%v) = %v, %t; want %v, true", params[i], got, ok, want)
+		}
+	}
+	if got, ok := PeriodOf(params[3]); ok {
+		t.Errorf("PeriodOf(%v) = %v, %t; want zero, false", params[3], got, ok)
+	}
+
+	if got, ok := Duration(params[0]); ok {
+		t.Errorf("Duration(%v) = %v, %t; want 0, false", params[0], got, ok)
+	}
+	if got, ok := Duration(params[1]); !ok || got != 30*day {
+		t.Errorf("Duration(%v) = %v, %t; want %v, true", params[1], got, ok, 30*day)
+	}
+}
+
+func TestNetAddrConversion(t *testing.T) {
+	l := NewLexer(strings.NewReader("input 10.0.0.1 10.0.0.0/8 10.0.0.1:80 foo;"))
+	l.Flags = LexNetAddrs
+	p := NewParser()
+	if err := p.Parse(l); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	params := p.Document().Children[0].(*Statement).Params
+
+	if got, ok := Addr(params[0]); !ok || got != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("Addr(%v) = %v, %t; want 10.0.0.1, true", params[0], got, ok)
+	}
+	if got, ok := Prefix(params[1]); !ok || got != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("Prefix(%v) = %v, %t; want 10.0.0.0/8, true", params[1], got, ok)
+	}
+	if got, ok := AddrPort(params[2]); !ok || got != netip.MustParseAddrPort("10.0.0.1:80") {
+		t.Errorf("AddrPort(%v) = %v, %t; want 10.0.0.1:80, true", params[2], got, ok)
+	}
+
+	for i, p := range params {
+		if got, ok := Addr(p); ok && i != 0 {
+			t.Errorf("Addr(%v) = %v, %t; want zero, false", p, got, ok)
+		}
+		if got, ok := Prefix(p); ok && i != 1 {
+			t.Errorf("Prefix(%v) = %v, %t; want zero, false", p, got, ok)
+		}
+		if got, ok := AddrPort(p); ok && i != 2 {
+			t.Errorf("AddrPort(%v) = %v, %t; want zero, false", p, got, ok)
+		}
+	}
+}
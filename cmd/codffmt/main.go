@@ -0,0 +1,249 @@
+// Command codffmt formats codf documents.
+//
+// Usage:
+//
+//	codffmt [flags] [path ...]
+//
+// Without a path, codffmt formats standard input and writes the result to standard output. Given
+// a directory, it formats all files ending in .codf under it. By default, formatted files are
+// written to standard output.
+//
+// The flags are:
+//
+//	-d
+//		Do not print formatted documents. If a file's formatting differs from codffmt's, print
+//		a diff to standard output.
+//	-l
+//		Do not print formatted documents. If a file's formatting differs from codffmt's, print
+//		its name to standard output.
+//	-w
+//		Do not print formatted documents. If a file's formatting differs from codffmt's,
+//		overwrite it with the formatted version.
+//	-indent n
+//		Indent each level by n tabs, or n spaces with -spaces.
+//	-spaces
+//		Indent with spaces instead of tabs.
+//	-align
+//		Align the parameters of consecutive statements.
+//	-width n
+//		Wrap arrays, maps, and parameters that would make a line wider than n columns.
+//
+// The fromjson subcommand converts a JSON object to a codf document:
+//
+//	codffmt fromjson [flags] [path]
+//
+// It reads the JSON object from path, or standard input if no path is given, and writes the
+// converted document to standard output. Objects become sections, arrays of objects become
+// repeated sections, and other values become statement parameters (see jsonc.Converter). It
+// accepts the -indent, -spaces, -align, and -width flags above, as well as:
+//
+//	-maps
+//		Convert objects to maps instead of sections, other than the top-level object.
+//
+// To format a file named fromjson, pass its path as ./fromjson.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/3JoB/codf/jsonc"
+	"github.com/3JoB/codf/printer"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// formatter formats files according to the command's flags.
+type formatter struct {
+	cfg   printer.Config
+	list  bool
+	write bool
+	diff  bool
+
+	stdout io.Writer
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "fromjson" {
+		return runFromJSON(args[1:], stdin, stdout, stderr)
+	}
+
+	f := formatter{stdout: stdout}
+	flags := flag.NewFlagSet("codffmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: codffmt [flags] [path ...]")
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&f.list, "l", false, "list files whose formatting differs from codffmt's")
+	flags.BoolVar(&f.write, "w", false, "write result to (source) file instead of stdout")
+	flags.BoolVar(&f.diff, "d", false, "display diffs instead of rewriting files")
+	configFlags(flags, &f.cfg)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if f.write {
+			fmt.Fprintln(stderr, "codffmt: cannot use -w with standard input")
+			return 2
+		}
+		if err := f.process("<standard input>", stdin, false); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		return 0
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		if err := f.walk(path); err != nil {
+			fmt.Fprintln(stderr, err)
+			code = 2
+		}
+	}
+	return code
+}
+
+// configFlags adds the flags controlling the printer's output to flags.
+func configFlags(flags *flag.FlagSet, cfg *printer.Config) {
+	flags.IntVar(&cfg.Indent, "indent", 0, "indent each level by `n` tabs or spaces (default 1 tab or 4 spaces)")
+	flags.BoolVar(&cfg.UseSpaces, "spaces", false, "indent with spaces instead of tabs")
+	flags.BoolVar(&cfg.AlignParams, "align", false, "align the parameters of consecutive statements")
+	flags.IntVar(&cfg.MaxWidth, "width", 0, "wrap lines wider than `n` columns (default no limit)")
+}
+
+// runFromJSON runs the fromjson subcommand.
+func runFromJSON(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var cfg printer.Config
+	var conv jsonc.Converter
+	flags := flag.NewFlagSet("codffmt fromjson", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: codffmt fromjson [flags] [path]")
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&conv.Maps, "maps", false, "convert objects to maps instead of sections")
+	configFlags(flags, &cfg)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	name, in := "<standard input>", stdin
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		name = flags.Arg(0)
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		defer file.Close()
+		in = file
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	doc, err := conv.Convert(in)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return 2
+	}
+	if err := cfg.Fprint(stdout, doc); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// walk formats path, or all .codf files under path if it is a directory.
+func (f *formatter) walk(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return f.processFile(path)
+	}
+
+	var errs []string
+	err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".codf") || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		if err := f.processFile(path); err != nil {
+			errs = append(errs, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func (f *formatter) processFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f.process(path, file, true)
+}
+
+// process formats the document read from in. If isFile is true, name is the path of the file and
+// may be rewritten with -w.
+func (f *formatter) process(name string, in io.Reader, isFile bool) error {
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	res, err := f.cfg.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if !f.list && !f.write && !f.diff {
+		_, err = f.stdout.Write(res)
+		return err
+	}
+
+	if bytes.Equal(src, res) {
+		return nil
+	}
+
+	if f.list {
+		fmt.Fprintln(f.stdout, name)
+	}
+	if f.write && isFile {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, res, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if f.diff {
+		_, err = f.stdout.Write(diff(name+".orig", name, src, res))
+		return err
+	}
+	return nil
+}
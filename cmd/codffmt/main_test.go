@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	unformatted = "server   example.com {\nlisten 80;\n}\n"
+	formatted   = "server example.com {\n\tlisten 80;\n}\n"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut strings.Builder
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStdin(t *testing.T) {
+	code, out, errOut := runCommand(t, unformatted)
+	if code != 0 || out != formatted {
+		t.Errorf("codffmt = %d, %q, %q; want 0, %q", code, out, errOut, formatted)
+	}
+
+	code, out, errOut = runCommand(t, unformatted, "-spaces", "-indent", "2")
+	if want := "server example.com {\n  listen 80;\n}\n"; code != 0 || out != want {
+		t.Errorf("codffmt -spaces -indent 2 = %d, %q, %q; want 0, %q", code, out, errOut, want)
+	}
+
+	code, _, errOut = runCommand(t, "foo {")
+	if code != 2 || !strings.Contains(errOut, "<standard input>: ") {
+		t.Errorf("codffmt = %d, %q; want 2 and a parse error", code, errOut)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.codf":         unformatted,
+		"b.codf":         formatted,
+		"sub/c.codf":     unformatted,
+		"sub/ignored.md": unformatted,
+	})
+
+	code, out, errOut := runCommand(t, "", "-l", dir)
+	want := filepath.Join(dir, "a.codf") + "\n" + filepath.Join(dir, "sub", "c.codf") + "\n"
+	if code != 0 || out != want {
+		t.Errorf("codffmt -l = %d, %q, %q; want 0, %q", code, out, errOut, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.codf": unformatted})
+	path := filepath.Join(dir, "a.codf")
+
+	code, out, errOut := runCommand(t, "", "-w", path)
+	if code != 0 || out != "" {
+		t.Errorf("codffmt -w = %d, %q, %q; want 0 and no output", code, out, errOut)
+	}
+	if got, _ := os.ReadFile(path); string(got) != formatted {
+		t.Errorf("file = %q; want %q", got, formatted)
+	}
+
+	code, _, errOut = runCommand(t, unformatted, "-w")
+	if code != 2 || errOut == "" {
+		t.Errorf("codffmt -w with stdin = %d, %q; want 2 and an error", code, errOut)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.codf": unformatted})
+	path := filepath.Join(dir, "a.codf")
+
+	code, out, errOut := runCommand(t, "", "-d", path)
+	want := "--- " + path + ".orig\n+++ " + path + "\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		"-server   example.com {\n" +
+		"-listen 80;\n" +
+		"+server example.com {\n" +
+		"+\tlisten 80;\n" +
+		" }\n"
+	if code != 0 || out != want {
+		t.Errorf("codffmt -d = %d, %q, %q; want 0,\n%s", code, out, errOut, want)
+	}
+	if got, _ := os.ReadFile(path); string(got) != unformatted {
+		t.Errorf("file = %q; want unchanged", got)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	const src = `{"server": {"listen": 80, "names": ["a", "b"]}, "upstream": [{"host": "x"}, {"host": "y"}]}`
+
+	code, out, errOut := runCommand(t, src, "fromjson", "-spaces", "-indent", "2")
+	want := "server {\n  listen 80;\n  names [a b];\n}\nupstream {\n  host x;\n}\nupstream {\n  host y;\n}\n"
+	if code != 0 || out != want {
+		t.Errorf("codffmt fromjson = %d, %q, %q; want 0, %q", code, out, errOut, want)
+	}
+
+	dir := writeFiles(t, map[string]string{"a.json": src})
+	code, out, errOut = runCommand(t, "", "fromjson", "-maps", filepath.Join(dir, "a.json"))
+	want = "server #{listen 80 names [a b]};\nupstream [#{host x} #{host y}];\n"
+	if code != 0 || out != want {
+		t.Errorf("codffmt fromjson -maps = %d, %q, %q; want 0, %q", code, out, errOut, want)
+	}
+
+	code, _, errOut = runCommand(t, "[1]", "fromjson")
+	if code != 2 || !strings.Contains(errOut, "<standard input>: jsonc: expected a JSON object") {
+		t.Errorf("codffmt fromjson = %d, %q; want 2 and a conversion error", code, errOut)
+	}
+
+	code, _, _ = runCommand(t, "", "fromjson", "a.json", "b.json")
+	if code != 2 {
+		t.Errorf("codffmt fromjson a.json b.json = %d; want 2", code)
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14"
+	b := "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	const want = "--- a\n+++ b\n" +
+		"@@ -1,4 +1,4 @@\n" +
+		"-1\n" +
+		"+one\n" +
+		" 2\n" +
+		" 3\n" +
+		" 4\n" +
+		"@@ -11,4 +11,5 @@\n" +
+		" 11\n" +
+		" 12\n" +
+		" 13\n" +
+		"-14\n" +
+		"\\ No newline at end of file\n" +
+		"+14\n" +
+		"+15\n"
+	if got := string(diff("a", "b", []byte(a), []byte(b))); got != want {
+		t.Errorf("diff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := diff("a", "b", []byte(a), []byte(a)); got != nil {
+		t.Errorf("diff() of equal input = %q; want nil", got)
+	}
+}
@@ -0,0 +1,123 @@
+package codf // import "go.spiff.io/codf"
+
+import "strings"
+
+// Comment is a single '//' comment in a document.
+type Comment struct {
+	Tok Token
+}
+
+// NewComment returns a Comment with the given text. The text should not include the leading '//'
+// or any newlines.
+func NewComment(text string) *Comment {
+	return &Comment{
+		Tok: Token{
+			Kind:  TComment,
+			Raw:   []byte("//" + text),
+			Value: text,
+		},
+	}
+}
+
+// Text returns the text of the comment following its leading '//'.
+func (c *Comment) Text() string {
+	str, _ := c.Tok.Value.(string)
+	return str
+}
+
+func (c *Comment) String() string {
+	if c.Tok.Raw == nil {
+		return "//" + c.Text()
+	}
+	return string(c.Tok.Raw)
+}
+
+// Comments holds the comments attached to a node. Comments are only attached to nodes by a Parser
+// with the ParseComments flag set, but may also be added to nodes by hand.
+type Comments struct {
+	// Leading comments are written on the lines before a statement or section.
+	// Comments between the name of a statement or section and its end (a semicolon or opening
+	// brace) are also leading comments.
+	Leading []*Comment
+
+	// Trailing is a comment following a statement or section on the line it ends on.
+	Trailing *Comment
+
+	// Dangling comments are inside of a node but not attached to any of its children.
+	// These are comments following the last child of a section or document, and any comments
+	// inside of an array or map. Comments in an array or map are written back next to the
+	// elements they were written next to, using their locations.
+	Dangling []*Comment
+}
+
+// commented is implemented by nodes that comments can be attached to.
+type commented interface {
+	comments() *Comments
+}
+
+func (d *Document) comments() *Comments  { return &d.Comments }
+func (s *Statement) comments() *Comments { return &s.Comments }
+func (s *Section) comments() *Comments   { return &s.Comments }
+func (a *Array) comments() *Comments     { return &a.Comments }
+func (m *Map) comments() *Comments       { return &m.Comments }
+
+// formatLeading returns the node's leading comments, each on its own line at prefix and followed
+// by a newline.
+func (c *Comments) formatLeading(prefix string) string {
+	var b strings.Builder
+	for _, lc := range c.Leading {
+		b.WriteString(prefix + lc.String() + "\n")
+	}
+	return b.String()
+}
+
+// formatTrailing returns the node's trailing comment, if any, preceded by a space.
+func (c *Comments) formatTrailing() string {
+	if c.Trailing == nil {
+		return ""
+	}
+	return " " + c.Trailing.String()
+}
+
+// formatBetween returns the comments that come before the element next, or all comments with
+// locations if next is nil, each preceded by a newline and prefix, along with the remaining
+// comments. A comment on the line that the element prev ends on follows it on the same line.
+// Comments without locations are left for formatDangling.
+func formatBetween(comments []*Comment, prev, next ExprNode, prefix string) (string, []*Comment) {
+	var b strings.Builder
+	for len(comments) > 0 {
+		start := comments[0].Tok.Start
+		if start.Line == 0 || (next != nil && start.Offset >= next.Token().Start.Offset) {
+			break
+		}
+		if prev != nil && start.Line == exprEndLine(prev) {
+			b.WriteString(" ")
+		} else {
+			b.WriteString("\n" + prefix)
+		}
+		b.WriteString(comments[0].String())
+		comments = comments[1:]
+	}
+	return b.String(), comments
+}
+
+// exprEndLine returns the line that the expression node ends on.
+func exprEndLine(node ExprNode) int {
+	switch node := node.(type) {
+	case *Array:
+		return node.EndTok.Start.Line
+	case *Map:
+		return node.EndTok.Start.Line
+	}
+	return node.Token().End.Line
+}
+
+// formatDangling returns the node's dangling comments, each on its own line at prefix and
+// preceded by a newline.
+func (c *Comments) formatDangling(prefix string) string {
+	var b strings.Builder
+	for _, dc := range c.Dangling {
+		b.WriteString("\n" + prefix + dc.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,139 @@
+package codf
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseComments(t *testing.T, in string) *Document {
+	t.Helper()
+	p := NewParser()
+	p.Flags = ParseComments
+	if err := p.Parse(NewLexer(strings.NewReader(in))); err != nil {
+		t.Fatalf("Parse(..) error = %v; want nil", err)
+	}
+	return p.Document()
+}
+
+func commentTexts(comments []*Comment) []string {
+	texts := make([]string, len(comments))
+	for i, c := range comments {
+		texts[i] = c.Text()
+	}
+	return texts
+}
+
+func TestParseComments(t *testing.T) {
+	const src = `// Header comment
+// second line
+server go.spiff.io { // brace line
+	listen 80; // trailing
+	// leading proxy
+	proxy a {
+	}
+	// dangling server
+} // after server
+set [a // in array
+	b] #{
+	// in map
+	k v
+};
+foo a // in params
+	b;
+// end of file
+`
+
+	doc := parseComments(t, src)
+
+	check := func(name string, got []string, want ...string) {
+		t.Helper()
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("%s = %q; want %q", name, got, want)
+		}
+	}
+	trailing := func(c *Comment) []string {
+		if c == nil {
+			return nil
+		}
+		return []string{c.Text()}
+	}
+
+	server := doc.Children[0].(*Section)
+	listen := server.Children[0].(*Statement)
+	proxy := server.Children[1].(*Section)
+	set := doc.Children[1].(*Statement)
+	foo := doc.Children[2].(*Statement)
+
+	check("server.Leading", commentTexts(server.Comments.Leading), " Header comment", " second line")
+	check("server.Trailing", trailing(server.Comments.Trailing), " after server")
+	check("server.Dangling", commentTexts(server.Comments.Dangling), " dangling server")
+	check("listen.Leading", commentTexts(listen.Comments.Leading), " brace line")
+	check("listen.Trailing", trailing(listen.Comments.Trailing), " trailing")
+	check("proxy.Leading", commentTexts(proxy.Comments.Leading), " leading proxy")
+	check("proxy.Trailing", trailing(proxy.Comments.Trailing))
+	check("set.Leading", commentTexts(set.Comments.Leading))
+	check("array.Dangling", commentTexts(set.Params[0].(*Array).Comments.Dangling), " in array")
+	check("map.Dangling", commentTexts(set.Params[1].(*Map).Comments.Dangling), " in map")
+	check("foo.Leading", commentTexts(foo.Comments.Leading), " in params")
+	check("doc.Dangling", commentTexts(doc.Comments.Dangling), " end of file")
+
+	const want = `// Header comment
+// second line
+server go.spiff.io {
+	// brace line
+	listen 80; // trailing
+	// leading proxy
+	proxy a {}
+	// dangling server
+} // after server
+set [
+	a // in array
+	b
+] #{
+	// in map
+	k v
+};
+// in params
+foo a b;
+// end of file`
+	formatted := doc.String()
+	if formatted != want {
+		t.Fatalf("String() =\n%s\nwant\n%s", formatted, want)
+	}
+
+	// Formatting is stable once comments have been moved.
+	if again := parseComments(t, formatted).String(); again != formatted {
+		t.Errorf("String() after reparsing =\n%s\nwant\n%s", again, formatted)
+	}
+}
+
+func TestParseCommentsDisabled(t *testing.T) {
+	doc := mustParse(t, "// leading\nfoo; // trailing\n// dangling")
+	stmt := doc.Children[0].(*Statement)
+	if len(stmt.Comments.Leading) != 0 || stmt.Comments.Trailing != nil || len(doc.Comments.Dangling) != 0 {
+		t.Errorf("comments attached without ParseComments: %#v, %#v", stmt.Comments, doc.Comments)
+	}
+	if got, want := doc.String(), "foo;"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+	doc := &Document{
+		Children: []Node{NewStatement("foo", NewInt(1))},
+	}
+	stmt := doc.Children[0].(*Statement)
+	stmt.Comments.Leading = []*Comment{NewComment(" Foo is one.")}
+	stmt.Comments.Trailing = NewComment(" really")
+	doc.Comments.Dangling = []*Comment{NewComment("")}
+
+	const want = "// Foo is one.\nfoo 1; // really\n//"
+	if got := doc.String(); got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+
+	empty := &Document{Comments: Comments{Dangling: []*Comment{NewComment(" only")}}}
+	if got, want := empty.String(), "// only"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
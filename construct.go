@@ -0,0 +1,307 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"math/big"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/regexp"
+)
+
+// The New* functions construct AST nodes from Go values. Unlike a Literal built from only a Kind
+// and Value, nodes returned by these functions have a Raw token that is valid codf, so an AST
+// assembled from them can be written with String() and parsed back into an equivalent AST.
+// Tokens of constructed nodes have zero Start and End locations.
+
+func newLiteral(kind TokenKind, raw string, value any) *Literal {
+	return &Literal{
+		Tok: Token{
+			Kind:  kind,
+			Raw:   []byte(raw),
+			Value: value,
+		},
+	}
+}
+
+// NewWord returns a word Literal for s. If s cannot be written as a bareword (for example, because
+// it is empty, contains whitespace, or would be read as a number), NewWord returns a quoted string
+// Literal instead.
+func NewWord(s string) *Literal {
+	if isWord(s) {
+		return newLiteral(TWord, s, s)
+	}
+	return NewString(s)
+}
+
+// NewString returns a quoted string Literal for s.
+func NewString(s string) *Literal {
+	return newLiteral(TString, strconv.Quote(s), s)
+}
+
+// NewHeredoc returns a heredoc Literal for s using the delimiter delim, such as EOF. The Literal's
+// lines are not indented. NewHeredoc returns nil if delim is not a valid delimiter or if s cannot
+// be written with it, because a line of s would end the heredoc or ends in a carriage return.
+func NewHeredoc(delim, s string) *Literal {
+	if delim == "" || isDecimal(rune(delim[0])) {
+		return nil
+	}
+	for _, r := range delim {
+		if !isHeredocNameRune(r) {
+			return nil
+		}
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasSuffix(line, "\r") {
+			return nil
+		}
+		rest, isDelim := strings.CutPrefix(strings.TrimLeft(line, " \t"), delim)
+		if isDelim && (rest == "" || !isHeredocNameRune(rune(rest[0]))) {
+			return nil
+		}
+	}
+	return newLiteral(THeredoc, "<<"+delim+"\n"+s+"\n"+delim, s)
+}
+
+// NewBool returns a boolean Literal for b.
+func NewBool(b bool) *Literal {
+	return newLiteral(TBoolean, strconv.FormatBool(b), b)
+}
+
+// NewInt returns an integer Literal for i.
+func NewInt(i int64) *Literal {
+	return NewBigInt(big.NewInt(i))
+}
+
+// NewBigInt returns an integer Literal for a copy of x.
+func NewBigInt(x *big.Int) *Literal {
+	return newLiteral(TInteger, x.String(), new(big.Int).Set(x))
+}
+
+// NewFloat returns a float Literal for f. The Literal's value is a *big.Float with
+// DefaultPrecision, as produced by the Lexer for the literal's text. NewFloat returns nil if f is
+// infinite or NaN.
+func NewFloat(f float64) *Literal {
+	raw := floatText(strconv.FormatFloat(f, 'g', -1, 64))
+	v, _, err := big.ParseFloat(raw, 10, DefaultPrecision, big.ToNearestEven)
+	if err != nil {
+		return nil
+	}
+	return newLiteral(TFloat, raw, v)
+}
+
+// NewBigFloat returns a float Literal for a copy of f. NewBigFloat returns nil if f is infinite.
+func NewBigFloat(f *big.Float) *Literal {
+	if f.IsInf() {
+		return nil
+	}
+	return newLiteral(TFloat, floatText(f.Text('g', -1)), new(big.Float).Copy(f))
+}
+
+// floatText adds a fractional part to raw if it would otherwise be read as an integer. It also
+// removes the '+' and leading zeroes that Go writes in exponents, such as in 1e+06, since the
+// lexer reads those as words.
+func floatText(raw string) string {
+	mant, exp, ok := strings.Cut(raw, "e")
+	if !ok {
+		if strings.ContainsAny(raw, ".IN") {
+			return raw
+		}
+		return raw + ".0"
+	}
+	sign := ""
+	if strings.HasPrefix(exp, "-") {
+		sign = "-"
+	}
+	exp = strings.TrimLeft(exp, "+-0")
+	if exp == "" {
+		exp = "0"
+	}
+	return mant + "e" + sign + exp
+}
+
+// NewRat returns a rational Literal for a copy of r.
+func NewRat(r *big.Rat) *Literal {
+	return newLiteral(TRational, r.String(), new(big.Rat).Set(r))
+}
+
+// NewDuration returns a duration Literal for d.
+func NewDuration(d time.Duration) *Literal {
+	// The lexer only accepts 'us' and 'μs' (U+03BC) for microseconds, but Duration.String
+	// writes 'µs' (U+00B5).
+	raw := strings.ReplaceAll(d.String(), "µs", "us")
+	return newLiteral(TDuration, raw, d)
+}
+
+// NewPeriod returns a period Literal for p. The Literal's text is p's String. NewPeriod returns nil
+// if p has a negative component, since negative periods cannot be written as ISO 8601 durations.
+func NewPeriod(p Period) *Literal {
+	if p.IsNegative() {
+		return nil
+	}
+	return newLiteral(TPeriod, p.String(), p)
+}
+
+// NewAddr returns an IP address Literal for addr. NewAddr returns nil if addr is the zero Addr.
+func NewAddr(addr netip.Addr) *Literal {
+	if !addr.IsValid() {
+		return nil
+	}
+	return newLiteral(TAddr, addr.String(), addr)
+}
+
+// NewPrefix returns an IP prefix Literal for prefix. NewPrefix returns nil if prefix is not valid.
+func NewPrefix(prefix netip.Prefix) *Literal {
+	if !prefix.IsValid() {
+		return nil
+	}
+	return newLiteral(TPrefix, prefix.String(), prefix)
+}
+
+// NewAddrPort returns an address and port Literal for addrPort. NewAddrPort returns nil if
+// addrPort's address is the zero Addr. IPv6 addresses are written in brackets, such as [::1]:80,
+// which can only be read back with LexBracketWords.
+func NewAddrPort(addrPort netip.AddrPort) *Literal {
+	if !addrPort.IsValid() {
+		return nil
+	}
+	return newLiteral(TAddrPort, addrPort.String(), addrPort)
+}
+
+// NewTime returns a timestamp Literal for t. The Literal's text is t in RFC 3339 format with
+// fractional seconds, if any, so t's monotonic clock reading and location name are not kept.
+func NewTime(t time.Time) *Literal {
+	raw := t.Format(time.RFC3339Nano)
+	v, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		// t's year is outside of 0 to 9999, which cannot be written as a timestamp.
+		return nil
+	}
+	return newLiteral(TTimestamp, raw, v)
+}
+
+// NewDate returns a date Literal for the date of t in t's location. The Literal's value is
+// midnight UTC on that date, as produced by the Lexer.
+func NewDate(t time.Time) *Literal {
+	raw := t.Format(dateLayout)
+	v, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return nil
+	}
+	return newLiteral(TDate, raw, v)
+}
+
+// byteSizeScales are the byte size units written by NewByteSize, from largest to smallest.
+var byteSizeScales = []string{"tib", "tb", "gib", "gb", "mib", "mb", "kib", "kb", "b"}
+
+// NewByteSize returns a byte size Literal for a copy of x. The Literal's text uses the largest
+// unit, decimal or binary, that x is a whole multiple of, such as 64mib for 67108864.
+func NewByteSize(x *big.Int) *Literal {
+	var q, r big.Int
+	for _, unit := range byteSizeScales {
+		q.QuoRem(x, big.NewInt(byteSizeUnits[unit]), &r)
+		if r.Sign() == 0 && (q.Sign() != 0 || unit == "b") {
+			return newLiteral(TByteSize, q.String()+unit, new(big.Int).Set(x))
+		}
+	}
+	panic("unreachable")
+}
+
+// NewRegexp returns a regexp Literal for rx. Forward slashes in rx's source are escaped in the
+// Literal's text.
+func NewRegexp(rx *regexp.Regexp) *Literal {
+	var raw strings.Builder
+	raw.WriteString("#/")
+	src := rx.String()
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '/':
+			raw.WriteString(`\/`)
+		case c == '\\' && i+1 < len(src):
+			// Escapes other than \/ are kept as-is by the lexer, so only an escaped slash
+			// needs to be rewritten.
+			i++
+			if src[i] == '/' {
+				raw.WriteString(`\/`)
+			} else {
+				raw.WriteByte('\\')
+				raw.WriteByte(src[i])
+			}
+		default:
+			raw.WriteByte(c)
+		}
+	}
+	raw.WriteString("/")
+	return newLiteral(TRegexp, raw.String(), rx)
+}
+
+// NewArray returns an Array of elems.
+func NewArray(elems ...ExprNode) *Array {
+	if elems == nil {
+		elems = []ExprNode{}
+	}
+	return &Array{
+		StartTok: Token{Kind: TBracketOpen},
+		EndTok:   Token{Kind: TBracketClose},
+		Elems:    elems,
+	}
+}
+
+// NewMapEntry returns a MapEntry for the key-value pair of key and val. The key is written as
+// a word if possible and a quoted string otherwise.
+func NewMapEntry(key string, val ExprNode) *MapEntry {
+	return &MapEntry{Key: NewWord(key), Val: val}
+}
+
+// NewMap returns a Map of entries. The Ord field of each entry is set to its position in entries.
+// As with parsed maps, an entry replaces any previous entry with the same key.
+func NewMap(entries ...*MapEntry) *Map {
+	m := &Map{
+		StartTok: Token{Kind: TMapOpen},
+		EndTok:   Token{Kind: TCurlClose},
+		Elems:    make(map[string]*MapEntry, len(entries)),
+	}
+	for i, entry := range entries {
+		entry.Ord = uint(i)
+		m.Elems[entry.Name()] = entry
+	}
+	return m
+}
+
+// NewStatement returns a Statement with the given name and parameters. The name must be a valid
+// bareword for the statement to be parsed back.
+func NewStatement(name string, params ...ExprNode) *Statement {
+	return &Statement{
+		NameTok: newLiteral(TWord, name, name),
+		Params:  params,
+		EndTok:  Token{Kind: TSemicolon},
+	}
+}
+
+// NewSection returns a Section with the given name, parameters, and children. The name must be
+// a valid bareword for the section to be parsed back.
+func NewSection(name string, params []ExprNode, children ...Node) *Section {
+	if children == nil {
+		children = []Node{}
+	}
+	return &Section{
+		NameTok:  newLiteral(TWord, name, name),
+		Params:   params,
+		StartTok: Token{Kind: TCurlOpen},
+		EndTok:   Token{Kind: TCurlClose},
+		Children: children,
+	}
+}
+
+// isWord returns true if s would be lexed as a single bareword with the value s.
+func isWord(s string) bool {
+	lex := NewLexer(strings.NewReader(s))
+	tok, err := lex.ReadToken()
+	if err != nil || tok.Kind != TWord || tok.Value != s {
+		return false
+	}
+	tok, err = lex.ReadToken()
+	return err == nil && tok.Kind == TEOF
+}
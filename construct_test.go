@@ -0,0 +1,187 @@
+package codf
+
+import (
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/regexp"
+)
+
+func TestConstructLiterals(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-1234567890123456789012345678901234567890", 10)
+
+	cases := []struct {
+		name string
+		lit  *Literal
+		kind TokenKind
+		raw  string
+	}{
+		{"Word", NewWord("foo.bar/baz"), TWord, "foo.bar/baz"},
+		{"WordNeedsQuotes", NewWord("two words"), TString, `"two words"`},
+		{"WordNumeric", NewWord("123"), TString, `"123"`},
+		{"WordEmpty", NewWord(""), TString, `""`},
+		{"WordBool", NewWord("true"), TString, `"true"`},
+		{"String", NewString("a \"b\"\n\tc\x00"), TString, `"a \"b\"\n\tc\x00"`},
+		{"Bool", NewBool(false), TBoolean, "false"},
+		{"Int", NewInt(-42), TInteger, "-42"},
+		{"BigInt", NewBigInt(huge), TInteger, huge.String()},
+		{"Float", NewFloat(0.1), TFloat, "0.1"},
+		{"FloatWhole", NewFloat(3), TFloat, "3.0"},
+		{"FloatExp", NewFloat(1e-30), TFloat, "1e-30"},
+		{"FloatExpLarge", NewFloat(1e6), TFloat, "1e6"},
+		{"FloatExpSmall", NewFloat(1e-5), TFloat, "1e-5"},
+		{"FloatExpFrac", NewFloat(1.5e6), TFloat, "1.5e6"},
+		{"FloatExpNeg", NewFloat(-2.5e-7), TFloat, "-2.5e-7"},
+		{"BigFloatExp", NewBigFloat(big.NewFloat(1e21)), TFloat, "1e21"},
+		{"BigFloat", NewBigFloat(big.NewFloat(-2.5)), TFloat, "-2.5"},
+		{"Rat", NewRat(big.NewRat(-3, 4)), TRational, "-3/4"},
+		{"RatWhole", NewRat(big.NewRat(6, 2)), TRational, "3/1"},
+		{"Duration", NewDuration(90 * time.Minute), TDuration, "1h30m0s"},
+		{"DurationMicro", NewDuration(1500 * time.Nanosecond), TDuration, "1.5us"},
+		{"DurationNeg", NewDuration(-time.Second), TDuration, "-1s"},
+		{"Period", NewPeriod(Period{Years: 1, Months: 2, Days: 3, Time: 4*time.Hour + 1500*time.Millisecond}), TPeriod, "P1Y2M3DT4H1.5S"},
+		{"PeriodZero", NewPeriod(Period{}), TPeriod, "PT0S"},
+		{"Time", NewTime(time.Date(2026, 10, 16, 4, 0, 0, 5e8, time.UTC)), TTimestamp, "2026-10-16T04:00:00.5Z"},
+		{"TimeZone", NewTime(time.Date(2026, 10, 16, 4, 0, 0, 0, time.FixedZone("", -7*3600))), TTimestamp, "2026-10-16T04:00:00-07:00"},
+		{"Date", NewDate(time.Date(2026, 10, 16, 23, 0, 0, 0, time.FixedZone("", 3600))), TDate, "2026-10-16"},
+		{"ByteSize", NewByteSize(big.NewInt(64 << 20)), TByteSize, "64mib"},
+		{"ByteSizeDecimal", NewByteSize(big.NewInt(3000)), TByteSize, "3kb"},
+		{"ByteSizeBytes", NewByteSize(big.NewInt(1500)), TByteSize, "1500b"},
+		{"ByteSizeZero", NewByteSize(big.NewInt(0)), TByteSize, "0b"},
+		{"ByteSizeNeg", NewByteSize(big.NewInt(-2e9)), TByteSize, "-2gb"},
+		{"Heredoc", NewHeredoc("EOF", "a\n  b\n\nEOFX"), THeredoc, "<<EOF\na\n  b\n\nEOFX\nEOF"},
+		{"HeredocEmpty", NewHeredoc("SQL", ""), THeredoc, "<<SQL\n\nSQL"},
+		{"Regexp", NewRegexp(regexp.MustCompile(`^/a\/b/\d+$`)), TRegexp, `#/^\/a\/b\/\d+$/`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.lit.Tok.Kind != c.kind {
+				t.Errorf("Kind = %v; want %v", c.lit.Tok.Kind, c.kind)
+			}
+			if got := string(c.lit.Tok.Raw); got != c.raw {
+				t.Errorf("Raw = %q; want %q", got, c.raw)
+			}
+
+			doc := mustParse(t, NewStatement("lit", c.lit).String())
+			got := doc.Children[0].(*Statement).Params[0].(*Literal)
+			if got.Tok.Kind != c.kind {
+				t.Errorf("parsed Kind = %v; want %v", got.Tok.Kind, c.kind)
+			}
+			if c.kind == TRegexp {
+				// Regexps are compared by source. The escaped slash in the original
+				// is parsed as a plain slash.
+				if want := `^/a/b/\d+$`; got.Value().(*regexp.Regexp).String() != want {
+					t.Errorf("parsed regexp = %v; want %v", got.Value(), want)
+				}
+				return
+			}
+			objectsEqual(t, "", got, c.lit)
+		})
+	}
+}
+
+func TestConstructHeredocInvalid(t *testing.T) {
+	cases := []struct {
+		name, delim, s string
+	}{
+		{"EmptyDelim", "", "a"},
+		{"NumericDelim", "1EOF", "a"},
+		{"BadDelim", "E-OF", "a"},
+		{"EndsEarly", "EOF", "a\nEOF\nb"},
+		{"EndsEarlyIndented", "EOF", "a\n\t EOF;"},
+		{"CarriageReturn", "EOF", "a\r\nb"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if lit := NewHeredoc(c.delim, c.s); lit != nil {
+				t.Errorf("NewHeredoc(%q, %q) = %v; want nil", c.delim, c.s, lit)
+			}
+		})
+	}
+}
+
+func TestConstructNetAddrs(t *testing.T) {
+	cases := []struct {
+		name string
+		lit  *Literal
+		kind TokenKind
+		raw  string
+	}{
+		{"Addr", NewAddr(netip.MustParseAddr("10.0.0.1")), TAddr, "10.0.0.1"},
+		{"Addr6", NewAddr(netip.MustParseAddr("2001:0db8::0001")), TAddr, "2001:db8::1"},
+		{"AddrZone", NewAddr(netip.MustParseAddr("fe80::1%eth0")), TAddr, "fe80::1%eth0"},
+		{"Prefix", NewPrefix(netip.MustParsePrefix("10.0.0.0/8")), TPrefix, "10.0.0.0/8"},
+		{"Prefix6", NewPrefix(netip.MustParsePrefix("2001:db8::/32")), TPrefix, "2001:db8::/32"},
+		{"AddrPort", NewAddrPort(netip.MustParseAddrPort("0.0.0.0:80")), TAddrPort, "0.0.0.0:80"},
+		{"AddrPort6", NewAddrPort(netip.MustParseAddrPort("[::1]:8080")), TAddrPort, "[::1]:8080"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.lit.Tok.Kind != c.kind {
+				t.Errorf("Kind = %v; want %v", c.lit.Tok.Kind, c.kind)
+			}
+			if got := string(c.lit.Tok.Raw); got != c.raw {
+				t.Errorf("Raw = %q; want %q", got, c.raw)
+			}
+
+			l := NewLexer(strings.NewReader(c.raw))
+			l.Flags = LexNetAddrs | LexBracketWords
+			tok, err := l.ReadToken()
+			if err != nil {
+				t.Fatalf("ReadToken() error = %v", err)
+			}
+			if tok.Kind != c.kind || tok.Value != c.lit.Tok.Value {
+				t.Errorf("ReadToken() = %v %v; want %v %v", tok.Kind, tok.Value, c.kind, c.lit.Tok.Value)
+			}
+		})
+	}
+
+	if lit := NewPeriod(Period{Time: -time.Hour}); lit != nil {
+		t.Errorf("NewPeriod(negative) = %v; want nil", lit)
+	}
+	if lit := NewAddr(netip.Addr{}); lit != nil {
+		t.Errorf("NewAddr(zero) = %v; want nil", lit)
+	}
+	if lit := NewPrefix(netip.Prefix{}); lit != nil {
+		t.Errorf("NewPrefix(zero) = %v; want nil", lit)
+	}
+	if lit := NewAddrPort(netip.AddrPort{}); lit != nil {
+		t.Errorf("NewAddrPort(zero) = %v; want nil", lit)
+	}
+}
+
+func TestConstructDocument(t *testing.T) {
+	want := &Document{
+		Children: []Node{
+			NewStatement("enabled", NewBool(true)),
+			NewSection("server", []ExprNode{NewWord("go.spiff.io")},
+				NewStatement("listen", NewWord("0.0.0.0:80"), NewInt(443)),
+				NewStatement("root", NewString("/var/www/my site")),
+				NewSection("empty", nil),
+				NewStatement("headers", NewMap(
+					NewMapEntry("x-foo", NewString("bar")),
+					NewMapEntry("key with space", NewArray()),
+					NewMapEntry("nested", NewArray(NewFloat(1.5), NewRat(big.NewRat(1, 3)))),
+				)),
+			),
+			NewStatement("timeout", NewDuration(5*time.Second)),
+			NewStatement("empty", NewMap()),
+		},
+	}
+
+	src := want.String()
+	if !strings.Contains(src, `"key with space" []`) {
+		t.Errorf("String() =\n%s\nwant quoted map key", src)
+	}
+
+	got := mustParse(t, src)
+	objectsEqual(t, "", got, want)
+	if again := got.String(); again != src {
+		t.Errorf("String() after parsing =\n%s\nwant\n%s", again, src)
+	}
+}
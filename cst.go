@@ -0,0 +1,261 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"bytes"
+	"io"
+)
+
+// SyntaxKind identifies the kind of a SyntaxNode in a concrete syntax tree.
+type SyntaxKind int
+
+const (
+	// SyntaxDocument is the root of a concrete syntax tree.
+	SyntaxDocument SyntaxKind = iota
+	// SyntaxStatement is a statement, from its name to its semicolon.
+	SyntaxStatement
+	// SyntaxSection is a section, from its name to its closing brace.
+	SyntaxSection
+	// SyntaxArray is an array, from its opening to its closing bracket.
+	SyntaxArray
+	// SyntaxMap is a map, from its opening '#{' to its closing brace.
+	SyntaxMap
+)
+
+var syntaxKindNames = map[SyntaxKind]string{
+	SyntaxDocument:  "document",
+	SyntaxStatement: "statement",
+	SyntaxSection:   "section",
+	SyntaxArray:     "array",
+	SyntaxMap:       "map",
+}
+
+func (k SyntaxKind) String() string {
+	if s, ok := syntaxKindNames[k]; ok {
+		return s
+	}
+	return "invalid"
+}
+
+// Syntax is an element of a concrete syntax tree: either a *SyntaxNode or a *SyntaxToken.
+type Syntax interface {
+	// Bytes returns the source text of the element.
+	Bytes() []byte
+
+	syntax()
+}
+
+// SyntaxToken is a single token in a concrete syntax tree, including whitespace, comments, and
+// punctuation.
+type SyntaxToken struct {
+	Tok Token
+}
+
+func (*SyntaxToken) syntax() {}
+
+// punctuation is the source text of tokens that the Lexer does not keep Raw text for.
+var punctuation = map[TokenKind]string{
+	TSemicolon:    ";",
+	TCurlOpen:     "{",
+	TCurlClose:    "}",
+	TBracketOpen:  "[",
+	TBracketClose: "]",
+	TMapOpen:      "#{",
+}
+
+// Bytes returns the source text of the token.
+func (t *SyntaxToken) Bytes() []byte {
+	if t.Tok.Raw == nil {
+		return []byte(punctuation[t.Tok.Kind])
+	}
+	return t.Tok.Raw
+}
+
+// SyntaxNode is a node of a concrete syntax tree. Unlike the AST built by a Parser, a concrete
+// syntax tree keeps every token read from its source, including whitespace, comments, and
+// semicolons, so that the source can be reproduced exactly by SyntaxNode.Bytes.
+//
+// Whitespace and comments between statements and sections belong to the enclosing document or
+// section. Whitespace and comments inside of a statement, array, or map belong to it.
+type SyntaxNode struct {
+	Kind     SyntaxKind
+	Children []Syntax
+}
+
+func (*SyntaxNode) syntax() {}
+
+// Bytes returns the source text of the node and its children.
+func (n *SyntaxNode) Bytes() []byte {
+	var buf bytes.Buffer
+	n.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+func (n *SyntaxNode) String() string {
+	return string(n.Bytes())
+}
+
+// WriteTo writes the source text of the node to w.
+func (n *SyntaxNode) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, tok := range n.Tokens() {
+		wn, err := w.Write((&SyntaxToken{Tok: tok}).Bytes())
+		total += int64(wn)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Tokens returns all tokens under the node in source order.
+func (n *SyntaxNode) Tokens() []Token {
+	var toks []Token
+	var walk func(*SyntaxNode)
+	walk = func(n *SyntaxNode) {
+		for _, child := range n.Children {
+			switch child := child.(type) {
+			case *SyntaxToken:
+				toks = append(toks, child.Tok)
+			case *SyntaxNode:
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	return toks
+}
+
+// Token returns the first token under the node. For statements and sections, this is the name
+// token.
+func (n *SyntaxNode) Token() Token {
+	for _, child := range n.Children {
+		switch child := child.(type) {
+		case *SyntaxToken:
+			return child.Tok
+		case *SyntaxNode:
+			return child.Token()
+		}
+	}
+	return noToken
+}
+
+// Document parses the tokens of the node as a Document. The tokens of nodes in the returned
+// Document are the same as those in the concrete syntax tree. Quoted statement and section names
+// are accepted, as with ParseQuotedNames.
+func (n *SyntaxNode) Document() (*Document, error) {
+	p := NewParser()
+	p.Flags = ParseQuotedNames
+	if err := p.Parse(&tokenSlice{toks: n.Tokens()}); err != nil {
+		return nil, err
+	}
+	return p.Document(), nil
+}
+
+// ParseSyntax consumes tokens from a TokenReader, as Parse does, and returns a concrete syntax
+// tree of the tokens read. The Parser's Document is updated as with Parse, so it can be used as
+// the AST of the returned tree.
+func (p *Parser) ParseSyntax(tr TokenReader) (*SyntaxNode, error) {
+	b := &syntaxBuilder{
+		tr:          tr,
+		root:        &SyntaxNode{Kind: SyntaxDocument},
+		quotedNames: !p.Flags.none(ParseQuotedNames),
+	}
+	if err := p.Parse(b); err != nil {
+		return nil, err
+	}
+	return b.root, nil
+}
+
+// ParseSyntax parses a concrete syntax tree from r.
+func ParseSyntax(r io.Reader) (*SyntaxNode, error) {
+	return NewParser().ParseSyntax(NewLexer(r))
+}
+
+// syntaxBuilder is a TokenReader that builds a concrete syntax tree from the tokens it reads. It
+// relies on the Parser reading from it to reject malformed token sequences.
+type syntaxBuilder struct {
+	tr    TokenReader
+	root  *SyntaxNode
+	stack []*SyntaxNode
+
+	// quotedNames is true if the Parser accepts quoted statement and section names.
+	quotedNames bool
+}
+
+func (b *syntaxBuilder) ReadToken() (Token, error) {
+	tok, err := b.tr.ReadToken()
+	if err == nil {
+		b.add(tok)
+	}
+	return tok, err
+}
+
+func (b *syntaxBuilder) top() *SyntaxNode {
+	if len(b.stack) == 0 {
+		return b.root
+	}
+	return b.stack[len(b.stack)-1]
+}
+
+func (b *syntaxBuilder) push(kind SyntaxKind, tok Token) {
+	node := &SyntaxNode{Kind: kind, Children: []Syntax{&SyntaxToken{Tok: tok}}}
+	b.top().Children = append(b.top().Children, node)
+	b.stack = append(b.stack, node)
+}
+
+func (b *syntaxBuilder) pop() {
+	if len(b.stack) > 0 {
+		b.stack = b.stack[:len(b.stack)-1]
+	}
+}
+
+func (b *syntaxBuilder) add(tok Token) {
+	top := b.top()
+	switch top.Kind {
+	case SyntaxDocument, SyntaxSection:
+		// A section on the stack has already consumed its opening brace, so both documents and
+		// sections are in a body here.
+		if tok.Kind == TWord || (b.quotedNames && (tok.Kind == TString || tok.Kind == TRawString)) {
+			b.push(SyntaxStatement, tok)
+			return
+		}
+		top.Children = append(top.Children, &SyntaxToken{Tok: tok})
+		if tok.Kind == TCurlClose && top.Kind == SyntaxSection {
+			b.pop()
+		}
+		return
+	}
+
+	switch tok.Kind {
+	case TBracketOpen:
+		b.push(SyntaxArray, tok)
+		return
+	case TMapOpen:
+		b.push(SyntaxMap, tok)
+		return
+	}
+
+	top.Children = append(top.Children, &SyntaxToken{Tok: tok})
+	switch {
+	case top.Kind == SyntaxStatement && tok.Kind == TSemicolon,
+		top.Kind == SyntaxArray && tok.Kind == TBracketClose,
+		top.Kind == SyntaxMap && tok.Kind == TCurlClose:
+		b.pop()
+	case top.Kind == SyntaxStatement && tok.Kind == TCurlOpen:
+		top.Kind = SyntaxSection
+	}
+}
+
+// tokenSlice is a TokenReader that reads from a slice of tokens.
+type tokenSlice struct {
+	toks []Token
+}
+
+func (s *tokenSlice) ReadToken() (Token, error) {
+	if len(s.toks) == 0 {
+		return Token{Kind: TEOF}, nil
+	}
+	tok := s.toks[0]
+	s.toks = s.toks[1:]
+	return tok, nil
+}
@@ -0,0 +1,121 @@
+package codf
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSyntaxRoundTrip(t *testing.T) {
+	simple, err := os.ReadFile("_test/simple-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		src  string
+	}{
+		{"Empty", ""},
+		{"Whitespace", " \t\r\n\n"},
+		{"SimpleFile", string(simple)},
+		{"Quoting", "s \"a\\x41\\u00e9\" `raw``q` 'single';"},
+		{"Numbers", "n 0x1F 0b101 0o17 8#17 1.50 1e3 3/6 -1 +2 1h30m 1.5us;"},
+		{"Compounds", "m #{ a [1 2 #{}]  \"b\" [] } [ // c\n 1 \n];"},
+		{"Sections", "a {b {c;}}d 1 {}\n;;\n  e #/x\\/y/ ; // done"},
+		{"NoTrailingNewline", "foo bar; // comment"},
+		{"CRLF", "foo {\r\n\tbar;\r\n}\r\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cst, err := ParseSyntax(strings.NewReader(c.src))
+			if err != nil {
+				t.Fatalf("ParseSyntax() error = %v; want nil", err)
+			}
+			if got := cst.String(); got != c.src {
+				t.Fatalf("String() = %q; want %q", got, c.src)
+			}
+
+			doc, err := cst.Document()
+			if err != nil {
+				t.Fatalf("Document() error = %v; want nil", err)
+			}
+			objectsEqual(t, "", doc, mustParse(t, c.src))
+		})
+	}
+}
+
+func TestSyntaxTree(t *testing.T) {
+	const src = "// c\nfoo [1] { bar #{k v}; }\n"
+
+	p := NewParser()
+	cst, err := p.ParseSyntax(NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatalf("ParseSyntax() error = %v; want nil", err)
+	}
+
+	var describe func(n *SyntaxNode) string
+	describe = func(n *SyntaxNode) string {
+		parts := []string{n.Kind.String() + "("}
+		for _, child := range n.Children {
+			switch child := child.(type) {
+			case *SyntaxNode:
+				parts = append(parts, describe(child))
+			case *SyntaxToken:
+				parts = append(parts, "'"+string(child.Bytes())+"'")
+			}
+		}
+		return strings.Join(parts, " ") + " )"
+	}
+
+	const want = `document( '// c' '` + "\n" + `' ` +
+		`section( 'foo' ' ' array( '[' '1' ']' ) ' ' '{' ' ' ` +
+		`statement( 'bar' ' ' map( '#{' 'k' ' ' 'v' '}' ) ';' ) ' ' '}' ) '` + "\n" + `' '' )`
+	if got := describe(cst); got != want {
+		t.Errorf("tree =\n%s\nwant\n%s", got, want)
+	}
+
+	// The parser's document shares tokens with the tree.
+	sect := p.Document().Children[0].(*Section)
+	if got, want := sect.Token(), cst.Children[2].(*SyntaxNode).Token(); got.Start != want.Start {
+		t.Errorf("section token = %v; want %v", got.Start, want.Start)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	if _, err := ParseSyntax(strings.NewReader("foo { bar;")); err == nil {
+		t.Fatal("ParseSyntax() error = nil; want error")
+	}
+}
+
+func TestSyntaxQuotedNames(t *testing.T) {
+	const src = "map $a $b { default x; \"\" y; }\n"
+
+	p := NewParser()
+	p.Flags = ParseQuotedNames
+	cst, err := p.ParseSyntax(NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatalf("ParseSyntax() error = %v; want nil", err)
+	}
+	if got := cst.String(); got != src {
+		t.Fatalf("String() = %q; want %q", got, src)
+	}
+
+	sect := cst.Children[0].(*SyntaxNode)
+	var names []string
+	for _, child := range sect.Children {
+		if stmt, ok := child.(*SyntaxNode); ok && stmt.Kind == SyntaxStatement {
+			names = append(names, string(stmt.Token().Raw))
+		}
+	}
+	if got, want := strings.Join(names, " "), `default ""`; got != want {
+		t.Errorf("statement names = %s; want %s", got, want)
+	}
+
+	doc, err := cst.Document()
+	if err != nil {
+		t.Fatalf("Document() error = %v; want nil", err)
+	}
+	objectsEqual(t, "", doc, p.Document())
+}
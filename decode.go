@@ -0,0 +1,803 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/regexp"
+)
+
+// Unmarshal decodes the statements and sections of doc into the value pointed to by v.
+//
+// v must be a non-nil pointer to a struct or a map with string keys. Each statement and section
+// in doc is matched by name to a struct field or map key and decoded into it:
+//
+//   - A statement with a single parameter is decoded by converting that parameter to the field's
+//     type. A statement with no parameters may be decoded into a bool, which is set to true.
+//   - Parameters are converted from their literal values: strings and words to string, booleans
+//     to bool, integers and byte sizes to any integer type or *big.Int, floats and rationals to
+//     float types, *big.Float, or *big.Rat, durations and periods to time.Duration or Period,
+//     dates and timestamps to time.Time, network addresses to netip.Addr, netip.Prefix, or
+//     netip.AddrPort, and regexps to *regexp.Regexp. Periods with years or months cannot be
+//     decoded into a time.Duration. Arrays are decoded into slices and arrays, and maps into maps
+//     with string keys. Any value may be decoded into an empty interface, in which case it
+//     receives the literal's value, []any for arrays, or map[string]any for maps.
+//   - A statement or section decoded into a struct assigns its parameters, in order, to the
+//     fields tagged with the "param" option. A field tagged with "params" receives all remaining
+//     parameters and must be a slice. A section's children are then decoded into the struct's
+//     remaining fields by name.
+//   - A section decoded into a map with string keys decodes each of its children into the map,
+//     keyed by the child's name.
+//   - Repeated statements and sections are appended to slice fields. If the slice's element type
+//     is a struct or another non-literal type, each statement or section appends one element.
+//     Otherwise, each parameter of each statement is appended to the slice.
+//   - Fields of the types *Statement, *Section, ParamNode, and Node receive the node itself, and
+//     fields of the types ExprNode, *Literal, *Array, and *Map receive a statement's parameter.
+//
+// Fields are matched using the name given in the field's codf tag, if any, or the name of the
+// field, case-insensitively. A field with the tag `codf:"-"` is ignored.
+//
+// Values whose types implement StatementUnmarshaler, SectionUnmarshaler, or ExprUnmarshaler decode
+// themselves from the corresponding node before any default conversion is attempted.
+//
+// If a statement or section does not correspond to any field, Unmarshal returns an error. If an
+// error occurs while decoding a node, Unmarshal returns an *UnmarshalError for it.
+func Unmarshal(doc *Document, v any) error {
+	var d decodeState
+	return d.unmarshal(doc, v)
+}
+
+// Decoder reads a codf document from an input stream and decodes it into a Go value.
+type Decoder struct {
+	// Flags is the set of Lex flags used when lexing the input.
+	Flags LexerFlag
+
+	// AllowUnknown, if true, causes the Decoder to skip statements and sections that do not
+	// correspond to a field. By default, these are errors.
+	AllowUnknown bool
+
+	r io.Reader
+}
+
+// NewDecoder allocates a new Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode parses the remainder of the Decoder's input as a Document and decodes it into the value
+// pointed to by v. See Unmarshal for details on how a Document is decoded.
+func (d *Decoder) Decode(v any) error {
+	lex := NewLexer(d.r)
+	lex.Flags = d.Flags
+	p := NewParser()
+	if err := p.Parse(lex); err != nil {
+		return err
+	}
+	ds := decodeState{allowUnknown: d.AllowUnknown}
+	return ds.unmarshal(p.Document(), v)
+}
+
+// UnmarshalError is returned by Unmarshal and Decoder when a node cannot be decoded.
+type UnmarshalError struct {
+	// Node is the node that could not be decoded.
+	Node Node
+
+	// Type is the Go type that Node was being decoded into, if any.
+	Type reflect.Type
+
+	// Err is the error describing why Node could not be decoded.
+	Err error
+}
+
+func (e *UnmarshalError) Error() string {
+	return "[" + e.Node.Token().Start.String() + "] " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidUnmarshalError is returned by Unmarshal when it is given a value that it cannot decode
+// into (i.e., anything other than a non-nil pointer).
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "cannot unmarshal into nil"
+	} else if e.Type.Kind() != reflect.Pointer {
+		return "cannot unmarshal into non-pointer " + e.Type.String()
+	}
+	return "cannot unmarshal into nil " + e.Type.String()
+}
+
+var (
+	durationType  = reflect.TypeOf(time.Duration(0))
+	timeType      = reflect.TypeOf(time.Time{})
+	periodType    = reflect.TypeOf(Period{})
+	addrType      = reflect.TypeOf(netip.Addr{})
+	prefixType    = reflect.TypeOf(netip.Prefix{})
+	addrPortType  = reflect.TypeOf(netip.AddrPort{})
+	bigIntType    = reflect.TypeOf((*big.Int)(nil))
+	bigFloatType  = reflect.TypeOf((*big.Float)(nil))
+	bigRatType    = reflect.TypeOf((*big.Rat)(nil))
+	regexpType    = reflect.TypeOf((*regexp.Regexp)(nil))
+	nodeType      = reflect.TypeOf((*Node)(nil)).Elem()
+	paramNodeType = reflect.TypeOf((*ParamNode)(nil)).Elem()
+	exprNodeType  = reflect.TypeOf((*ExprNode)(nil)).Elem()
+	statementType = reflect.TypeOf((*Statement)(nil))
+	sectionType   = reflect.TypeOf((*Section)(nil))
+	literalType   = reflect.TypeOf((*Literal)(nil))
+	arrayType     = reflect.TypeOf((*Array)(nil))
+	mapType       = reflect.TypeOf((*Map)(nil))
+)
+
+// isLiteralType returns true if t is decoded from a single ExprNode rather than from a statement
+// or section.
+func isLiteralType(t reflect.Type) bool {
+	switch t {
+	case durationType, timeType, periodType, addrType, prefixType, addrPortType,
+		bigIntType, bigFloatType, bigRatType, regexpType,
+		exprNodeType, literalType, arrayType, mapType:
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	case reflect.Pointer:
+		return isLiteralType(t.Elem())
+	}
+	return false
+}
+
+// field describes a struct field that nodes or parameters can be decoded into.
+type field struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	tagged    bool // name was set by a tag
+	param     bool // receives a single parameter
+	params    bool // receives all remaining parameters
+	omitEmpty bool
+}
+
+// structInfo describes the fields of a struct type.
+type structInfo struct {
+	params []*field // fields receiving parameters, in order
+	fields []*field // fields receiving statements and sections
+}
+
+// names returns the names of the fields receiving statements and sections.
+func (s *structInfo) names() []string {
+	names := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// lookup returns the field for the given statement or section name. Tagged names must match
+// exactly, while field names are matched case-insensitively.
+func (s *structInfo) lookup(name string) *field {
+	for _, f := range s.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	for _, f := range s.fields {
+		if !f.tagged && strings.EqualFold(f.name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+var structInfoCache sync.Map // map[reflect.Type]*structInfo
+
+func cachedStructInfo(t reflect.Type) *structInfo {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+	info := &structInfo{}
+	addStructFields(info, t, nil)
+	actual, _ := structInfoCache.LoadOrStore(t, info)
+	return actual.(*structInfo)
+}
+
+func addStructFields(info *structInfo, t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("codf")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		// Flatten untagged embedded structs into their parent.
+		if sf.Anonymous && !hasTag && sf.Type.Kind() == reflect.Struct && !isLiteralType(sf.Type) {
+			addStructFields(info, sf.Type, fieldIndex)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := &field{
+			name:   name,
+			index:  fieldIndex,
+			typ:    sf.Type,
+			tagged: name != "",
+		}
+		if f.name == "" {
+			f.name = strings.ToLower(sf.Name)
+		}
+		for opts != "" {
+			var opt string
+			opt, opts, _ = strings.Cut(opts, ",")
+			switch opt {
+			case "param":
+				f.param = true
+			case "params":
+				f.params = true
+			case "omitempty":
+				f.omitEmpty = true
+			}
+		}
+
+		if f.param || f.params {
+			info.params = append(info.params, f)
+		} else {
+			info.fields = append(info.fields, f)
+		}
+	}
+}
+
+// fieldByIndex returns the field of v at index, allocating embedded struct pointers as needed.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// indirect follows pointers in v, allocating them as needed, until it reaches a non-pointer value.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// eachChild calls fn for each statement and section in parent. Documents nested in parent are
+// walked as though their children belonged to parent, and nil children are skipped.
+func eachChild(parent ParentNode, fn func(ParamNode) error) error {
+	for _, child := range parent.Nodes() {
+		var err error
+		switch child := child.(type) {
+		case nil:
+		case *Document:
+			err = eachChild(child, fn)
+		case ParamNode:
+			err = fn(child)
+		default:
+			err = fmt.Errorf("unrecognized node type: %T", child)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type decodeState struct {
+	allowUnknown bool
+}
+
+func (d *decodeState) unmarshal(parent ParentNode, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	return d.decodeBody(parent, rv.Elem())
+}
+
+// error wraps err, returned while decoding node into t, in an *UnmarshalError. If err is nil or
+// already an *UnmarshalError, it is returned as-is.
+func (d *decodeState) error(node Node, t reflect.Type, err error) error {
+	var ue *UnmarshalError
+	if err == nil || errors.As(err, &ue) {
+		return err
+	}
+	return &UnmarshalError{Node: node, Type: t, Err: err}
+}
+
+func (d *decodeState) errorf(node Node, t reflect.Type, format string, args ...any) error {
+	return &UnmarshalError{Node: node, Type: t, Err: fmt.Errorf(format, args...)}
+}
+
+// nodeKind returns a short description of node for use in error messages.
+func nodeKind(node Node) string {
+	switch node := node.(type) {
+	case *Statement:
+		return "statement"
+	case *Section:
+		return "section"
+	case *Array:
+		return "array"
+	case *Map:
+		return "map"
+	default:
+		return node.Token().Kind.String()
+	}
+}
+
+// decodeBody decodes the children of parent into v, which must be a struct or map (or a pointer to
+// one).
+func (d *decodeState) decodeBody(parent ParentNode, v reflect.Value) error {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		info := cachedStructInfo(v.Type())
+		return eachChild(parent, func(node ParamNode) error {
+			f := info.lookup(node.Name())
+			if f == nil {
+				if d.allowUnknown {
+					return nil
+				}
+				return &UnmarshalError{Node: node, Type: v.Type(), Err: UnknownName(node, info.names())}
+			}
+			return d.decodeNode(node, fieldByIndex(v, f.index))
+		})
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		return eachChild(parent, func(node ParamNode) error {
+			key := reflect.ValueOf(node.Name()).Convert(v.Type().Key())
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if prev := v.MapIndex(key); prev.IsValid() {
+				elem.Set(prev)
+			}
+			if err := d.decodeNode(node, elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+			return nil
+		})
+	}
+	return d.errorf(parent, v.Type(), "cannot decode %s into %v", nodeKind(parent), v.Type())
+}
+
+// decodeNode decodes a statement or section into v.
+func (d *decodeState) decodeNode(node ParamNode, v reflect.Value) error {
+	// AST nodes are assigned as-is
+	switch v.Type() {
+	case statementType, sectionType, paramNodeType, nodeType:
+		nv := reflect.ValueOf(node)
+		if !nv.Type().AssignableTo(v.Type()) {
+			return d.errorf(node, v.Type(), "cannot decode %s into %v", nodeKind(node), v.Type())
+		}
+		v.Set(nv)
+		return nil
+	}
+
+	if ok, err := d.decodeUnmarshaler(node, v); ok {
+		return err
+	}
+
+	if isExprType(v.Type()) {
+		return d.decodeParam(node, v)
+	}
+
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Slice:
+		elemType := v.Type().Elem()
+		if isExprType(elemType) {
+			return d.appendParams(node, node.Parameters(), v)
+		}
+		elem := reflect.New(elemType).Elem()
+		if err := d.decodeNode(node, elem); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, elem))
+		return nil
+
+	case reflect.Array:
+		return d.decodeParam(node, v)
+
+	case reflect.Struct:
+		return d.decodeStruct(node, v)
+
+	case reflect.Map:
+		if sect, ok := node.(*Section); ok {
+			if len(sect.Params) > 0 {
+				return d.errorf(sect.Params[0], v.Type(), "unexpected parameter to section %q", sect.Name())
+			}
+			return d.decodeBody(sect, v)
+		}
+		return d.decodeParam(node, v)
+	}
+
+	return d.errorf(node, v.Type(), "cannot decode %s %q into %v", nodeKind(node), node.Name(), v.Type())
+}
+
+// decodeParam decodes the single parameter of a statement into v. If the statement has no
+// parameters and v is a bool, v is set to true.
+func (d *decodeState) decodeParam(node ParamNode, v reflect.Value) error {
+	if sect, ok := node.(*Section); ok {
+		return d.errorf(sect, v.Type(), "cannot decode section %q into %v", sect.Name(), v.Type())
+	}
+
+	switch params := node.Parameters(); len(params) {
+	case 0:
+		if indirect(v).Kind() == reflect.Bool {
+			indirect(v).SetBool(true)
+			return nil
+		}
+		return d.errorf(node, v.Type(), "expected a parameter for %q", node.Name())
+	case 1:
+		return d.decodeExpr(params[0], v)
+	default:
+		return d.errorf(params[1], v.Type(), "expected a single parameter for %q", node.Name())
+	}
+}
+
+// appendParams appends each of params to the slice v. Arrays in params are flattened into v.
+func (d *decodeState) appendParams(node Node, params []ExprNode, v reflect.Value) error {
+	elemType := v.Type().Elem()
+	for _, p := range params {
+		if ary, ok := p.(*Array); ok && elemType != arrayType && elemType != exprNodeType {
+			if err := d.appendParams(ary, ary.Elems, v); err != nil {
+				return err
+			}
+			continue
+		}
+		elem := reflect.New(elemType).Elem()
+		if err := d.decodeExpr(p, elem); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, elem))
+	}
+	return nil
+}
+
+// decodeStruct decodes the parameters and, if node is a section, children of node into the struct
+// v.
+func (d *decodeState) decodeStruct(node ParamNode, v reflect.Value) error {
+	info := cachedStructInfo(v.Type())
+	params := node.Parameters()
+	for _, f := range info.params {
+		fv := fieldByIndex(v, f.index)
+		if f.params {
+			fv = indirect(fv)
+			if fv.Kind() != reflect.Slice {
+				return d.errorf(node, f.typ, "cannot decode parameters into %v", f.typ)
+			}
+			if err := d.appendParams(node, params, fv); err != nil {
+				return err
+			}
+			params = nil
+			continue
+		}
+		if len(params) == 0 {
+			break
+		}
+		if err := d.decodeExpr(params[0], fv); err != nil {
+			return err
+		}
+		params = params[1:]
+	}
+
+	if len(params) > 0 {
+		return d.errorf(params[0], v.Type(), "unexpected parameter to %s %q", nodeKind(node), node.Name())
+	}
+
+	if sect, ok := node.(*Section); ok {
+		return d.decodeBody(sect, v)
+	}
+	return nil
+}
+
+// decodeExpr converts the value of expr to v's type and assigns it to v.
+func (d *decodeState) decodeExpr(expr ExprNode, v reflect.Value) error {
+	fail := func() error {
+		return d.errorf(expr, v.Type(), "cannot decode %s into %v", nodeKind(expr), v.Type())
+	}
+
+	switch v.Type() {
+	case exprNodeType, literalType, arrayType, mapType:
+		ev := reflect.ValueOf(expr)
+		if !ev.Type().AssignableTo(v.Type()) {
+			return fail()
+		}
+		v.Set(ev)
+		return nil
+	}
+
+	if ok, err := d.decodeExprUnmarshaler(expr, v); ok {
+		return err
+	}
+
+	// Allocate pointers, except for the *big and *regexp types, which are assigned below.
+	for v.Kind() == reflect.Pointer {
+		switch v.Type() {
+		case bigIntType, bigFloatType, bigRatType, regexpType:
+		default:
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+			continue
+		}
+		break
+	}
+
+	switch v.Type() {
+	case durationType:
+		dur, ok := Duration(expr)
+		if !ok {
+			return fail()
+		}
+		v.SetInt(int64(dur))
+		return nil
+
+	case periodType:
+		p, ok := PeriodOf(expr)
+		if !ok {
+			return fail()
+		}
+		v.Set(reflect.ValueOf(p))
+		return nil
+
+	case timeType:
+		t, ok := Time(expr)
+		if !ok {
+			return fail()
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+
+	case addrType:
+		addr, ok := Addr(expr)
+		if !ok {
+			return fail()
+		}
+		v.Set(reflect.ValueOf(addr))
+		return nil
+
+	case prefixType:
+		prefix, ok := Prefix(expr)
+		if !ok {
+			return fail()
+		}
+		v.Set(reflect.ValueOf(prefix))
+		return nil
+
+	case addrPortType:
+		addrPort, ok := AddrPort(expr)
+		if !ok {
+			return fail()
+		}
+		v.Set(reflect.ValueOf(addrPort))
+		return nil
+
+	case bigIntType:
+		x := integerValue(expr)
+		if x == nil {
+			return fail()
+		}
+		v.Set(reflect.ValueOf(new(big.Int).Set(x)))
+		return nil
+
+	case bigFloatType:
+		var f *big.Float
+		switch x := Value(expr).(type) {
+		case *big.Float:
+			f = new(big.Float).Copy(x)
+		case *big.Int:
+			f = new(big.Float).SetPrec(DefaultPrecision).SetInt(x)
+		case *big.Rat:
+			f = new(big.Float).SetPrec(DefaultPrecision).SetRat(x)
+		default:
+			return fail()
+		}
+		v.Set(reflect.ValueOf(f))
+		return nil
+
+	case bigRatType:
+		var r *big.Rat
+		switch x := Value(expr).(type) {
+		case *big.Rat:
+			r = new(big.Rat).Set(x)
+		case *big.Int:
+			r = new(big.Rat).SetInt(x)
+		case *big.Float:
+			if x.IsInf() {
+				return fail()
+			}
+			r, _ = x.Rat(nil)
+		default:
+			return fail()
+		}
+		v.Set(reflect.ValueOf(r))
+		return nil
+
+	case regexpType:
+		rx := Regexp(expr)
+		if rx == nil {
+			return fail()
+		}
+		v.Set(reflect.ValueOf(rx))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := Bool(expr)
+		if !ok {
+			return fail()
+		}
+		v.SetBool(b)
+		return nil
+
+	case reflect.String:
+		s, ok := String(expr)
+		if !ok {
+			return fail()
+		}
+		v.SetString(s)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x := integerValue(expr)
+		if x == nil {
+			return fail()
+		}
+		if !x.IsInt64() || v.OverflowInt(x.Int64()) {
+			return d.errorf(expr, v.Type(), "integer %v overflows %v", x, v.Type())
+		}
+		v.SetInt(x.Int64())
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x := integerValue(expr)
+		if x == nil {
+			return fail()
+		}
+		if x.Sign() < 0 || !x.IsUint64() || v.OverflowUint(x.Uint64()) {
+			return d.errorf(expr, v.Type(), "integer %v overflows %v", x, v.Type())
+		}
+		v.SetUint(x.Uint64())
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, ok := Float64(expr)
+		if !ok {
+			return fail()
+		}
+		if v.OverflowFloat(f) {
+			return d.errorf(expr, v.Type(), "number %v overflows %v", f, v.Type())
+		}
+		v.SetFloat(f)
+		return nil
+
+	case reflect.Slice:
+		ary, ok := expr.(*Array)
+		if !ok {
+			return fail()
+		}
+		s := reflect.MakeSlice(v.Type(), len(ary.Elems), len(ary.Elems))
+		for i, elem := range ary.Elems {
+			if err := d.decodeExpr(elem, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+
+	case reflect.Array:
+		ary, ok := expr.(*Array)
+		if !ok {
+			return fail()
+		}
+		if len(ary.Elems) != v.Len() {
+			return d.errorf(expr, v.Type(), "cannot decode array of length %d into %v", len(ary.Elems), v.Type())
+		}
+		for i, elem := range ary.Elems {
+			if err := d.decodeExpr(elem, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		m, ok := expr.(*Map)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return fail()
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for _, entry := range m.Pairs() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decodeExpr(entry.Val, elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(entry.Name()).Convert(v.Type().Key()), elem)
+		}
+		return nil
+
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fail()
+		}
+		v.Set(reflect.ValueOf(plainValue(expr)))
+		return nil
+	}
+
+	return fail()
+}
+
+// integerValue returns the value of expr as a *big.Int if it is an integer or a rational that is
+// also an integer. Otherwise, it returns nil.
+func integerValue(expr ExprNode) *big.Int {
+	switch x := Value(expr).(type) {
+	case *big.Int:
+		return x
+	case *big.Rat:
+		if x.IsInt() {
+			return x.Num()
+		}
+	}
+	return nil
+}
+
+// plainValue returns the value of expr with arrays and maps converted to []any and
+// map[string]any, respectively.
+func plainValue(expr ExprNode) any {
+	switch expr := expr.(type) {
+	case *Array:
+		values := make([]any, len(expr.Elems))
+		for i, elem := range expr.Elems {
+			values[i] = plainValue(elem)
+		}
+		return values
+	case *Map:
+		values := make(map[string]any, len(expr.Elems))
+		for key, entry := range expr.Elems {
+			values[key] = plainValue(entry.Val)
+		}
+		return values
+	}
+	return Value(expr)
+}
@@ -0,0 +1,273 @@
+package codf
+
+import (
+	"errors"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/regexp"
+)
+
+type decodeProxy struct {
+	Addr          string `codf:",param"`
+	StripXHeaders bool   `codf:"strip-x-headers"`
+	LogAccess     bool   `codf:"log-access"`
+}
+
+type decodeExpire struct {
+	After time.Duration `codf:",param"`
+	Codes []int         `codf:",params"`
+}
+
+type decodeCache struct {
+	Kind   string         `codf:",param"`
+	Size   int64          `codf:",param"`
+	Expire []decodeExpire `codf:"expire"`
+}
+
+type decodeServer struct {
+	Name    string        `codf:",param"`
+	Listen  []string      `codf:"listen"`
+	Control string        `codf:"control"`
+	Proxy   []decodeProxy `codf:"proxy"`
+	Cache   *decodeCache  `codf:"cache"`
+}
+
+func TestUnmarshalExample(t *testing.T) {
+	const src = `server go.spiff.io {
+    listen 0.0.0.0:80;
+    listen 0.0.0.0:443;
+    control unix:///var/run/httpd.sock;
+    proxy unix:///var/run/go-redirect.sock {
+        strip-x-headers yes;
+        log-access no;
+    }
+    cache memory 64mb {
+         expire 10m 404;
+         expire 1h  301 302;
+    }
+}`
+
+	var got struct {
+		Servers []decodeServer `codf:"server"`
+	}
+	if err := Unmarshal(mustParse(t, src), &got); err != nil {
+		t.Fatalf("Unmarshal() = %v; want nil", err)
+	}
+
+	want := []decodeServer{{
+		Name:    "go.spiff.io",
+		Listen:  []string{"0.0.0.0:80", "0.0.0.0:443"},
+		Control: "unix:///var/run/httpd.sock",
+		Proxy: []decodeProxy{
+			{Addr: "unix:///var/run/go-redirect.sock", StripXHeaders: true},
+		},
+		Cache: &decodeCache{
+			Kind: "memory",
+			Size: 64e6,
+			Expire: []decodeExpire{
+				{After: 10 * time.Minute, Codes: []int{404}},
+				{After: time.Hour, Codes: []int{301, 302}},
+			},
+		},
+	}}
+	if !reflect.DeepEqual(got.Servers, want) {
+		t.Errorf("Unmarshal() =\n%#v\nwant\n%#v", got.Servers, want)
+	}
+}
+
+func TestUnmarshalLiterals(t *testing.T) {
+	const src = `
+	str "quoted"; word bare; on yes; off no;
+	int -12; uint 0xff; i8 127; f64 1/4; f32 2.5;
+	dur 1h30m; bigint 1234567890123456789012345678901234567890;
+	bigfloat 1.5; bigrat 3/4; rx #/^foo$/;
+	ary [1 2 3]; fixed [a b]; kv #{ a 1 b 2 };
+	any [1 two #{k v}];
+	node 1; stmt x; misc;
+	`
+
+	type literals struct {
+		Str      string
+		Word     *string
+		On       bool
+		Off      bool
+		Int      int
+		Uint     uint8
+		I8       int8
+		F64      float64
+		F32      float32
+		Dur      time.Duration
+		BigInt   *big.Int
+		BigFloat *big.Float
+		BigRat   *big.Rat
+		Rx       *regexp.Regexp
+		Ary      []int
+		Fixed    [2]string
+		KV       map[string]int
+		Any      any
+		Node     ExprNode
+		Stmt     *Statement
+		Misc     bool
+	}
+
+	var got literals
+	if err := Unmarshal(mustParse(t, src), &got); err != nil {
+		t.Fatalf("Unmarshal() = %v; want nil", err)
+	}
+
+	check := func(name string, got, want any) {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %#v; want %#v", name, got, want)
+		}
+	}
+
+	bigint, _ := new(big.Int).SetString("1234567890123456789012345678901234567890", 10)
+	check("Str", got.Str, "quoted")
+	check("Word", *got.Word, "bare")
+	check("On", got.On, true)
+	check("Off", got.Off, false)
+	check("Int", got.Int, -12)
+	check("Uint", got.Uint, uint8(0xff))
+	check("I8", got.I8, int8(127))
+	check("F64", got.F64, 0.25)
+	check("F32", got.F32, float32(2.5))
+	check("Dur", got.Dur, time.Hour+30*time.Minute)
+	check("BigInt", got.BigInt.Cmp(bigint), 0)
+	check("BigFloat", got.BigFloat.String(), "1.5")
+	check("BigRat", got.BigRat.String(), "3/4")
+	check("Rx", got.Rx.String(), "^foo$")
+	check("Ary", got.Ary, []int{1, 2, 3})
+	check("Fixed", got.Fixed, [2]string{"a", "b"})
+	check("KV", got.KV, map[string]int{"a": 1, "b": 2})
+	check("Any", got.Any, []any{big.NewInt(1), "two", map[string]any{"k": "v"}})
+	check("Node", got.Node.(*Literal).Value(), any(big.NewInt(1)))
+	check("Stmt", got.Stmt.Name(), "stmt")
+	check("Misc", got.Misc, true)
+}
+
+func TestUnmarshalSectionMap(t *testing.T) {
+	const src = `upstreams { a 1; b 2; a 3; }`
+
+	var got struct {
+		Upstreams map[string][]int
+	}
+	if err := Unmarshal(mustParse(t, src), &got); err != nil {
+		t.Fatalf("Unmarshal() = %v; want nil", err)
+	}
+	want := map[string][]int{"a": {1, 3}, "b": {2}}
+	if !reflect.DeepEqual(got.Upstreams, want) {
+		t.Errorf("Upstreams = %v; want %v", got.Upstreams, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type config struct {
+		Port    uint16
+		Name    string
+		Workers int `codf:"workers"`
+		Section struct {
+			Arg string `codf:",param"`
+		}
+	}
+
+	cases := []struct {
+		name string
+		src  string
+		msg  string
+	}{
+		{"Unknown", "\nport 80;\n  prot 81;", "[3:3:12] unknown statement \"prot\"; did you mean \"port\"?"},
+		{"UnknownNoSuggestion", "listen 80;", "[1:1:0] unknown statement \"listen\""},
+		{"Overflow", "port 65536;", "[1:6:5] integer 65536 overflows uint16"},
+		{"Type", "name 1234;", "[1:6:5] cannot decode integer into string"},
+		{"NoParams", "name;", "[1:1:0] expected a parameter for \"name\""},
+		{"TooManyParams", "name a b;", "[1:8:7] expected a single parameter for \"name\""},
+		{"SectionAsLiteral", "workers {}", "[1:1:0] cannot decode section \"workers\" into int"},
+		{"ExtraParam", "section a b {}", "[1:11:10] unexpected parameter to section \"section\""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var dst config
+			err := Unmarshal(mustParse(t, c.src), &dst)
+			var ue *UnmarshalError
+			if !errors.As(err, &ue) {
+				t.Fatalf("Unmarshal() = %v; want *UnmarshalError", err)
+			}
+			if got := err.Error(); got != c.msg {
+				t.Errorf("Unmarshal() = %q; want %q", got, c.msg)
+			}
+		})
+	}
+
+	t.Run("NonPointer", func(t *testing.T) {
+		var dst config
+		var ie *InvalidUnmarshalError
+		if err := Unmarshal(mustParse(t, ""), dst); !errors.As(err, &ie) {
+			t.Fatalf("Unmarshal() = %v; want *InvalidUnmarshalError", err)
+		}
+	})
+}
+
+func TestDecodeNetAddrs(t *testing.T) {
+	const src = `
+	listen 0.0.0.0:80;
+	allow 10.0.0.1 ::1;
+	deny 10.0.0.0/8;
+	`
+
+	type addrs struct {
+		Listen netip.AddrPort
+		Allow  []netip.Addr
+		Deny   netip.Prefix
+	}
+	want := addrs{
+		Listen: netip.MustParseAddrPort("0.0.0.0:80"),
+		Allow:  []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")},
+		Deny:   netip.MustParsePrefix("10.0.0.0/8"),
+	}
+
+	// Network addresses are decoded from words, using UnmarshalText, and from the address
+	// literals read with LexNetAddrs.
+	for _, flags := range []LexerFlag{LexDefaultFlags, LexNetAddrs} {
+		var got addrs
+		dec := NewDecoder(strings.NewReader(src))
+		dec.Flags = flags
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("Decode() with flags %v = %v; want nil", flags, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode() with flags %v = %#v; want %#v", flags, got, want)
+		}
+	}
+
+	var wrong struct{ Listen netip.Addr }
+	dec := NewDecoder(strings.NewReader(src))
+	dec.Flags = LexNetAddrs
+	dec.AllowUnknown = true
+	if err := dec.Decode(&wrong); err == nil {
+		t.Error("Decode() = nil; want error for address and port decoded into netip.Addr")
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	const src = `name foo; unknown 1;`
+
+	var got struct{ Name string }
+	dec := NewDecoder(strings.NewReader(src))
+	dec.AllowUnknown = true
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode() = %v; want nil", err)
+	}
+	if got.Name != "foo" {
+		t.Errorf("Name = %q; want %q", got.Name, "foo")
+	}
+
+	if err := NewDecoder(strings.NewReader(src)).Decode(&got); err == nil {
+		t.Error("Decode() = nil; want error for unknown statement")
+	}
+}
@@ -0,0 +1,214 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Note describes a location related to an error, such as where a section that was not closed
+// began.
+type Note struct {
+	// Start and End are the locations of the start and end of the text the note refers to.
+	Start, End Location
+	// Msg is a message describing the location.
+	Msg string
+}
+
+// Diagnostic is an error message with the span of source text it refers to and any related notes.
+type Diagnostic struct {
+	// Start and End are the locations of the start and end of the text the error refers to.
+	Start, End Location
+	// Msg is a message describing the error, without its location.
+	Msg string
+	// Notes describe locations related to the error.
+	Notes []Note
+}
+
+// DiagnosticOf returns a Diagnostic for err. If err is, or wraps, one of the located errors of this
+// package, such as *ExpectedError, *LexError, or *WalkError, the Diagnostic has its location and
+// notes and ok is true. Otherwise, the Diagnostic only has err's message and ok is false.
+func DiagnosticOf(err error) (diag Diagnostic, ok bool) {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch e := e.(type) {
+		case *ExpectedError:
+			return Diagnostic{
+				Start: e.Tok.Start,
+				End:   e.Tok.End,
+				Msg:   "unexpected " + e.Tok.Kind.String() + ": " + e.Msg,
+				Notes: e.Notes,
+			}, true
+		case *LexError:
+			return Diagnostic{Start: e.Start, End: e.End, Msg: e.Msg}, true
+		case *WalkError:
+			return nodeDiagnostic(e.Node, contextName(e.Node)+" in "+contextName(e.Context)+": "+e.Err.Error()), true
+		case *UnmarshalError:
+			return nodeDiagnostic(e.Node, e.Err.Error()), true
+		case *ValidationError:
+			if _, isDoc := e.Node.(*Document); !isDoc {
+				return nodeDiagnostic(e.Node, e.Msg), true
+			}
+		case *IncludeError:
+			return nodeDiagnostic(e.Stmt, e.Stmt.Name()+": "+e.Err.Error()), true
+		case *UnknownNameError:
+			return nodeDiagnostic(e.Node, e.Error()), true
+		case *VarError:
+			msg := e.Err.Error()
+			if e.Name != "" {
+				msg = "$" + e.Name + ": " + msg
+			}
+			return Diagnostic{Start: e.Tok.Start, End: e.Tok.End, Msg: msg}, true
+		}
+	}
+	return Diagnostic{Msg: err.Error()}, false
+}
+
+func nodeDiagnostic(node Node, msg string) Diagnostic {
+	tok := node.Token()
+	return Diagnostic{Start: tok.Start, End: tok.End, Msg: msg}
+}
+
+// DiagnosticPrinter writes errors as human-readable diagnostics. Each diagnostic is written as its
+// message followed by its location and the line of source it refers to, with the text the error
+// refers to underlined by carets. Locations are written as line:column:offset. Notes are written
+// the same way after the error. For example:
+//
+//	error: unexpected close brace: expected end of statement "b" beginning at app.conf:2:5:8
+//	 --> app.conf:2:7:10
+//	  |
+//	2 |     b }
+//	  |       ^
+//	note: statement "b" begins here
+//	 --> app.conf:2:5:8
+//	  |
+//	2 |     b }
+//	  |     ^
+type DiagnosticPrinter struct {
+	// Color enables ANSI color and bold escape sequences in the output.
+	Color bool
+}
+
+// ANSI escape sequences used by a DiagnosticPrinter with Color set.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiError = "\x1b[1;31m"
+	ansiNote  = "\x1b[1;36m"
+	ansiFrame = "\x1b[1;34m"
+)
+
+// Fprint writes err to w as a diagnostic. src is the source text that the locations in err refer
+// to. If err is an ErrorList, each of its errors is written in order. Locations beyond the end of
+// src are written without a line of source.
+func (p *DiagnosticPrinter) Fprint(w io.Writer, src []byte, err error) error {
+	var buf bytes.Buffer
+	var list ErrorList
+	if errors.As(err, &list) {
+		for _, err := range list {
+			p.diagnostic(&buf, src, err)
+		}
+	} else {
+		p.diagnostic(&buf, src, err)
+	}
+	_, werr := w.Write(buf.Bytes())
+	return werr
+}
+
+// FormatDiagnostic returns err formatted as a diagnostic without color. See DiagnosticPrinter.
+func FormatDiagnostic(src []byte, err error) string {
+	var sb strings.Builder
+	_ = (&DiagnosticPrinter{}).Fprint(&sb, src, err)
+	return sb.String()
+}
+
+func (p *DiagnosticPrinter) diagnostic(buf *bytes.Buffer, src []byte, err error) {
+	diag, ok := DiagnosticOf(err)
+	width := 0
+	if ok {
+		width = len(strconv.Itoa(diag.Start.Line))
+		for _, n := range diag.Notes {
+			width = max(width, len(strconv.Itoa(n.Start.Line)))
+		}
+	}
+
+	p.header(buf, "error", ansiError, diag.Msg)
+	if !ok {
+		return
+	}
+	p.snippet(buf, src, width, diag.Start, diag.End, ansiError)
+	for _, n := range diag.Notes {
+		p.header(buf, "note", ansiNote, n.Msg)
+		p.snippet(buf, src, width, n.Start, n.End, ansiNote)
+	}
+}
+
+func (p *DiagnosticPrinter) header(buf *bytes.Buffer, label, color, msg string) {
+	buf.WriteString(p.style(color, label+":"))
+	buf.WriteString(p.style(ansiBold, " "+msg))
+	buf.WriteByte('\n')
+}
+
+// snippet writes the location from start to end, the source line containing start, and an
+// underline beneath the text from start to end. The underline ends at the end of the line if end
+// is on a later line. width is the width of the line number gutter.
+func (p *DiagnosticPrinter) snippet(buf *bytes.Buffer, src []byte, width int, start, end Location, color string) {
+	pad := strings.Repeat(" ", width)
+	buf.WriteString(pad + p.style(ansiFrame, "-->") + " " + start.String() + "\n")
+
+	line, ok := sourceLine(src, start)
+	if !ok {
+		return
+	}
+	num := strconv.Itoa(start.Line)
+	buf.WriteString(pad + " " + p.style(ansiFrame, "|") + "\n")
+	buf.WriteString(strings.Repeat(" ", width-len(num)) + p.style(ansiFrame, num+" |") + " " + line + "\n")
+
+	// Indent the underline with the whitespace of the line so that tabs line up.
+	var indent strings.Builder
+	col := 1
+	for _, r := range line {
+		if col >= start.Column {
+			break
+		}
+		if r == '\t' {
+			indent.WriteByte('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+		col++
+	}
+
+	n := 1
+	if end.Line == start.Line && end.Column > start.Column {
+		n = end.Column - start.Column
+	} else if end.Line > start.Line {
+		n = max(1, utf8.RuneCountInString(line)-start.Column+1)
+	}
+	buf.WriteString(pad + " " + p.style(ansiFrame, "|") + " " + indent.String() + p.style(color, strings.Repeat("^", n)) + "\n")
+}
+
+func (p *DiagnosticPrinter) style(color, text string) string {
+	if !p.Color {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// sourceLine returns the line of src containing loc, without its line ending. It uses loc's
+// Offset to find the line, so that it agrees with the lexer regardless of line endings.
+func sourceLine(src []byte, loc Location) (string, bool) {
+	if loc.Line < 1 || loc.Offset < 0 || loc.Offset > len(src) {
+		return "", false
+	}
+	start := bytes.LastIndexByte(src[:loc.Offset], '\n') + 1
+	end := bytes.IndexByte(src[loc.Offset:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += loc.Offset
+	}
+	return strings.TrimSuffix(string(src[start:end]), "\r"), true
+}
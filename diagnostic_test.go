@@ -0,0 +1,174 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func parseNamed(name, src string, flags ParserFlag) error {
+	lex := NewLexer(strings.NewReader(src))
+	lex.Name = name
+	p := NewParser()
+	p.Flags = flags
+	return p.Parse(lex)
+}
+
+func TestFormatDiagnostic(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		err  func(src string) error
+		want string
+	}{
+		{
+			name: "Expected",
+			src:  "a {\n\tb 1 }\n",
+			err:  func(src string) error { return parseNamed("app.conf", src, 0) },
+			want: "" +
+				"error: unexpected close brace: expected end of statement \"b\" beginning at app.conf:2:2:5\n" +
+				" --> app.conf:2:6:9\n" +
+				"  |\n" +
+				"2 | \tb 1 }\n" +
+				"  | \t    ^\n" +
+				"note: statement \"b\" begins here\n" +
+				" --> app.conf:2:2:5\n" +
+				"  |\n" +
+				"2 | \tb 1 }\n" +
+				"  | \t^\n",
+		},
+		{
+			// The example in the DiagnosticPrinter documentation.
+			name: "DocExample",
+			src:  "a {\n    b }\n",
+			err:  func(src string) error { return parseNamed("app.conf", src, 0) },
+			want: "" +
+				"error: unexpected close brace: expected end of statement \"b\" beginning at app.conf:2:5:8\n" +
+				" --> app.conf:2:7:10\n" +
+				"  |\n" +
+				"2 |     b }\n" +
+				"  |       ^\n" +
+				"note: statement \"b\" begins here\n" +
+				" --> app.conf:2:5:8\n" +
+				"  |\n" +
+				"2 |     b }\n" +
+				"  |     ^\n",
+		},
+		{
+			name: "Lex",
+			src:  "a 1;\nb 0x;",
+			err:  func(src string) error { return parseNamed("", src, 0) },
+			want: "" +
+				"error: unexpected character ';': expected hex digit\n" +
+				" --> 2:5:9\n" +
+				"  |\n" +
+				"2 | b 0x;\n" +
+				"  |     ^\n",
+		},
+		{
+			name: "Walk",
+			src:  "server example.com {\n  proxy;\n}",
+			err: func(src string) error {
+				doc := mustParseNamed(t, "", src)
+				return Walk(doc, sectionWalker("", sectionWalker("server")))
+			},
+			want: "" +
+				"error: proxy in server: invalid statement: proxy\n" +
+				" --> 2:3:23\n" +
+				"  |\n" +
+				"2 |   proxy;\n" +
+				"  |   ^^^^^\n",
+		},
+		{
+			name: "List",
+			src:  "a [1;\nb ];",
+			err:  func(src string) error { return parseNamed("", src, ParseRecover) },
+			want: "" +
+				"error: unexpected semicolon: expected end of array beginning at 1:3:2\n" +
+				" --> 1:5:4\n" +
+				"  |\n" +
+				"1 | a [1;\n" +
+				"  |     ^\n" +
+				"note: array begins here\n" +
+				" --> 1:3:2\n" +
+				"  |\n" +
+				"1 | a [1;\n" +
+				"  |   ^\n" +
+				"error: unexpected close bracket: expected end of statement \"b\" beginning at 2:1:6\n" +
+				" --> 2:3:8\n" +
+				"  |\n" +
+				"2 | b ];\n" +
+				"  |   ^\n" +
+				"note: statement \"b\" begins here\n" +
+				" --> 2:1:6\n" +
+				"  |\n" +
+				"2 | b ];\n" +
+				"  | ^\n",
+		},
+		{
+			name: "Wrapped",
+			src:  "a \"b",
+			err: func(src string) error {
+				return fmt.Errorf("loading: %w", parseNamed("", src, 0))
+			},
+			want: "" +
+				"error: unexpected EOF: expected close of string\n" +
+				" --> 1:5:4\n" +
+				"  |\n" +
+				"1 | a \"b\n" +
+				"  |     ^\n",
+		},
+		{
+			name: "Unlocated",
+			src:  "",
+			err:  func(string) error { return errors.New("no location") },
+			want: "error: no location\n",
+		},
+		{
+			name: "OutOfRange",
+			src:  "a;",
+			err: func(string) error {
+				return &LexError{Start: Location{Line: 9, Column: 1, Offset: 90}, Msg: "elsewhere"}
+			},
+			want: "error: elsewhere\n --> 9:1:90\n",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			err := c.err(c.src)
+			if err == nil {
+				t.Fatal("error = nil; want error")
+			}
+			if got := FormatDiagnostic([]byte(c.src), err); got != c.want {
+				t.Errorf("FormatDiagnostic() =\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDiagnosticPrinterColor(t *testing.T) {
+	src := "a b"
+	err := parseNamed("", src, 0)
+	var buf bytes.Buffer
+	if err := (&DiagnosticPrinter{Color: true}).Fprint(&buf, []byte(src), err); err != nil {
+		t.Fatalf("Fprint() error = %v", err)
+	}
+	want := "" +
+		"\x1b[1;31merror:\x1b[0m\x1b[1m unexpected EOF: expected end of statement \"a\" beginning at 1:1:0\x1b[0m\n" +
+		" \x1b[1;34m-->\x1b[0m 1:4:3\n" +
+		"  \x1b[1;34m|\x1b[0m\n" +
+		"\x1b[1;34m1 |\x1b[0m a b\n" +
+		"  \x1b[1;34m|\x1b[0m    \x1b[1;31m^\x1b[0m\n" +
+		"\x1b[1;36mnote:\x1b[0m\x1b[1m statement \"a\" begins here\x1b[0m\n" +
+		" \x1b[1;34m-->\x1b[0m 1:1:0\n" +
+		"  \x1b[1;34m|\x1b[0m\n" +
+		"\x1b[1;34m1 |\x1b[0m a b\n" +
+		"  \x1b[1;34m|\x1b[0m \x1b[1;36m^\x1b[0m\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Fprint() =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,322 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Editor makes changes to the source of a document without reformatting it. Each edit is
+// recorded as a replacement of a byte range of the original source, derived from the offsets of
+// the tokens of the nodes being edited, so that text outside of the edited nodes, including
+// comments and whitespace, is left as-is.
+//
+// Nodes passed to an Editor's methods must come from the Editor's Document. Edits are always made
+// against the original source, so edits do not change the Document and nodes added by one edit
+// cannot be edited by another.
+type Editor struct {
+	src   []byte
+	doc   *Document
+	edits []splice
+	unit  string // The indentation of one level, inferred from the source.
+}
+
+// splice replaces the bytes of the source from start to end with text.
+type splice struct {
+	start, end int
+	text       string
+}
+
+// NewEditor parses src and returns an Editor for it. The Editor's Document is parsed with the
+// ParseComments flag set.
+func NewEditor(src []byte) (*Editor, error) {
+	p := NewParser()
+	p.Flags = ParseComments
+	if err := p.Parse(NewLexer(bytes.NewReader(src))); err != nil {
+		return nil, err
+	}
+	e := &Editor{src: src, doc: p.Document()}
+	e.unit = e.inferIndentUnit()
+	return e, nil
+}
+
+// Document returns the Document parsed from the Editor's source.
+func (e *Editor) Document() *Document {
+	return e.doc
+}
+
+// Bytes returns the source with all edits applied. It returns an error if any two edits overlap.
+// Edits are applied in the order of the positions they start at, regardless of the order they were
+// made in. Insertions at the position where another edit starts are written before that edit's
+// text, and insertions at the same position are applied in the order they were made.
+func (e *Editor) Bytes() ([]byte, error) {
+	edits := append([]splice(nil), e.edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end < edits[j].end
+	})
+
+	var buf bytes.Buffer
+	last := 0
+	for _, ed := range edits {
+		if ed.start < last {
+			return nil, fmt.Errorf("overlapping edits at offset %d", ed.start)
+		}
+		buf.Write(e.src[last:ed.start])
+		buf.WriteString(ed.text)
+		last = ed.end
+	}
+	buf.Write(e.src[last:])
+	return buf.Bytes(), nil
+}
+
+// ReplaceParam replaces the parameter at index i of a statement or section with expr.
+func (e *Editor) ReplaceParam(node ParamNode, i int, expr ExprNode) error {
+	params := node.Parameters()
+	if i < 0 || i >= len(params) {
+		return fmt.Errorf("parameter index %d out of range for %s with %d parameters",
+			i, node.Name(), len(params))
+	}
+	start, end := e.span(params[i])
+	return e.splice(start, end, e.format(expr, e.indentAt(start)))
+}
+
+// Rename changes the name of a statement or section.
+func (e *Editor) Rename(node ParamNode, name string) error {
+	if !isWord(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	tok := node.Token()
+	return e.splice(tok.Start.Offset, tok.End.Offset, name)
+}
+
+// InsertChild inserts child, a statement or section, into parent so that it is at index i of
+// parent's children. The child is written on its own line with the indentation of its new
+// siblings or, if parent has no children, one level deeper than parent.
+func (e *Editor) InsertChild(parent ParentNode, i int, child Node) error {
+	switch child.(type) {
+	case *Statement, *Section:
+	default:
+		return fmt.Errorf("cannot insert %T as a child", child)
+	}
+
+	children := parent.Nodes()
+	if i < 0 || i > len(children) {
+		return fmt.Errorf("child index %d out of range for %d children", i, len(children))
+	}
+
+	switch {
+	case i < len(children):
+		// Insert before the sibling at i, on its own line if the sibling begins its line.
+		start, _ := e.extent(children[i])
+		indent := e.indentAt(start)
+		text := e.format(child, indent)
+		if e.startsLine(start) {
+			return e.insert(e.lineStart(start), indent+text+"\n")
+		}
+		return e.insert(start, text+" ")
+
+	case i > 0:
+		// Insert after the last child, following anything else on its line.
+		start, end := e.span(children[i-1])
+		indent := e.indentAt(start)
+		text := e.format(child, indent)
+		at := e.lineEnd(end)
+		if sect, ok := parent.(*Section); ok && sect.EndTok.Start.Offset < at {
+			// The section is closed on the same line as its last child.
+			return e.insert(end, " "+text)
+		}
+		return e.insert(at, "\n"+indent+text)
+	}
+
+	sect, ok := parent.(*Section)
+	if !ok {
+		// An empty document.
+		text := e.format(child, "") + "\n"
+		if n := len(e.src); n > 0 && e.src[n-1] != '\n' {
+			text = "\n" + text
+		}
+		return e.insert(len(e.src), text)
+	}
+
+	outer := e.indentAt(sect.Token().Start.Offset)
+	indent := outer + e.unit
+	text := e.format(child, indent)
+	open, close := sect.StartTok.End.Offset, sect.EndTok.Start.Offset
+	if sect.StartTok.Start.Line == sect.EndTok.Start.Line {
+		// Expand "name {}" to hold the child.
+		return e.splice(open, close, "\n"+indent+text+"\n"+outer)
+	}
+	return e.insert(e.lineEnd(open), "\n"+indent+text)
+}
+
+// Delete removes a statement, section, map entry, or parameter from the source. Statements and
+// sections that are on their own lines are removed along with their lines, their leading comments,
+// and their trailing comment. Parameters and map entries are removed along with the whitespace
+// preceding them.
+func (e *Editor) Delete(node Node) error {
+	start, end := e.span(node)
+	switch node.(type) {
+	case *Statement, *Section:
+		start, end = e.extent(node)
+		if e.startsLine(start) && e.endsLine(end) {
+			start, end = e.lineStart(start), e.lineEnd(end)
+			// Remove the line ending as well.
+			if end < len(e.src) && e.src[end] == '\r' {
+				end++
+			}
+			if end < len(e.src) && e.src[end] == '\n' {
+				end++
+			}
+		}
+	default:
+		start = e.skipSpaceBefore(start)
+	}
+	return e.splice(start, end, "")
+}
+
+func (e *Editor) splice(start, end int, text string) error {
+	if start < 0 || end < start || end > len(e.src) {
+		return errors.New("node is not part of the editor's document")
+	}
+	e.edits = append(e.edits, splice{start: start, end: end, text: text})
+	return nil
+}
+
+func (e *Editor) insert(at int, text string) error {
+	return e.splice(at, at, text)
+}
+
+// span returns the byte offsets of the start and end of node in the source.
+func (e *Editor) span(node Node) (start, end int) {
+	start = node.Token().Start.Offset
+	switch node := node.(type) {
+	case *Statement:
+		end = node.EndTok.End.Offset
+	case *Section:
+		end = node.EndTok.End.Offset
+	case *Array:
+		end = node.EndTok.End.Offset
+	case *Map:
+		end = node.EndTok.End.Offset
+	case *MapEntry:
+		_, end = e.span(node.Val)
+	default:
+		end = node.Token().End.Offset
+	}
+	return start, end
+}
+
+// extent returns the byte offsets of the start and end of node in the source, including any
+// comments directly above it and its trailing comment.
+func (e *Editor) extent(node Node) (start, end int) {
+	start, end = e.span(node)
+	comments := commentsOf(node)
+	if c := comments.Trailing; c != nil {
+		end = c.Tok.End.Offset
+	}
+	line := node.Token().Start.Line
+	for i := len(comments.Leading) - 1; i >= 0; i-- {
+		c := comments.Leading[i]
+		if c.Tok.Start.Offset > start {
+			continue // A comment between the node's name and its end.
+		} else if c.Tok.Start.Line != line-1 || !e.startsLine(c.Tok.Start.Offset) {
+			break
+		}
+		start, line = c.Tok.Start.Offset, c.Tok.Start.Line
+	}
+	return start, end
+}
+
+// format returns the text of node. Lines after the first are indented by indent followed by
+// e.unit for each level they are nested by. The first line is indented by the caller.
+func (e *Editor) format(node Node, indent string) string {
+	lines := strings.Split(node.format(""), "\n")
+	for i, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, "\t")
+		depth := len(line) - len(trimmed)
+		lines[i+1] = indent + strings.Repeat(e.unit, depth) + trimmed
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *Editor) lineStart(off int) int {
+	return bytes.LastIndexByte(e.src[:off], '\n') + 1
+}
+
+// lineEnd returns the offset of the line ending ("\n" or "\r\n") following off.
+func (e *Editor) lineEnd(off int) int {
+	i := bytes.IndexByte(e.src[off:], '\n')
+	if i < 0 {
+		return len(e.src)
+	}
+	if i > 0 && e.src[off+i-1] == '\r' {
+		i--
+	}
+	return off + i
+}
+
+// indentAt returns the whitespace at the start of the line containing off.
+func (e *Editor) indentAt(off int) string {
+	line := e.src[e.lineStart(off):off]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+// startsLine returns true if only whitespace precedes off on its line.
+func (e *Editor) startsLine(off int) bool {
+	return len(bytes.Trim(e.src[e.lineStart(off):off], " \t")) == 0
+}
+
+// endsLine returns true if only whitespace follows off on its line.
+func (e *Editor) endsLine(off int) bool {
+	return len(bytes.Trim(e.src[off:e.lineEnd(off)], " \t\r")) == 0
+}
+
+// skipSpaceBefore returns the offset of the first of any spaces or tabs immediately before off.
+func (e *Editor) skipSpaceBefore(off int) int {
+	for off > 0 && (e.src[off-1] == ' ' || e.src[off-1] == '\t') {
+		off--
+	}
+	return off
+}
+
+// inferIndentUnit returns the indentation used for one level of nesting in the source, taken
+// from the first section with children on their own lines. It returns a tab if there is none.
+func (e *Editor) inferIndentUnit() string {
+	var find func(ParentNode) string
+	find = func(parent ParentNode) string {
+		for _, child := range parent.Nodes() {
+			sect, ok := child.(*Section)
+			if !ok || len(sect.Children) == 0 {
+				continue
+			}
+			outer := e.indentAt(sect.Token().Start.Offset)
+			start, _ := e.span(sect.Children[0])
+			if inner := e.indentAt(start); e.startsLine(start) && len(inner) > len(outer) &&
+				strings.HasPrefix(inner, outer) {
+				return inner[len(outer):]
+			}
+			if unit := find(sect); unit != "" {
+				return unit
+			}
+		}
+		return ""
+	}
+	if unit := find(e.doc); unit != "" {
+		return unit
+	}
+	return "\t"
+}
+
+// commentsOf returns the comments attached to node. If node cannot have comments, it returns
+// empty Comments.
+func commentsOf(node Node) *Comments {
+	if c, ok := node.(commented); ok {
+		return c.comments()
+	}
+	return &Comments{}
+}
@@ -0,0 +1,246 @@
+package codf
+
+import (
+	"strings"
+	"testing"
+)
+
+const editSource = `// Deployment settings.
+
+workers 8; // per host
+timeout 30s;
+
+server go.spiff.io {
+    listen 0.0.0.0:80;
+
+    // Proxy to the redirect service.
+    proxy unix:///var/run/go-redirect.sock {
+        strip-x-headers yes;
+    }
+    empty {}
+}
+inline { a 1; }
+`
+
+func TestEditor(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(*testing.T, *Editor, *Document)
+		want string
+	}{
+		{
+			name: "NoEdits",
+			edit: func(*testing.T, *Editor, *Document) {},
+			want: editSource,
+		},
+		{
+			name: "ReplaceParam",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				mustEdit(t, e.ReplaceParam(doc.Children[0].(*Statement), 0, NewInt(16)))
+				mustEdit(t, e.ReplaceParam(doc.Children[2].(*Section), 0, NewString("example.com")))
+			},
+			want: strings.NewReplacer(
+				"workers 8;", "workers 16;",
+				"server go.spiff.io", `server "example.com"`,
+			).Replace(editSource),
+		},
+		{
+			name: "ReplaceParamMultiline",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				listen := doc.Children[2].(*Section).Children[0].(*Statement)
+				mustEdit(t, e.ReplaceParam(listen, 0, NewMap(NewMapEntry("port", NewInt(80)))))
+			},
+			want: strings.Replace(editSource, "listen 0.0.0.0:80;",
+				"listen #{\n        port 80\n    };", 1),
+		},
+		{
+			name: "Rename",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				mustEdit(t, e.Rename(doc.Children[1].(*Statement), "read-timeout"))
+			},
+			want: strings.Replace(editSource, "timeout 30s;", "read-timeout 30s;", 1),
+		},
+		{
+			name: "InsertBefore",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				server := doc.Children[2].(*Section)
+				mustEdit(t, e.InsertChild(server, 1, NewStatement("listen", NewWord("0.0.0.0:443"))))
+			},
+			want: strings.Replace(editSource, "\n    // Proxy",
+				"\n    listen 0.0.0.0:443;\n    // Proxy", 1),
+		},
+		{
+			name: "InsertAfter",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				mustEdit(t, e.InsertChild(doc, 1, NewStatement("threads", NewInt(2))))
+				mustEdit(t, e.InsertChild(doc, len(doc.Children), NewStatement("last")))
+			},
+			want: strings.NewReplacer(
+				"// per host\n", "// per host\nthreads 2;\n",
+				"inline { a 1; }\n", "inline { a 1; }\nlast;\n",
+			).Replace(editSource),
+		},
+		{
+			name: "InsertSection",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				proxy := doc.Children[2].(*Section).Children[1].(*Section)
+				child := NewSection("headers", nil, NewStatement("x-foo", NewWord("bar")))
+				mustEdit(t, e.InsertChild(proxy, 1, child))
+			},
+			want: strings.Replace(editSource, "strip-x-headers yes;\n",
+				"strip-x-headers yes;\n        headers {\n            x-foo bar;\n        }\n", 1),
+		},
+		{
+			name: "InsertIntoEmpty",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				empty := doc.Children[2].(*Section).Children[2].(*Section)
+				mustEdit(t, e.InsertChild(empty, 0, NewStatement("x", NewInt(1))))
+			},
+			want: strings.Replace(editSource, "empty {}", "empty {\n        x 1;\n    }", 1),
+		},
+		{
+			name: "InsertInline",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				inline := doc.Children[3].(*Section)
+				mustEdit(t, e.InsertChild(inline, 1, NewStatement("b", NewInt(2))))
+				mustEdit(t, e.InsertChild(inline, 0, NewStatement("z")))
+			},
+			want: strings.Replace(editSource, "inline { a 1; }", "inline { z; a 1; b 2; }", 1),
+		},
+		{
+			name: "DeleteStatement",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				mustEdit(t, e.Delete(doc.Children[0]))
+			},
+			want: strings.Replace(editSource, "workers 8; // per host\n", "", 1),
+		},
+		{
+			name: "DeleteSectionWithComment",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				mustEdit(t, e.Delete(doc.Children[2].(*Section).Children[1]))
+			},
+			want: strings.Replace(editSource, `    // Proxy to the redirect service.
+    proxy unix:///var/run/go-redirect.sock {
+        strip-x-headers yes;
+    }
+`, "", 1),
+		},
+		{
+			name: "DeleteInline",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				mustEdit(t, e.Delete(doc.Children[3].(*Section).Children[0]))
+			},
+			want: strings.Replace(editSource, "inline { a 1; }", "inline {  }", 1),
+		},
+		{
+			name: "DeleteParam",
+			edit: func(t *testing.T, e *Editor, doc *Document) {
+				mustEdit(t, e.Delete(doc.Children[1].(*Statement).Params[0]))
+			},
+			want: strings.Replace(editSource, "timeout 30s;", "timeout;", 1),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := NewEditor([]byte(editSource))
+			if err != nil {
+				t.Fatalf("NewEditor() error = %v; want nil", err)
+			}
+			c.edit(t, e, e.Document())
+			got, err := e.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v; want nil", err)
+			}
+			if string(got) != c.want {
+				t.Fatalf("Bytes() =\n%s\nwant\n%s", got, c.want)
+			}
+			if _, err := parse(string(got)); err != nil {
+				t.Errorf("edited source does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestEditorOrder(t *testing.T) {
+	// An insertion at the start of a replaced range is written before the replacement, whichever
+	// edit is made first.
+	const want = "first;\nthreads 8;\n"
+	for _, insertFirst := range []bool{true, false} {
+		e, err := NewEditor([]byte("workers 8;\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmt := e.Document().Children[0].(*Statement)
+		insert := func() { mustEdit(t, e.InsertChild(e.Document(), 0, NewStatement("first"))) }
+		if insertFirst {
+			insert()
+		}
+		mustEdit(t, e.Rename(stmt, "threads"))
+		if !insertFirst {
+			insert()
+		}
+		got, err := e.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes() error = %v; want nil", err)
+		}
+		if string(got) != want {
+			t.Errorf("Bytes() = %q; want %q", got, want)
+		}
+	}
+}
+
+func mustEdit(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("edit error = %v; want nil", err)
+	}
+}
+
+func TestEditorInferIndent(t *testing.T) {
+	e, err := NewEditor([]byte("a {\n\tb {}\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := e.Document().Children[0].(*Section).Children[0].(*Section)
+	mustEdit(t, e.InsertChild(b, 0, NewStatement("c")))
+	got, _ := e.Bytes()
+	if want := "a {\n\tb {\n\t\tc;\n\t}\n}\n"; string(got) != want {
+		t.Errorf("Bytes() = %q; want %q", got, want)
+	}
+
+	e, _ = NewEditor(nil)
+	mustEdit(t, e.InsertChild(e.Document(), 0, NewStatement("first")))
+	got, _ = e.Bytes()
+	if want := "first;\n"; string(got) != want {
+		t.Errorf("Bytes() = %q; want %q", got, want)
+	}
+}
+
+func TestEditorErrors(t *testing.T) {
+	e, err := NewEditor([]byte("a 1;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := e.Document().Children[0].(*Statement)
+
+	if err := e.ReplaceParam(stmt, 1, NewInt(2)); err == nil {
+		t.Error("ReplaceParam() out of range = nil; want error")
+	}
+	if err := e.Rename(stmt, "two words"); err == nil {
+		t.Error("Rename() invalid name = nil; want error")
+	}
+	if err := e.InsertChild(e.Document(), 0, NewInt(1)); err == nil {
+		t.Error("InsertChild() literal = nil; want error")
+	}
+
+	mustEdit(t, e.ReplaceParam(stmt, 0, NewInt(2)))
+	mustEdit(t, e.Delete(stmt))
+	if _, err := e.Bytes(); err == nil {
+		t.Error("Bytes() with overlapping edits = nil; want error")
+	}
+
+	if _, err := NewEditor([]byte("a {")); err == nil {
+		t.Error("NewEditor() invalid source = nil; want error")
+	}
+}
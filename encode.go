@@ -0,0 +1,448 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/regexp"
+)
+
+// Marshal returns a Document encoding v.
+//
+// v must be a struct or a map with string keys, or a pointer to one. Marshal is the inverse of
+// Unmarshal: struct fields and map entries are encoded as statements and sections named after the
+// field's codf tag or lowercased field name, or the map key, as follows:
+//
+//   - Booleans, strings, numbers, durations, periods, times, regexps, and values implementing
+//     encoding.TextMarshaler are encoded as a statement with a single parameter. Strings are
+//     written as barewords where possible and quoted otherwise, and times as timestamps.
+//   - Slices of the above are encoded as a single statement whose parameters are the elements of
+//     the slice. Slices of any other type encode one statement or section per element. Arrays
+//     are encoded as a statement with a single array parameter.
+//   - Structs are encoded as sections whose parameters are the struct's fields tagged with
+//     "param" or "params", followed by its remaining fields as children. Structs with only
+//     parameter fields are encoded as statements.
+//   - Maps with string keys are encoded as a statement with a single map parameter. If the map's
+//     values are structs or other non-literal types, the map is encoded as a section with one
+//     child per entry instead.
+//   - Nested slices and maps inside of parameters are encoded as arrays and maps.
+//   - Fields holding a Node are included in the Document as-is.
+//
+// Nil pointers, interfaces, slices, and maps are omitted, as are fields tagged with the
+// "omitempty" option that hold a zero value. Map entries are written in order of their keys.
+func Marshal(v any) (*Document, error) {
+	var e encodeState
+	children, err := e.encodeBody(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Children: children}, nil
+}
+
+// Encoder writes Go values to an output stream as codf documents.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder allocates a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the codf encoding of v to the Encoder's stream, followed by a newline. See Marshal
+// for details on how v is encoded.
+func (e *Encoder) Encode(v any) error {
+	doc, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(e.w, doc.String()+"\n")
+	return err
+}
+
+// UnsupportedTypeError is returned by Marshal when it encounters a value whose type it cannot
+// encode.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "cannot marshal value of type " + e.Type.String()
+}
+
+// UnsupportedValueError is returned by Marshal when it encounters a value it cannot encode, such as
+// an infinite float or a name that cannot be written as a bareword.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "cannot marshal value: " + e.Str
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type encodeState struct{}
+
+// encodeBody encodes the fields of a struct or the entries of a map as statements and sections.
+func (e *encodeState) encodeBody(v reflect.Value) ([]Node, error) {
+	v = elemValue(v)
+	if !v.IsValid() {
+		return []Node{}, nil
+	}
+
+	children := []Node{}
+	switch v.Kind() {
+	case reflect.Struct:
+		info := cachedStructInfo(v.Type())
+		for _, f := range info.fields {
+			fv, ok := fieldValue(v, f.index)
+			if !ok || (f.omitEmpty && fv.IsZero()) {
+				continue
+			}
+			nodes, err := e.encodeNode(f.name, fv)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, nodes...)
+		}
+		return children, nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		for _, key := range sortedKeys(v) {
+			nodes, err := e.encodeNode(key.String(), v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, nodes...)
+		}
+		return children, nil
+	}
+	return nil, &UnsupportedTypeError{Type: v.Type()}
+}
+
+// encodeNode encodes v as zero or more statements or sections with the given name.
+func (e *encodeState) encodeNode(name string, v reflect.Value) ([]Node, error) {
+	if node, ok := nodeValue(v); ok {
+		switch node.(type) {
+		case *Statement, *Section, *Document:
+			return []Node{node}, nil
+		}
+	}
+
+	// Whether v is encoded as a parameter depends on its declared type, as in Unmarshal, so that
+	// an interface holding a slice is still encoded as a single array.
+	orig := v
+	v = elemValue(v)
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if !isWord(name) {
+		return nil, &UnsupportedValueError{Value: v, Str: fmt.Sprintf("invalid statement name %q", name)}
+	}
+
+	if isMarshalExprType(orig.Type()) || v.Kind() == reflect.Array {
+		expr, err := e.encodeExpr(orig)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{NewStatement(name, expr)}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if isMarshalExprType(v.Type().Elem()) {
+			params, err := e.encodeExprs(v)
+			if err != nil {
+				return nil, err
+			}
+			return []Node{NewStatement(name, params...)}, nil
+		}
+		var nodes []Node
+		for i := 0; i < v.Len(); i++ {
+			elemNodes, err := e.encodeNode(name, v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, elemNodes...)
+		}
+		return nodes, nil
+
+	case reflect.Struct:
+		node, err := e.encodeStruct(name, v)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{node}, nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if isMarshalExprType(v.Type().Elem()) {
+			m, err := e.encodeExpr(v)
+			if err != nil {
+				return nil, err
+			}
+			return []Node{NewStatement(name, m)}, nil
+		}
+		children, err := e.encodeBody(v)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{NewSection(name, nil, children...)}, nil
+	}
+
+	return nil, &UnsupportedTypeError{Type: v.Type()}
+}
+
+// encodeStruct encodes a struct as a section or, if it has no fields other than parameters,
+// a statement.
+func (e *encodeState) encodeStruct(name string, v reflect.Value) (Node, error) {
+	info := cachedStructInfo(v.Type())
+
+	var params []ExprNode
+	for _, f := range info.params {
+		fv, ok := fieldValue(v, f.index)
+		if !ok || !elemValue(fv).IsValid() || (f.omitEmpty && fv.IsZero()) {
+			continue
+		}
+		if f.params {
+			exprs, err := e.encodeExprs(elemValue(fv))
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, exprs...)
+			continue
+		}
+		expr, err := e.encodeExpr(fv)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, expr)
+	}
+
+	if len(info.fields) == 0 {
+		return NewStatement(name, params...), nil
+	}
+
+	children, err := e.encodeBody(v)
+	if err != nil {
+		return nil, err
+	}
+	return NewSection(name, params, children...), nil
+}
+
+// encodeExprs encodes each element of the slice or array v as an ExprNode.
+func (e *encodeState) encodeExprs(v reflect.Value) ([]ExprNode, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if k := v.Kind(); k != reflect.Slice && k != reflect.Array {
+		return nil, &UnsupportedTypeError{Type: v.Type()}
+	}
+	exprs := make([]ExprNode, v.Len())
+	for i := range exprs {
+		expr, err := e.encodeExpr(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		exprs[i] = expr
+	}
+	return exprs, nil
+}
+
+// encodeExpr encodes v as a single ExprNode.
+func (e *encodeState) encodeExpr(v reflect.Value) (ExprNode, error) {
+	if node, ok := nodeValue(v); ok {
+		if expr, ok := node.(ExprNode); ok {
+			return expr, nil
+		}
+	}
+
+	v = elemValue(v)
+	if !v.IsValid() {
+		return nil, &UnsupportedValueError{Value: v, Str: "cannot encode nil as a parameter"}
+	}
+
+	switch v.Type() {
+	case durationType:
+		return NewDuration(time.Duration(v.Int())), nil
+	case periodType:
+		p := v.Interface().(Period)
+		if lit := NewPeriod(p); lit != nil {
+			return lit, nil
+		}
+		return nil, &UnsupportedValueError{Value: v, Str: "negative period " + p.String()}
+	case timeType:
+		t := v.Interface().(time.Time)
+		if lit := NewTime(t); lit != nil {
+			return lit, nil
+		}
+		return nil, &UnsupportedValueError{Value: v, Str: fmt.Sprintf("time %v has a year outside of 0 to 9999", t)}
+	case bigIntType.Elem():
+		x := v.Interface().(big.Int)
+		return NewBigInt(&x), nil
+	case bigIntType:
+		return NewBigInt(v.Interface().(*big.Int)), nil
+	case bigFloatType:
+		f := v.Interface().(*big.Float)
+		if f.IsInf() {
+			return nil, &UnsupportedValueError{Value: v, Str: f.String()}
+		}
+		return NewBigFloat(f), nil
+	case bigRatType:
+		return NewRat(v.Interface().(*big.Rat)), nil
+	case regexpType:
+		return NewRegexp(v.Interface().(*regexp.Regexp)), nil
+	}
+
+	if implements(v.Type(), textMarshalerType) {
+		text, err := textMarshaler(v).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return NewWord(string(text)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return NewBool(v.Bool()), nil
+
+	case reflect.String:
+		return NewWord(v.String()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInt(v.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewBigInt(new(big.Int).SetUint64(v.Uint())), nil
+
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, &UnsupportedValueError{Value: v, Str: strconv.FormatFloat(f, 'g', -1, 64)}
+		}
+		return NewFloat(f), nil
+
+	case reflect.Slice, reflect.Array:
+		elems, err := e.encodeExprs(v)
+		if err != nil {
+			return nil, err
+		}
+		return NewArray(elems...), nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		keys := sortedKeys(v)
+		entries := make([]*MapEntry, len(keys))
+		for i, key := range keys {
+			val, err := e.encodeExpr(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			entries[i] = NewMapEntry(key.String(), val)
+		}
+		return NewMap(entries...), nil
+	}
+
+	return nil, &UnsupportedTypeError{Type: v.Type()}
+}
+
+// textMarshaler returns v as an encoding.TextMarshaler. If only a pointer to v's type implements it,
+// the pointer is taken to v or, if v is not addressable, to a copy of v.
+func textMarshaler(v reflect.Value) encoding.TextMarshaler {
+	if v.Type().Implements(textMarshalerType) {
+		return v.Interface().(encoding.TextMarshaler)
+	}
+	if !v.CanAddr() {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		return ptr.Interface().(encoding.TextMarshaler)
+	}
+	return v.Addr().Interface().(encoding.TextMarshaler)
+}
+
+// isMarshalExprType returns true if values of type t are encoded as a single ExprNode.
+func isMarshalExprType(t reflect.Type) bool {
+	return isLiteralType(t) || implements(t, textMarshalerType)
+}
+
+// nodeValue returns the value held by v if it is a non-nil Node.
+func nodeValue(v reflect.Value) (Node, bool) {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || !v.CanInterface() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return nil, false
+	}
+	node, ok := v.Interface().(Node)
+	return node, ok
+}
+
+// elemValue follows pointers and interfaces in v until it reaches a concrete value. It returns
+// the zero Value if it encounters a nil pointer, interface, slice, or map.
+func elemValue(v reflect.Value) reflect.Value {
+	for v.IsValid() {
+		switch v.Kind() {
+		case reflect.Pointer:
+			if v.IsNil() {
+				return reflect.Value{}
+			} else if v.Type().Implements(textMarshalerType) || v.Type() == bigIntType ||
+				v.Type() == bigFloatType || v.Type() == bigRatType || v.Type() == regexpType {
+				return v
+			}
+			v = v.Elem()
+		case reflect.Interface:
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		case reflect.Slice, reflect.Map:
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			return v
+		default:
+			return v
+		}
+	}
+	return v
+}
+
+// fieldValue returns the field of v at index. It returns false if the field is inside of a nil
+// embedded struct pointer.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
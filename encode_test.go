@@ -0,0 +1,254 @@
+package codf
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/regexp"
+)
+
+func TestMarshalExample(t *testing.T) {
+	in := struct {
+		Servers []decodeServer `codf:"server"`
+	}{
+		Servers: []decodeServer{{
+			Name:    "go.spiff.io",
+			Listen:  []string{"0.0.0.0:80", "0.0.0.0:443"},
+			Control: "unix:///var/run/httpd.sock",
+			Proxy: []decodeProxy{
+				{Addr: "unix:///var/run/go-redirect.sock", StripXHeaders: true},
+			},
+			Cache: &decodeCache{
+				Kind: "memory",
+				Size: 64e6,
+				Expire: []decodeExpire{
+					{After: 10 * time.Minute, Codes: []int{404}},
+					{After: time.Hour, Codes: []int{301, 302}},
+				},
+			},
+		}},
+	}
+
+	doc, err := Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal() = %v; want nil", err)
+	}
+
+	const want = `server go.spiff.io {
+	listen 0.0.0.0:80 0.0.0.0:443;
+	control unix:///var/run/httpd.sock;
+	proxy unix:///var/run/go-redirect.sock {
+		strip-x-headers true;
+		log-access false;
+	}
+	cache memory 64000000 {
+		expire 10m0s 404;
+		expire 1h0m0s 301 302;
+	}
+}`
+	if got := doc.String(); got != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+
+	var out struct {
+		Servers []decodeServer `codf:"server"`
+	}
+	if err := Unmarshal(mustParse(t, doc.String()), &out); err != nil {
+		t.Fatalf("Unmarshal() = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Unmarshal(Marshal()) =\n%#v\nwant\n%#v", out, in)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	type values struct {
+		Str      string
+		Quoted   string
+		Empty    string
+		Bool     bool
+		Int      int
+		Uint     uint8
+		F64      float64
+		F32      float32
+		Dur      time.Duration
+		Micro    time.Duration
+		Time     time.Time
+		Period   Period
+		Days     time.Duration
+		BigInt   *big.Int
+		BigRat   *big.Rat
+		Rx       *regexp.Regexp
+		Ary      []int
+		Nested   [][]string
+		Fixed    [2]string
+		KV       map[string]int
+		Sections map[string][]int
+		Addr     netip.Addr
+		Omitted  *string
+		Zero     int `codf:",omitempty"`
+	}
+
+	in := values{
+		Str:      "bare",
+		Quoted:   "has spaces; and \"quotes\"",
+		Bool:     true,
+		Int:      -12,
+		Uint:     0xff,
+		F64:      3,
+		F32:      2.5,
+		Dur:      90 * time.Minute,
+		Micro:    1500 * time.Nanosecond,
+		Time:     time.Date(2026, 10, 16, 4, 0, 0, 5e8, time.UTC),
+		Period:   Period{Years: 1, Days: 14, Time: 90 * time.Minute},
+		Days:     30 * 24 * time.Hour,
+		BigInt:   new(big.Int).Lsh(big.NewInt(1), 100),
+		BigRat:   big.NewRat(3, 4),
+		Rx:       regexp.MustCompile(`^/foo/(\d+)$`),
+		Ary:      []int{1, 2, 3},
+		Nested:   [][]string{{"a", "b"}, {"c"}},
+		Fixed:    [2]string{"x", "y z"},
+		KV:       map[string]int{"b": 2, "a": 1},
+		Sections: map[string][]int{"a": {1, 3}, "b": {2}},
+		Addr:     netip.MustParseAddr("10.0.0.1"),
+	}
+
+	doc, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() = %v; want nil", err)
+	}
+	if strings.Contains(doc.String(), "zero") {
+		t.Errorf("Marshal() =\n%s\nwant no zero statement", doc)
+	}
+
+	var out values
+	if err := Unmarshal(mustParse(t, doc.String()), &out); err != nil {
+		t.Fatalf("Unmarshal() = %v; want nil\n%s", err, doc)
+	}
+
+	// Regexps are compared by their source.
+	if got, want := out.Rx.String(), in.Rx.String(); got != want {
+		t.Errorf("Rx = %q; want %q", got, want)
+	}
+	out.Rx, in.Rx = nil, nil
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Unmarshal(Marshal()) =\n%#v\nwant\n%#v\nsource:\n%s", out, in, doc)
+	}
+}
+
+func TestMarshalFloatRoundTrip(t *testing.T) {
+	type floats struct {
+		X float64   `codf:"x"`
+		Y []float64 `codf:"y"`
+	}
+	for _, f := range []float64{2e6, 1e21, 1.5e6, 1e-5, 0.00001234, -3e-9, 1e300, 5e-324} {
+		in := floats{X: f, Y: []float64{f, -f}}
+		doc, err := Marshal(&in)
+		if err != nil {
+			t.Fatalf("Marshal(%v) = %v; want nil", f, err)
+		}
+
+		var out floats
+		if err := Unmarshal(mustParse(t, doc.String()), &out); err != nil {
+			t.Fatalf("Unmarshal() = %v; want nil\n%s", err, doc)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("Unmarshal(Marshal()) = %#v; want %#v\nsource:\n%s", out, in, doc)
+		}
+	}
+}
+
+func TestMarshalNodes(t *testing.T) {
+	in := struct {
+		Stmt  *Statement
+		Param ExprNode
+		Any   any
+	}{
+		Stmt:  mustParse(t, "raw 1 2;").Children[0].(*Statement),
+		Param: mustParse(t, "x [a b];").Children[0].(*Statement).Params[0],
+		Any:   []any{"a", 1, map[string]any{"k": true}},
+	}
+
+	doc, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() = %v; want nil", err)
+	}
+
+	const want = "raw 1 2;\nparam [a b];\nany [a 1 #{\n\t\tk true\n\t}];"
+	if got := doc.String(); got != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// ptrText implements encoding.TextMarshaler with a pointer receiver.
+type ptrText struct{ S string }
+
+func (p *ptrText) MarshalText() ([]byte, error) {
+	return []byte("text:" + p.S), nil
+}
+
+func TestMarshalPointerTextMarshaler(t *testing.T) {
+	// Neither the struct passed by value nor the map values are addressable.
+	in := struct {
+		Field ptrText            `codf:"field"`
+		List  []ptrText          `codf:"list"`
+		Map   map[string]ptrText `codf:"map"`
+	}{
+		Field: ptrText{"a"},
+		List:  []ptrText{{"b"}},
+		Map:   map[string]ptrText{"k": {"c"}},
+	}
+
+	doc, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() = %v; want nil", err)
+	}
+
+	const want = "field text:a;\nlist text:b;\nmap #{\n\tk text:c\n};"
+	if got := doc.String(); got != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		in   any
+		err  any
+	}{
+		{"NotStruct", 1, new(*UnsupportedTypeError)},
+		{"Chan", struct{ C chan int }{make(chan int)}, new(*UnsupportedTypeError)},
+		{"IntKeys", map[int]int{1: 1}, new(*UnsupportedTypeError)},
+		{"BadName", map[string]int{"two words": 1}, new(*UnsupportedValueError)},
+		{"Inf", struct{ F float64 }{math.Inf(1)}, new(*UnsupportedValueError)},
+		{"NegativePeriod", struct{ P Period }{Period{Months: -1}}, new(*UnsupportedValueError)},
+		{"TimeYear", struct{ T time.Time }{time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)}, new(*UnsupportedValueError)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Marshal(c.in)
+			if err == nil || !errors.As(err, c.err) {
+				t.Fatalf("Marshal() = %v; want %T", err, reflect.ValueOf(c.err).Elem().Interface())
+			}
+		})
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var buf strings.Builder
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(map[string]any{"name": "foo", "port": 80}); err != nil {
+		t.Fatalf("Encode() = %v; want nil", err)
+	}
+	const want = "name foo;\nport 80;\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode() wrote %q; want %q", got, want)
+	}
+}
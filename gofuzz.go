@@ -5,7 +5,6 @@ package codf // import "go.spiff.io/codf"
 
 import (
 	"bytes"
-	"fmt"
 )
 
 func Fuzz(b []byte) (rc int) {
@@ -27,23 +26,30 @@ func FuzzLexer(b []byte) (rc int) {
 
 type fuzzWalker struct{}
 
+var (
+	fuzzStatements = []string{
+		"server_name", "listen", "proxy_pass", "add_header",
+		"user", "daemon", "log_format", "acces_log", "error_log",
+	}
+	fuzzSections = []string{"http", "server", "location", "upstream", "stream"}
+)
+
 func (w *fuzzWalker) Statement(st *Statement) error {
-	switch st.Name() {
-	case "server_name", "listen", "proxy_pass", "add_header",
-		"user", "daemon", "log_format", "acces_log", "error_log":
-		return nil
-	default:
-		return fmt.Errorf("invalid statement name: %s", st.Name())
+	for _, name := range fuzzStatements {
+		if st.Name() == name {
+			return nil
+		}
 	}
+	return UnknownName(st, fuzzStatements)
 }
 
 func (w *fuzzWalker) EnterSection(sec *Section) (Walker, error) {
-	switch sec.Name() {
-	case "http", "server", "location", "upstream", "stream":
-		return w, nil
-	default:
-		return nil, fmt.Errorf("invalid section name: %s", sec.Name())
+	for _, name := range fuzzSections {
+		if sec.Name() == name {
+			return w, nil
+		}
 	}
+	return nil, UnknownName(sec, fuzzSections)
 }
 
 func FuzzWalker(b []byte) (rc int) {
@@ -0,0 +1,196 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// DefaultIncludeName is the name of the statements expanded by an Includer if its Name is empty.
+const DefaultIncludeName = "include"
+
+// Includer expands include statements in a Document by parsing the files they name from an
+// fs.FS. For example, given the statement
+//
+//	include conf.d/*.conf;
+//
+// an Includer replaces the statement with a *Document for each file matching the pattern, in
+// lexical order, so that the files' statements and sections take the include's place in its
+// parent. Walk, Unmarshal, and the printer treat these Documents as part of their parent, so
+// included statements are seen as though they were written in place of the include.
+//
+// Each parameter of an include statement is a pattern, as accepted by fs.Glob. Patterns are
+// relative to the directory of the file containing the include, or to the root of the FS if they
+// begin with a slash. A pattern without glob metacharacters must name an existing file, while
+// a pattern with them may match no files.
+//
+// Included files are expanded recursively, and including a file that is already being included
+// is an error. The Name of each included Document and the Location.Name of its tokens are set to
+// the file's path in the FS.
+type Includer struct {
+	// FS is the file system included files are read from.
+	FS fs.FS
+
+	// Name is the name of include statements. If empty, it defaults to DefaultIncludeName.
+	Name string
+
+	// LexerFlags and ParserFlags are the flags used to lex and parse included files.
+	LexerFlags  LexerFlag
+	ParserFlags ParserFlag
+}
+
+// IncludeError is returned by an Includer when it cannot expand an include statement.
+type IncludeError struct {
+	// Stmt is the include statement.
+	Stmt *Statement
+	// Err is the error that occurred when including a file.
+	Err error
+}
+
+func (e *IncludeError) Error() string {
+	return "[" + e.Stmt.Token().Start.String() + "] " + e.Stmt.Name() + ": " + e.Err.Error()
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// ErrIncludeCycle is returned, wrapped in an IncludeError, when a file includes itself, directly
+// or through other files.
+var ErrIncludeCycle = errors.New("include cycle")
+
+// ParseFile parses the named file from the Includer's FS and expands its include statements.
+func (in *Includer) ParseFile(name string) (*Document, error) {
+	return in.parseFile(name, nil)
+}
+
+// Expand expands the include statements in doc, including those in its sections. The directory of
+// doc.Name is used to resolve relative patterns. Expand stops at the first error.
+func (in *Includer) Expand(doc *Document) error {
+	var stack []string
+	if doc.Name != "" {
+		stack = []string{path.Clean(strings.TrimPrefix(doc.Name, "/"))}
+	}
+	return in.expand(doc, doc.Name, stack)
+}
+
+func (in *Includer) parseFile(name string, stack []string) (*Document, error) {
+	src, err := fs.ReadFile(in.FS, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read from a bytes.Reader so that the lexer uses name for locations rather than the name
+	// of an underlying file.
+	lex := NewLexer(bytes.NewReader(src))
+	lex.Name = name
+	lex.Flags = in.LexerFlags
+
+	p := NewParser()
+	p.Flags = in.ParserFlags
+	doc := &Document{Name: name, Children: []Node{}}
+	if err := p.ParseInContext(lex, doc); err != nil {
+		return nil, err
+	}
+
+	stack = append(stack[:len(stack):len(stack)], name)
+	if err := in.expand(doc, name, stack); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// expand replaces include statements among the children of parent, and their children, with the
+// files they include. file is the name of the file parent was read from and stack is the list of
+// files currently being included.
+func (in *Includer) expand(parent ParentNode, file string, stack []string) error {
+	children := make([]Node, 0, len(parent.Nodes()))
+	for _, child := range parent.Nodes() {
+		switch node := child.(type) {
+		case *Section:
+			if err := in.expand(node, file, stack); err != nil {
+				return err
+			}
+		case *Statement:
+			if node.Name() == in.name() {
+				docs, err := in.include(node, file, stack)
+				if err != nil {
+					return err
+				}
+				children = append(children, docs...)
+				continue
+			}
+		}
+		children = append(children, child)
+	}
+
+	switch parent := parent.(type) {
+	case *Document:
+		parent.Children = children
+	case *Section:
+		parent.Children = children
+	}
+	return nil
+}
+
+// include parses the files named by an include statement.
+func (in *Includer) include(stmt *Statement, file string, stack []string) ([]Node, error) {
+	if len(stmt.Params) == 0 {
+		return nil, &IncludeError{Stmt: stmt, Err: errors.New("expected at least one file pattern")}
+	}
+
+	docs := []Node{}
+	for _, param := range stmt.Params {
+		pattern, ok := String(param)
+		if !ok {
+			return nil, &IncludeError{Stmt: stmt, Err: fmt.Errorf("expected a file pattern; got %v", param.Token().Kind)}
+		}
+		if strings.HasPrefix(pattern, "/") {
+			pattern = path.Clean(strings.TrimLeft(pattern, "/"))
+		} else {
+			pattern = path.Join(path.Dir(file), pattern)
+		}
+
+		matches, err := fs.Glob(in.FS, pattern)
+		if err == nil && len(matches) == 0 && !hasGlobMeta(pattern) {
+			_, err = fs.Stat(in.FS, pattern)
+		}
+		if err != nil {
+			return nil, &IncludeError{Stmt: stmt, Err: err}
+		}
+
+		for _, match := range matches {
+			for i, name := range stack {
+				if name == match {
+					cycle := strings.Join(stack[i:], " -> ") + " -> " + match
+					return nil, &IncludeError{Stmt: stmt, Err: fmt.Errorf("%w: %s", ErrIncludeCycle, cycle)}
+				}
+			}
+
+			doc, err := in.parseFile(match, stack)
+			if err != nil {
+				var ie *IncludeError
+				if !errors.As(err, &ie) {
+					err = &IncludeError{Stmt: stmt, Err: err}
+				}
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+func (in *Includer) name() string {
+	if in.Name == "" {
+		return DefaultIncludeName
+	}
+	return in.Name
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
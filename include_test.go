@@ -0,0 +1,124 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseInContext(t *testing.T) {
+	doc := mustParse(t, "server { listen 80; }\nlast;")
+	sect := doc.Children[0].(*Section)
+
+	p := NewParser()
+	lex := NewLexer(strings.NewReader("listen 443;\nproxy a { b; }"))
+	if err := p.ParseInContext(lex, sect); err != nil {
+		t.Fatalf("ParseInContext() error = %v; want nil", err)
+	}
+
+	objectsEqual(t, "", doc, mustParse(t, "server { listen 80; listen 443; proxy a { b; } }\nlast;"))
+	if len(p.Document().Children) != 0 {
+		t.Errorf("parser document has %d children; want 0", len(p.Document().Children))
+	}
+
+	// Failed parses and stray closing braces leave the parent unchanged.
+	for _, src := range []string{"a; b {", "a; }", "a [1;"} {
+		err := p.ParseInContext(NewLexer(strings.NewReader(src)), sect)
+		if err == nil {
+			t.Errorf("ParseInContext(%q) error = nil; want error", src)
+		}
+		if len(sect.Children) != 3 {
+			t.Errorf("ParseInContext(%q) modified the section: %v", src, sect)
+		}
+	}
+
+	// The parser is still usable after ParseInContext.
+	if err := p.Parse(NewLexer(strings.NewReader("x;"))); err != nil {
+		t.Fatalf("Parse() error = %v; want nil", err)
+	}
+	objectsEqual(t, "", p.Document(), mustParse(t, "x;"))
+}
+
+func TestIncluder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.conf":           {Data: []byte("workers 4;\nhttp {\n    include conf.d/*.conf;\n}\ninclude /extra.conf;\n")},
+		"conf.d/a.conf":       {Data: []byte("server a;\n")},
+		"conf.d/b.conf":       {Data: []byte("server b { include ../nested/c.conf; }\n")},
+		"conf.d/skip.txt":     {Data: []byte("invalid {")},
+		"nested/c.conf":       {Data: []byte("root /srv;\n")},
+		"extra.conf":          {Data: []byte("// nothing but a comment\n")},
+		"cycle/one.conf":      {Data: []byte("include two.conf;")},
+		"cycle/two.conf":      {Data: []byte("\ninclude one.conf;")},
+		"broken/main.conf":    {Data: []byte("include bad.conf;")},
+		"broken/bad.conf":     {Data: []byte("a {")},
+		"missing/main.conf":   {Data: []byte("include none.conf; include none/*.conf;")},
+		"badparam/main.conf":  {Data: []byte("include 1;")},
+		"nomatches/main.conf": {Data: []byte("include none/*.conf;")},
+	}
+	in := &Includer{FS: fsys}
+
+	doc, err := in.ParseFile("main.conf")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v; want nil", err)
+	}
+
+	const want = "workers 4;\nhttp {\n\tserver a;\n\tserver b {\n\t\troot /srv;\n\t}\n}"
+	if got := doc.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	http := doc.Children[1].(*Section)
+	a := http.Children[0].(*Document)
+	if a.Name != "conf.d/a.conf" {
+		t.Errorf("included Name = %q; want %q", a.Name, "conf.d/a.conf")
+	}
+	root := http.Children[1].(*Document).Children[0].(*Section).Children[0].(*Document).Children[0]
+	if got := root.Token().Start.String(); got != "nested/c.conf:1:1:0" {
+		t.Errorf("root location = %s; want nested/c.conf:1:1:0", got)
+	}
+
+	if _, err := in.ParseFile("nomatches/main.conf"); err != nil {
+		t.Errorf("ParseFile() with no glob matches error = %v; want nil", err)
+	}
+
+	errCases := []struct {
+		file string
+		msg  string
+	}{
+		{"cycle/one.conf", "[cycle/two.conf:2:1:1] include: include cycle: cycle/one.conf -> cycle/two.conf -> cycle/one.conf"},
+		{"broken/main.conf", "[broken/main.conf:1:1:0] include: [broken/bad.conf:1:4:3] unexpected EOF: expected end of section \"a\" beginning at broken/bad.conf:1:1:0"},
+		{"missing/main.conf", "[missing/main.conf:1:1:0] include: open missing/none.conf: file does not exist"},
+		{"badparam/main.conf", "[badparam/main.conf:1:1:0] include: expected a file pattern; got integer"},
+	}
+	for _, c := range errCases {
+		_, err := in.ParseFile(c.file)
+		var ie *IncludeError
+		if !errors.As(err, &ie) {
+			t.Errorf("ParseFile(%q) error = %v; want *IncludeError", c.file, err)
+			continue
+		}
+		if err.Error() != c.msg {
+			t.Errorf("ParseFile(%q) error =\n%v\nwant\n%s", c.file, err, c.msg)
+		}
+	}
+
+	_, err = in.ParseFile("cycle/one.conf")
+	if !errors.Is(err, ErrIncludeCycle) {
+		t.Errorf("ParseFile() error = %v; want ErrIncludeCycle", err)
+	}
+}
+
+func TestIncluderExpand(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/site.conf": {Data: []byte("a 1;")},
+	}
+	doc := mustParseNamed(t, "etc/main.conf", "use site.conf;\nb 2;")
+	in := &Includer{FS: fsys, Name: "use"}
+	if err := in.Expand(doc); err != nil {
+		t.Fatalf("Expand() error = %v; want nil", err)
+	}
+	if got, want := doc.String(), "a 1;\nb 2;"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
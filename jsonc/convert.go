@@ -0,0 +1,262 @@
+package jsonc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/3JoB/codf"
+)
+
+// Converter converts plain JSON, such as existing configuration files, to codf documents. Unlike
+// Unmarshal, which decodes the JSON form of a codf document produced by Marshal, a Converter
+// accepts any JSON object.
+//
+// The members of the top-level object become the statements and sections of the document, in the
+// order they appear in the JSON. Each member becomes:
+//
+//   - A section, if its value is an object. Its members become the section's children.
+//   - One section per element, if its value is a non-empty array of objects.
+//   - A statement with no parameters, if its value is null.
+//   - A statement with the value as its only parameter, otherwise.
+//
+// Objects that are not converted to sections, such as elements of arrays of mixed values, become
+// maps. Arrays become arrays, strings become words if they can be written as words and quoted
+// strings otherwise, numbers become integers if they have no fraction or exponent and floats
+// otherwise, booleans become booleans, and null becomes the word null.
+//
+// Statement and section names must be words, so a member whose name cannot be written as a word,
+// such as a name with spaces or one that would be read as a number, is an error unless it is
+// converted to a map key.
+type Converter struct {
+	// Maps converts object members to statements with maps instead of to sections. Arrays of
+	// objects become statements with arrays of maps. The members of the top-level object are
+	// always converted to statements or sections.
+	Maps bool
+}
+
+// Convert reads a JSON object from r and converts it to a Document using the zero Converter.
+func Convert(r io.Reader) (*codf.Document, error) {
+	return (&Converter{}).Convert(r)
+}
+
+// Convert reads a JSON object from r and converts it to a Document. The JSON must contain exactly
+// one object.
+func (c *Converter) Convert(r io.Reader) (*codf.Document, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	root, err := readJSON(dec)
+	if err != nil {
+		return nil, fmt.Errorf("jsonc: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+		return nil, fmt.Errorf("jsonc: %w", err)
+	}
+	if root.tok != json.Delim('{') {
+		return nil, fmt.Errorf("jsonc: expected a JSON object; got %s", describeJSON(root.tok))
+	}
+
+	children, err := c.members("", root)
+	if err != nil {
+		return nil, err
+	}
+	return &codf.Document{Children: children}, nil
+}
+
+// members converts the members of obj to statements and sections. path is the JSON pointer of
+// obj, used in errors.
+func (c *Converter) members(path string, obj *jsonValue) ([]codf.Node, error) {
+	nodes := []codf.Node{}
+	for _, m := range obj.members {
+		mpath := path + "/" + escapePointer(m.name)
+		if codf.NewWord(m.name).Tok.Kind != codf.TWord {
+			return nil, fmt.Errorf("jsonc: %s: member name %q cannot be a statement or section name", mpath, m.name)
+		}
+
+		switch {
+		case m.value.tok == nil:
+			nodes = append(nodes, codf.NewStatement(m.name))
+		case !c.Maps && m.value.tok == json.Delim('{'):
+			children, err := c.members(mpath, m.value)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, codf.NewSection(m.name, nil, children...))
+		case !c.Maps && m.value.isObjectArray():
+			for i, elem := range m.value.elems {
+				children, err := c.members(mpath+"/"+strconv.Itoa(i), elem)
+				if err != nil {
+					return nil, err
+				}
+				nodes = append(nodes, codf.NewSection(m.name, nil, children...))
+			}
+		default:
+			val, err := c.value(mpath, m.value)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, codf.NewStatement(m.name, val))
+		}
+	}
+	return nodes, nil
+}
+
+// value converts v to an expression.
+func (c *Converter) value(path string, v *jsonValue) (codf.ExprNode, error) {
+	switch tok := v.tok.(type) {
+	case nil:
+		return codf.NewWord("null"), nil
+	case bool:
+		return codf.NewBool(tok), nil
+	case string:
+		return codf.NewWord(tok), nil
+	case json.Number:
+		return number(path, tok)
+	case json.Delim:
+		if tok == '[' {
+			elems := make([]codf.ExprNode, len(v.elems))
+			for i, elem := range v.elems {
+				var err error
+				if elems[i], err = c.value(path+"/"+strconv.Itoa(i), elem); err != nil {
+					return nil, err
+				}
+			}
+			return codf.NewArray(elems...), nil
+		}
+
+		entries := make([]*codf.MapEntry, len(v.members))
+		for i, m := range v.members {
+			val, err := c.value(path+"/"+escapePointer(m.name), m.value)
+			if err != nil {
+				return nil, err
+			}
+			entries[i] = codf.NewMapEntry(m.name, val)
+		}
+		return codf.NewMap(entries...), nil
+	}
+	return nil, fmt.Errorf("jsonc: %s: unexpected %s", path, describeJSON(v.tok))
+}
+
+// number converts a JSON number to an integer Literal if it has no fraction or exponent and to a
+// float Literal otherwise.
+func number(path string, n json.Number) (codf.ExprNode, error) {
+	text := n.String()
+	if !strings.ContainsAny(text, ".eE") {
+		i, ok := new(big.Int).SetString(text, 10)
+		if !ok {
+			return nil, fmt.Errorf("jsonc: %s: malformed integer %s", path, text)
+		}
+		return codf.NewBigInt(i), nil
+	}
+	f, ok := new(big.Float).SetPrec(codf.DefaultPrecision).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("jsonc: %s: malformed number %s", path, text)
+	}
+	lit := codf.NewBigFloat(f)
+	if lit == nil {
+		return nil, fmt.Errorf("jsonc: %s: number %s is out of range", path, text)
+	}
+	return lit, nil
+}
+
+// jsonValue is a JSON value read by readJSON. Unlike values decoded by encoding/json, objects keep
+// the order of their members.
+type jsonValue struct {
+	// tok is the value of a scalar or the opening delimiter of an object or array.
+	tok     json.Token
+	members []jsonMember
+	elems   []*jsonValue
+}
+
+type jsonMember struct {
+	name  string
+	value *jsonValue
+}
+
+// isObjectArray returns true if v is a non-empty array of objects.
+func (v *jsonValue) isObjectArray() bool {
+	if v.tok != json.Delim('[') || len(v.elems) == 0 {
+		return false
+	}
+	for _, elem := range v.elems {
+		if elem.tok != json.Delim('{') {
+			return false
+		}
+	}
+	return true
+}
+
+// readJSON reads the next JSON value from dec.
+func readJSON(dec *json.Decoder) (*jsonValue, error) {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	v := &jsonValue{tok: tok}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			v.members = append(v.members, jsonMember{name: key.(string), value: val})
+		}
+	case json.Delim('['):
+		for dec.More() {
+			elem, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			v.elems = append(v.elems, elem)
+		}
+	default:
+		return v, nil
+	}
+
+	// Read the closing delimiter.
+	if _, err := dec.Token(); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func describeJSON(tok json.Token) string {
+	switch tok := tok.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case json.Delim:
+		if tok == '[' {
+			return "array"
+		}
+	}
+	return "object"
+}
+
+// escapePointer escapes a member name for use in a JSON pointer (RFC 6901).
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,181 @@
+package jsonc
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/3JoB/codf"
+	"github.com/3JoB/codf/printer"
+)
+
+func TestConvert(t *testing.T) {
+	const src = `{
+	"user": "http",
+	"workers": 4,
+	"ratio": 0.75,
+	"daemon": false,
+	"pid": null,
+	"http": {
+		"server": [
+			{"name": "go.spiff.io", "listen": [80, 443], "tls": {"cert": "a b.pem"}},
+			{"name": "example.com", "listen": 8080}
+		],
+		"gzip": true
+	},
+	"big": 123456789012345678901234567890,
+	"mixed": [{"a": 1}, "b", null, 1e3],
+	"nested": [[1, 2], []],
+	"headers": {"Z-Last": "x", "A-First": "80"},
+	"empty": {},
+	"none": []
+}`
+
+	cases := []struct {
+		name string
+		conv Converter
+		want string
+	}{
+		{
+			name: "Sections",
+			want: `user http;
+workers 4;
+ratio 0.75;
+daemon false;
+pid;
+http {
+	server {
+		name go.spiff.io;
+		listen [80 443];
+		tls {
+			cert "a b.pem";
+		}
+	}
+	server {
+		name example.com;
+		listen 8080;
+	}
+	gzip true;
+}
+big 123456789012345678901234567890;
+mixed [#{a 1} b null 1000.0];
+nested [[1 2] []];
+headers {
+	Z-Last x;
+	A-First "80";
+}
+empty {}
+none [];
+`,
+		},
+		{
+			name: "Maps",
+			conv: Converter{Maps: true},
+			want: `user http;
+workers 4;
+ratio 0.75;
+daemon false;
+pid;
+http #{server [#{name go.spiff.io listen [80 443] tls #{cert "a b.pem"}} #{name example.com listen 8080}] gzip true};
+big 123456789012345678901234567890;
+mixed [#{a 1} b null 1000.0];
+nested [[1 2] []];
+headers #{Z-Last x A-First "80"};
+empty #{};
+none [];
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := c.conv.Convert(strings.NewReader(src))
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			var sb strings.Builder
+			if err := printer.Fprint(&sb, doc); err != nil {
+				t.Fatalf("Fprint() error = %v", err)
+			}
+			if got := sb.String(); got != c.want {
+				t.Errorf("Convert() =\n%s\nwant\n%s", got, c.want)
+			}
+			// The converted document must be valid codf.
+			if _, err := printer.Source([]byte(sb.String())); err != nil {
+				t.Errorf("Source() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestConvertNumbers(t *testing.T) {
+	const src = `{"a": 0.00001, "b": 1e6, "c": 1.5E+6, "d": -2.5e-7, "e": 1e400, "f": 12, "g": 0.5}`
+	want := []struct {
+		kind codf.TokenKind
+		text string
+	}{
+		{codf.TFloat, "1e-5"},
+		{codf.TFloat, "1e6"},
+		{codf.TFloat, "1.5e6"},
+		{codf.TFloat, "-2.5e-7"},
+		{codf.TFloat, "1e400"},
+		{codf.TInteger, "12"},
+		{codf.TFloat, "0.5"},
+	}
+
+	doc, err := Convert(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+
+	// Read the converted document back to check that numbers keep their kinds.
+	parsed := parse(t, doc.String())
+	if len(parsed.Children) != len(want) {
+		t.Fatalf("parsed %d statements; want %d\n%s", len(parsed.Children), len(want), doc)
+	}
+	for i, w := range want {
+		lit := parsed.Children[i].(*codf.Statement).Params[0].(*codf.Literal)
+		if lit.Tok.Kind != w.kind || string(lit.Tok.Raw) != w.text {
+			t.Errorf("%s = %v %s; want %v %s", parsed.Children[i].(*codf.Statement).Name(), lit.Tok.Kind, lit.Tok.Raw, w.kind, w.text)
+		}
+		orig := doc.Children[i].(*codf.Statement).Params[0].(*codf.Literal)
+		if f, ok := orig.Value().(*big.Float); ok && f.Cmp(lit.Value().(*big.Float)) != 0 {
+			t.Errorf("%s = %v; want %v", orig.Tok.Raw, lit.Value(), f)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		conv Converter
+		src  string
+		msg  string
+	}{
+		{"NotObject", Converter{}, `[1, 2]`, "jsonc: expected a JSON object; got array"},
+		{"Trailing", Converter{}, `{} {}`, "jsonc: unexpected data after top-level value"},
+		{"Empty", Converter{}, ``, "jsonc: unexpected EOF"},
+		{"Truncated", Converter{}, `{"a": [1, 2`, "jsonc: unexpected end of JSON input"},
+		{"Syntax", Converter{}, `{"a": }`, "jsonc: missing value after object key"},
+		{"Name", Converter{}, `{"a": {"b/c d": 1}}`,
+			`jsonc: /a/b~1c d: member name "b/c d" cannot be a statement or section name`},
+		{"NumericName", Converter{}, `{"servers": [{"a": 1}, {"80": 1}]}`,
+			`jsonc: /servers/1/80: member name "80" cannot be a statement or section name`},
+		{"BoolName", Converter{}, `{"true": 1}`,
+			`jsonc: /true: member name "true" cannot be a statement or section name`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.conv.Convert(strings.NewReader(c.src))
+			if err == nil || err.Error() != c.msg {
+				t.Errorf("Convert() = %v; want %q", err, c.msg)
+			}
+		})
+	}
+
+	// Names that are not words are allowed as map keys.
+	if _, err := (&Converter{Maps: true}).Convert(strings.NewReader(`{"a": {"b c": 1}}`)); err != nil {
+		t.Errorf("Convert() error = %v; want nil", err)
+	}
+}
@@ -0,0 +1,749 @@
+// Package jsonc encodes codf documents as JSON and decodes them back without losing information.
+//
+// Every node is encoded as a JSON object with a "type" of document, statement, section, array,
+// map, or literal. Literals keep their token kind, such as "duration" or "hex integer", and the
+// source text of the token in "raw". Their values are encoded as JSON strings, booleans, or, for
+// numbers, durations, and regular expressions, strings that hold the exact value, so that integers
+// and floats of any precision survive being read by a JSON parser that uses float64. Map entries
+// keep their order and comments attached by the parser are kept with the nodes they belong to.
+// With Encoder.Locations set, the locations of tokens in the source are included as well.
+//
+// For example, the statement "timeout 1m30s 0x1F;" is encoded as:
+//
+//	{
+//	  "type": "statement",
+//	  "name": "timeout",
+//	  "params": [
+//	    {"type": "literal", "kind": "duration", "raw": "1m30s", "value": "1m30s"},
+//	    {"type": "literal", "kind": "hex integer", "raw": "0x1F", "value": "31"}
+//	  ]
+//	}
+//
+// Statements and sections whose names are not words, such as quoted names, also hold their name
+// as a literal in "nameLiteral".
+//
+// Decoding the JSON produced by Marshal reconstructs an AST equal to the one encoded, including
+// token locations if they were encoded.
+package jsonc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"time"
+
+	"github.com/3JoB/codf"
+	"github.com/grafana/regexp"
+)
+
+// Node types in JSON.
+const (
+	typeDocument  = "document"
+	typeStatement = "statement"
+	typeSection   = "section"
+	typeArray     = "array"
+	typeMap       = "map"
+	typeLiteral   = "literal"
+)
+
+// node is the JSON form of every codf node. Fields not used by a node's type are omitted.
+type node struct {
+	Type string `json:"type"`
+
+	// Name is the name of a document, statement, or section. NameLiteral is the literal
+	// holding the name of a statement or section if it is not a word, such as a quoted name
+	// parsed with codf.ParseQuotedNames.
+	Name        string `json:"name,omitempty"`
+	NameLiteral *node  `json:"nameLiteral,omitempty"`
+
+	// Kind, Raw, Value, and Prec describe a literal. Prec is the precision of a float.
+	Kind  string          `json:"kind,omitempty"`
+	Raw   *string         `json:"raw,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Prec  uint            `json:"prec,omitempty"`
+
+	Params   []*node  `json:"params,omitempty"`
+	Children []*node  `json:"children,omitempty"`
+	Elems    []*node  `json:"elems,omitempty"`
+	Entries  []*entry `json:"entries,omitempty"`
+
+	Comments *comments `json:"comments,omitempty"`
+
+	// Pos is the span of the node's first token: the name of a statement or section, the
+	// opening token of an array or map, or a literal. Open is the span of a section's opening
+	// brace, and Close is the span of the token ending a statement, section, array, or map.
+	Pos   *span `json:"pos,omitempty"`
+	Open  *span `json:"open,omitempty"`
+	Close *span `json:"close,omitempty"`
+}
+
+type entry struct {
+	Ord   uint  `json:"ord"`
+	Key   *node `json:"key"`
+	Value *node `json:"value"`
+}
+
+type comments struct {
+	Leading  []*comment `json:"leading,omitempty"`
+	Trailing *comment   `json:"trailing,omitempty"`
+	Dangling []*comment `json:"dangling,omitempty"`
+}
+
+type comment struct {
+	Text string  `json:"text"`
+	Raw  *string `json:"raw,omitempty"`
+	Pos  *span   `json:"pos,omitempty"`
+}
+
+type span struct {
+	Start location `json:"start"`
+	End   location `json:"end"`
+}
+
+type location struct {
+	Name   string `json:"name,omitempty"`
+	Offset int    `json:"offset"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Encoder encodes codf nodes as JSON. The zero Encoder omits locations and does not indent.
+type Encoder struct {
+	// Locations includes the locations of tokens in the JSON.
+	Locations bool
+
+	// Indent, if not empty, is used to indent each level of the JSON, as by json.MarshalIndent.
+	Indent string
+}
+
+// Marshal returns the JSON encoding of node using the zero Encoder.
+func Marshal(node codf.Node) ([]byte, error) {
+	return (&Encoder{}).Marshal(node)
+}
+
+// Marshal returns the JSON encoding of node. It returns an error if node, or any node in it, is of
+// a type not produced by the codf parser or has a value that does not match its token kind.
+func (e *Encoder) Marshal(n codf.Node) ([]byte, error) {
+	jn, err := e.encode(n)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", e.Indent)
+	if err := enc.Encode(jn); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (e *Encoder) encode(n codf.Node) (*node, error) {
+	switch n := n.(type) {
+	case *codf.Document:
+		jn := &node{Type: typeDocument, Name: n.Name, Comments: e.comments(&n.Comments)}
+		var err error
+		jn.Children, err = e.encodeNodes(n.Children)
+		return jn, err
+
+	case *codf.Statement:
+		jn := &node{
+			Type:     typeStatement,
+			Name:     n.Name(),
+			Comments: e.comments(&n.Comments),
+			Pos:      e.span(n.NameTok.Tok),
+			Close:    e.span(n.EndTok),
+		}
+		var err error
+		if jn.NameLiteral, err = e.encodeName(n.NameTok); err != nil {
+			return nil, err
+		}
+		jn.Params, err = e.encodeExprs(n.Params)
+		return jn, err
+
+	case *codf.Section:
+		jn := &node{
+			Type:     typeSection,
+			Name:     n.Name(),
+			Comments: e.comments(&n.Comments),
+			Pos:      e.span(n.NameTok.Tok),
+			Open:     e.span(n.StartTok),
+			Close:    e.span(n.EndTok),
+		}
+		var err error
+		if jn.NameLiteral, err = e.encodeName(n.NameTok); err != nil {
+			return nil, err
+		}
+		if jn.Params, err = e.encodeExprs(n.Params); err != nil {
+			return nil, err
+		}
+		jn.Children, err = e.encodeNodes(n.Children)
+		return jn, err
+
+	case *codf.Array:
+		jn := &node{
+			Type:     typeArray,
+			Comments: e.comments(&n.Comments),
+			Pos:      e.span(n.StartTok),
+			Close:    e.span(n.EndTok),
+		}
+		var err error
+		jn.Elems, err = e.encodeExprs(n.Elems)
+		return jn, err
+
+	case *codf.Map:
+		jn := &node{
+			Type:     typeMap,
+			Comments: e.comments(&n.Comments),
+			Pos:      e.span(n.StartTok),
+			Close:    e.span(n.EndTok),
+		}
+		for _, pair := range n.Pairs() {
+			key, err := e.encode(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			val, err := e.encode(pair.Val)
+			if err != nil {
+				return nil, err
+			}
+			jn.Entries = append(jn.Entries, &entry{Ord: pair.Ord, Key: key, Value: val})
+		}
+		return jn, nil
+
+	case *codf.Literal:
+		return e.encodeLiteral(n.Tok)
+	}
+	return nil, fmt.Errorf("jsonc: unsupported node type %T", n)
+}
+
+func (e *Encoder) encodeNodes(nodes []codf.Node) ([]*node, error) {
+	jns := make([]*node, len(nodes))
+	for i, n := range nodes {
+		jn, err := e.encode(n)
+		if err != nil {
+			return nil, err
+		}
+		jns[i] = jn
+	}
+	return jns, nil
+}
+
+func (e *Encoder) encodeExprs(exprs []codf.ExprNode) ([]*node, error) {
+	jns := make([]*node, len(exprs))
+	for i, expr := range exprs {
+		jn, err := e.encode(expr)
+		if err != nil {
+			return nil, err
+		}
+		jns[i] = jn
+	}
+	return jns, nil
+}
+
+// encodeName returns the literal node for a statement or section name, or nil if the name is a
+// word whose raw text is its value and is kept by the node's name alone.
+func (e *Encoder) encodeName(name *codf.Literal) (*node, error) {
+	if str, ok := name.Tok.Value.(string); ok && name.Tok.Kind == codf.TWord && string(name.Tok.Raw) == str {
+		return nil, nil
+	}
+	return e.encodeLiteral(name.Tok)
+}
+
+func (e *Encoder) encodeLiteral(tok codf.Token) (*node, error) {
+	jn := &node{
+		Type: typeLiteral,
+		Kind: tok.Kind.String(),
+		Raw:  rawString(tok.Raw),
+		Pos:  e.span(tok),
+	}
+
+	var value any
+	switch v := tok.Value.(type) {
+	case nil:
+	case string, bool:
+		value = v
+	case *big.Int:
+		value = v.String()
+	case *big.Float:
+		value = v.Text('g', -1)
+		jn.Prec = v.Prec()
+	case *big.Rat:
+		value = v.String()
+	case time.Duration:
+		value = v.String()
+	case time.Time:
+		value = v.Format(time.RFC3339Nano)
+	case codf.Period:
+		value = v.String()
+	case netip.Addr, netip.Prefix, netip.AddrPort:
+		value = v.(fmt.Stringer).String()
+	case *regexp.Regexp:
+		value = v.String()
+	default:
+		return nil, fmt.Errorf("jsonc: unsupported value type %T for %v literal", v, tok.Kind)
+	}
+	if kind := valueKind(tok.Kind); value != nil && kind != valueAny && valueKindOf(tok.Value) != kind {
+		return nil, fmt.Errorf("jsonc: value of type %T does not match %v literal", tok.Value, tok.Kind)
+	}
+
+	if value != nil {
+		var err error
+		if jn.Value, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+	return jn, nil
+}
+
+func (e *Encoder) comments(c *codf.Comments) *comments {
+	if len(c.Leading) == 0 && c.Trailing == nil && len(c.Dangling) == 0 {
+		return nil
+	}
+	jc := &comments{
+		Leading:  e.commentList(c.Leading),
+		Dangling: e.commentList(c.Dangling),
+	}
+	if c.Trailing != nil {
+		jc.Trailing = e.comment(c.Trailing)
+	}
+	return jc
+}
+
+func (e *Encoder) commentList(list []*codf.Comment) []*comment {
+	if len(list) == 0 {
+		return nil
+	}
+	jcs := make([]*comment, len(list))
+	for i, c := range list {
+		jcs[i] = e.comment(c)
+	}
+	return jcs
+}
+
+func (e *Encoder) comment(c *codf.Comment) *comment {
+	return &comment{Text: c.Text(), Raw: rawString(c.Tok.Raw), Pos: e.span(c.Tok)}
+}
+
+func (e *Encoder) span(tok codf.Token) *span {
+	if !e.Locations {
+		return nil
+	}
+	return &span{Start: location(tok.Start), End: location(tok.End)}
+}
+
+func rawString(raw []byte) *string {
+	if raw == nil {
+		return nil
+	}
+	s := string(raw)
+	return &s
+}
+
+// valueType identifies the Go type of a literal's value.
+type valueType int
+
+const (
+	valueAny valueType = iota
+	valueString
+	valueBool
+	valueInt
+	valueFloat
+	valueRat
+	valueDuration
+	valueTime
+	valuePeriod
+	valueAddr
+	valuePrefix
+	valueAddrPort
+	valueRegexp
+)
+
+// valueKind returns the type of value held by literals of the given kind, or valueAny if the kind
+// is not one produced by the lexer for literals.
+func valueKind(kind codf.TokenKind) valueType {
+	switch kind {
+	case codf.TWord, codf.TString, codf.TRawString, codf.THeredoc:
+		return valueString
+	case codf.TBoolean:
+		return valueBool
+	case codf.TInteger, codf.THex, codf.TOctal, codf.TBinary, codf.TBaseInt, codf.TByteSize:
+		return valueInt
+	case codf.TFloat:
+		return valueFloat
+	case codf.TRational:
+		return valueRat
+	case codf.TDuration:
+		return valueDuration
+	case codf.TDate, codf.TTimestamp:
+		return valueTime
+	case codf.TPeriod:
+		return valuePeriod
+	case codf.TAddr:
+		return valueAddr
+	case codf.TPrefix:
+		return valuePrefix
+	case codf.TAddrPort:
+		return valueAddrPort
+	case codf.TRegexp:
+		return valueRegexp
+	}
+	return valueAny
+}
+
+// valueKindOf returns the type of v.
+func valueKindOf(v any) valueType {
+	switch v.(type) {
+	case string:
+		return valueString
+	case bool:
+		return valueBool
+	case *big.Int:
+		return valueInt
+	case *big.Float:
+		return valueFloat
+	case *big.Rat:
+		return valueRat
+	case time.Duration:
+		return valueDuration
+	case time.Time:
+		return valueTime
+	case codf.Period:
+		return valuePeriod
+	case netip.Addr:
+		return valueAddr
+	case netip.Prefix:
+		return valuePrefix
+	case netip.AddrPort:
+		return valueAddrPort
+	case *regexp.Regexp:
+		return valueRegexp
+	}
+	return valueAny
+}
+
+// Unmarshal decodes a document from JSON produced by Marshal.
+func Unmarshal(data []byte) (*codf.Document, error) {
+	n, err := UnmarshalNode(data)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := n.(*codf.Document)
+	if !ok {
+		return nil, fmt.Errorf("jsonc: expected a document; got %T", n)
+	}
+	return doc, nil
+}
+
+// UnmarshalNode decodes a node of any type from JSON produced by Marshal.
+func UnmarshalNode(data []byte) (codf.Node, error) {
+	var jn node
+	if err := json.Unmarshal(data, &jn); err != nil {
+		return nil, err
+	}
+	return decode(&jn)
+}
+
+func decode(jn *node) (codf.Node, error) {
+	if jn == nil {
+		return nil, fmt.Errorf("jsonc: null node")
+	}
+	switch jn.Type {
+	case typeDocument:
+		children, err := decodeChildren(jn.Children)
+		if err != nil {
+			return nil, err
+		}
+		return &codf.Document{Name: jn.Name, Children: children, Comments: decodeComments(jn.Comments)}, nil
+
+	case typeStatement:
+		name, err := decodeName(jn)
+		if err != nil {
+			return nil, err
+		}
+		params, err := decodeExprs(jn.Params)
+		if err != nil {
+			return nil, err
+		}
+		return &codf.Statement{
+			NameTok:  name,
+			Params:   params,
+			EndTok:   token(codf.TSemicolon, jn.Close),
+			Comments: decodeComments(jn.Comments),
+		}, nil
+
+	case typeSection:
+		name, err := decodeName(jn)
+		if err != nil {
+			return nil, err
+		}
+		params, err := decodeExprs(jn.Params)
+		if err != nil {
+			return nil, err
+		}
+		children, err := decodeChildren(jn.Children)
+		if err != nil {
+			return nil, err
+		}
+		if children == nil {
+			children = []codf.Node{}
+		}
+		return &codf.Section{
+			NameTok:  name,
+			Params:   params,
+			Children: children,
+			StartTok: token(codf.TCurlOpen, jn.Open),
+			EndTok:   token(codf.TCurlClose, jn.Close),
+			Comments: decodeComments(jn.Comments),
+		}, nil
+
+	case typeArray:
+		elems, err := decodeExprs(jn.Elems)
+		if err != nil {
+			return nil, err
+		}
+		if elems == nil {
+			elems = []codf.ExprNode{}
+		}
+		return &codf.Array{
+			StartTok: token(codf.TBracketOpen, jn.Pos),
+			EndTok:   token(codf.TBracketClose, jn.Close),
+			Elems:    elems,
+			Comments: decodeComments(jn.Comments),
+		}, nil
+
+	case typeMap:
+		m := &codf.Map{
+			StartTok: token(codf.TMapOpen, jn.Pos),
+			EndTok:   token(codf.TCurlClose, jn.Close),
+			Elems:    make(map[string]*codf.MapEntry, len(jn.Entries)),
+			Comments: decodeComments(jn.Comments),
+		}
+		for _, je := range jn.Entries {
+			if je == nil || je.Key == nil || je.Value == nil {
+				return nil, fmt.Errorf("jsonc: map entry must have a key and value")
+			}
+			key, err := decodeExpr(je.Key)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := codf.String(key)
+			if !ok {
+				return nil, fmt.Errorf("jsonc: map key must be a word or string; got %v", key.Token().Kind)
+			}
+			val, err := decodeExpr(je.Value)
+			if err != nil {
+				return nil, err
+			}
+			m.Elems[name] = &codf.MapEntry{Ord: je.Ord, Key: key, Val: val}
+		}
+		return m, nil
+
+	case typeLiteral:
+		return decodeLiteral(jn)
+	}
+	return nil, fmt.Errorf("jsonc: unknown node type %q", jn.Type)
+}
+
+func decodeChildren(jns []*node) ([]codf.Node, error) {
+	if len(jns) == 0 {
+		return nil, nil
+	}
+	nodes := make([]codf.Node, len(jns))
+	for i, jn := range jns {
+		n, err := decode(jn)
+		if err != nil {
+			return nil, err
+		}
+		switch n.(type) {
+		case *codf.Document, *codf.Statement, *codf.Section:
+		default:
+			return nil, fmt.Errorf("jsonc: %s is not a valid child", jn.Type)
+		}
+		nodes[i] = n
+	}
+	return nodes, nil
+}
+
+func decodeExprs(jns []*node) ([]codf.ExprNode, error) {
+	if len(jns) == 0 {
+		return nil, nil
+	}
+	exprs := make([]codf.ExprNode, len(jns))
+	for i, jn := range jns {
+		expr, err := decodeExpr(jn)
+		if err != nil {
+			return nil, err
+		}
+		exprs[i] = expr
+	}
+	return exprs, nil
+}
+
+func decodeExpr(jn *node) (codf.ExprNode, error) {
+	n, err := decode(jn)
+	if err != nil {
+		return nil, err
+	}
+	expr, ok := n.(codf.ExprNode)
+	if !ok {
+		return nil, fmt.Errorf("jsonc: %s is not a valid parameter or value", jn.Type)
+	}
+	return expr, nil
+}
+
+// kinds maps the names of token kinds to token kinds.
+var kinds = func() map[string]codf.TokenKind {
+	m := map[string]codf.TokenKind{}
+	for k := codf.TokenKind(0); k.String() != "invalid"; k++ {
+		m[k.String()] = k
+	}
+	return m
+}()
+
+func decodeLiteral(jn *node) (*codf.Literal, error) {
+	kind, ok := kinds[jn.Kind]
+	if !ok {
+		return nil, fmt.Errorf("jsonc: unknown literal kind %q", jn.Kind)
+	}
+	tok := token(kind, jn.Pos)
+	if jn.Raw != nil {
+		tok.Raw = []byte(*jn.Raw)
+	}
+	if len(jn.Value) == 0 {
+		return &codf.Literal{Tok: tok}, nil
+	}
+
+	var err error
+	tok.Value, err = decodeValue(kind, jn.Value, jn.Prec)
+	if err != nil {
+		return nil, fmt.Errorf("jsonc: invalid value for %v literal: %w", kind, err)
+	}
+
+	// A float parsed from its shortest decimal text has the same value as one parsed from its
+	// source text, but may differ in its accuracy. Prefer the source text, as the lexer does.
+	if f, ok := tok.Value.(*big.Float); ok && jn.Raw != nil {
+		raw, ok := new(big.Float).SetPrec(f.Prec()).SetString(*jn.Raw)
+		if ok && raw.Cmp(f) == 0 && raw.Signbit() == f.Signbit() {
+			tok.Value = raw
+		}
+	}
+	return &codf.Literal{Tok: tok}, nil
+}
+
+func decodeValue(kind codf.TokenKind, data json.RawMessage, prec uint) (any, error) {
+	if valueKind(kind) == valueBool {
+		var b bool
+		err := json.Unmarshal(data, &b)
+		return b, err
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	switch valueKind(kind) {
+	case valueInt:
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("malformed integer %q", s)
+		}
+		return v, nil
+	case valueFloat:
+		if prec == 0 {
+			prec = codf.DefaultPrecision
+		}
+		v, ok := new(big.Float).SetPrec(prec).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("malformed float %q", s)
+		}
+		return v, nil
+	case valueRat:
+		v, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("malformed rational %q", s)
+		}
+		return v, nil
+	case valueDuration:
+		return time.ParseDuration(s)
+	case valueTime:
+		return time.Parse(time.RFC3339Nano, s)
+	case valuePeriod:
+		return codf.ParsePeriod(s)
+	case valueAddr:
+		return netip.ParseAddr(s)
+	case valuePrefix:
+		return netip.ParsePrefix(s)
+	case valueAddrPort:
+		return netip.ParseAddrPort(s)
+	case valueRegexp:
+		return regexp.Compile(s)
+	}
+	return s, nil
+}
+
+// decodeName returns the name literal of a statement or section node.
+func decodeName(jn *node) (*codf.Literal, error) {
+	if jn.NameLiteral == nil {
+		tok := token(codf.TWord, jn.Pos)
+		tok.Raw = []byte(jn.Name)
+		tok.Value = jn.Name
+		return &codf.Literal{Tok: tok}, nil
+	}
+	lit, err := decodeLiteral(jn.NameLiteral)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := codf.String(lit); !ok {
+		return nil, fmt.Errorf("jsonc: %s name must be a word or string; got %v", jn.Type, lit.Tok.Kind)
+	}
+	return lit, nil
+}
+
+func token(kind codf.TokenKind, s *span) codf.Token {
+	tok := codf.Token{Kind: kind}
+	if s != nil {
+		tok.Start = codf.Location(s.Start)
+		tok.End = codf.Location(s.End)
+	}
+	return tok
+}
+
+func decodeComments(jc *comments) codf.Comments {
+	if jc == nil {
+		return codf.Comments{}
+	}
+	c := codf.Comments{
+		Leading:  decodeCommentList(jc.Leading),
+		Dangling: decodeCommentList(jc.Dangling),
+	}
+	if jc.Trailing != nil {
+		c.Trailing = decodeComment(jc.Trailing)
+	}
+	return c
+}
+
+func decodeCommentList(jcs []*comment) []*codf.Comment {
+	if len(jcs) == 0 {
+		return nil
+	}
+	list := make([]*codf.Comment, 0, len(jcs))
+	for _, jc := range jcs {
+		if jc != nil {
+			list = append(list, decodeComment(jc))
+		}
+	}
+	return list
+}
+
+func decodeComment(jc *comment) *codf.Comment {
+	tok := token(codf.TComment, jc.Pos)
+	tok.Value = jc.Text
+	if jc.Raw != nil {
+		tok.Raw = []byte(*jc.Raw)
+	}
+	return &codf.Comment{Tok: tok}
+}
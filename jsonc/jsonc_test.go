@@ -0,0 +1,341 @@
+package jsonc
+
+import (
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/3JoB/codf"
+)
+
+const testSource = `// Leading comment.
+user http; // Trailing comment.
+server go.spiff.io {
+	listen 0.0.0.0:80 "[::]:80" ` + "`raw`" + `;
+	timeouts 1h30m 250ms -5s 30d 1w2d;
+	retention P1Y2M3DT4H5M6.5S P2W;
+	sizes 64mib 1.5kb 0b;
+	window 2026-10-16 2026-10-16T04:00:00.5+02:00;
+	numbers 42 -0x1F 0o17 0b101 16#ff 2/4 3.14159265358979323846264338327950288 1e-400;
+	huge 123456789012345678901234567890;
+	flags yes No TRUE;
+	match #/^\/api(\/.*)?$/;
+	script <<LUA
+		if ngx.var.host then
+		  return 1
+		end
+		LUA;
+	headers #{
+		// Map comment.
+		z last
+		a first
+		"quoted key" [1 2 #{ nested true }]
+	};
+	empty [] #{};
+	// Dangling comment.
+}
+`
+
+func parse(t *testing.T, src string) *codf.Document {
+	t.Helper()
+	p := codf.NewParser()
+	p.Flags = codf.ParseComments
+	if err := p.Parse(codf.NewLexer(strings.NewReader(src))); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	doc := p.Document()
+	doc.Name = "test.conf"
+	return doc
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, locations := range []bool{true, false} {
+		name := "NoLocations"
+		if locations {
+			name = "Locations"
+		}
+		t.Run(name, func(t *testing.T) {
+			doc := parse(t, testSource)
+			if !locations {
+				// Without locations, the decoded document is equal to one with zero locations.
+				clearLocations(doc)
+			}
+
+			enc := &Encoder{Locations: locations}
+			data, err := enc.Marshal(doc)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			t.Logf("JSON: %s", data)
+
+			got, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, doc) {
+				t.Errorf("Unmarshal() =\n%v\nwant\n%v", got, doc)
+			}
+			if got.String() != doc.String() {
+				t.Errorf("Unmarshal().String() =\n%s\nwant\n%s", got, doc)
+			}
+		})
+	}
+}
+
+func TestRoundTripConstructed(t *testing.T) {
+	doc := &codf.Document{Children: []codf.Node{
+		codf.NewStatement("size", codf.NewBigInt(new(big.Int).Lsh(big.NewInt(1), 100))),
+		codf.NewSection("nested", []codf.ExprNode{codf.NewDuration(time.Minute)},
+			codf.NewStatement("ratio", codf.NewRat(big.NewRat(-1, 3))),
+			codf.NewStatement("map", codf.NewMap(
+				codf.NewMapEntry("b", codf.NewFloat(0.1)),
+				codf.NewMapEntry("a", codf.NewString("x\ny")),
+			)),
+		),
+		codf.NewStatement("allow",
+			codf.NewAddr(netip.MustParseAddr("fe80::1%eth0")),
+			codf.NewPrefix(netip.MustParsePrefix("10.0.0.0/8")),
+			codf.NewAddrPort(netip.MustParseAddrPort("[::1]:80")),
+		),
+		&codf.Document{Name: "included.conf", Children: []codf.Node{codf.NewStatement("included")}},
+	}}
+
+	data, err := Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	got, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, doc) {
+		t.Errorf("Unmarshal() =\n%v\nwant\n%v", got, doc)
+	}
+}
+
+func TestRoundTripQuotedNames(t *testing.T) {
+	l := codf.NewLexer(strings.NewReader("map $a $b {\n\t'~^/a b' 1;\n\t'' 0;\n}\n`raw` {}\n"))
+	l.Flags = codf.LexNginx
+	p := codf.NewParser()
+	p.Flags = codf.ParseQuotedNames
+	if err := p.Parse(l); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	doc := p.Document()
+
+	for _, locations := range []bool{true, false} {
+		if !locations {
+			clearLocations(doc)
+		}
+		data, err := (&Encoder{Locations: locations}).Marshal(doc)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		got, err := Unmarshal(data)
+		if err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, doc) {
+			t.Errorf("Unmarshal() =\n%v\nwant\n%v", got, doc)
+		}
+		stmt := got.Children[0].(*codf.Section).Children[0].(*codf.Statement)
+		if stmt.NameTok.Tok.Kind != codf.TString || string(stmt.NameTok.Tok.Raw) != "'~^/a b'" {
+			t.Errorf("name = %v %q; want string %q", stmt.NameTok.Tok.Kind, stmt.NameTok.Tok.Raw, "'~^/a b'")
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	doc := parse(t, "timeout 1m30s 0x1F 1.5 [a] #{k v};")
+	data, err := (&Encoder{Indent: "  "}).Marshal(doc.Children[0])
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := `{
+  "type": "statement",
+  "name": "timeout",
+  "params": [
+    {
+      "type": "literal",
+      "kind": "duration",
+      "raw": "1m30s",
+      "value": "1m30s"
+    },
+    {
+      "type": "literal",
+      "kind": "hex integer",
+      "raw": "0x1F",
+      "value": "31"
+    },
+    {
+      "type": "literal",
+      "kind": "float",
+      "raw": "1.5",
+      "value": "1.5",
+      "prec": 80
+    },
+    {
+      "type": "array",
+      "elems": [
+        {
+          "type": "literal",
+          "kind": "word",
+          "raw": "a",
+          "value": "a"
+        }
+      ]
+    },
+    {
+      "type": "map",
+      "entries": [
+        {
+          "ord": 0,
+          "key": {
+            "type": "literal",
+            "kind": "word",
+            "raw": "k",
+            "value": "k"
+          },
+          "value": {
+            "type": "literal",
+            "kind": "word",
+            "raw": "v",
+            "value": "v"
+          }
+        }
+      ]
+    }
+  ]
+}`
+	if got := string(data); got != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		node codf.Node
+		msg  string
+	}{
+		{
+			"ValueType",
+			&codf.Literal{Tok: codf.Token{Kind: codf.TInteger, Value: 1}},
+			"jsonc: unsupported value type int for integer literal",
+		},
+		{
+			"KindMismatch",
+			&codf.Literal{Tok: codf.Token{Kind: codf.TDuration, Value: "1s"}},
+			"jsonc: value of type string does not match duration literal",
+		},
+		{
+			"NodeType",
+			&codf.MapEntry{Key: codf.NewWord("a"), Val: codf.NewWord("b")},
+			"jsonc: unsupported node type *codf.MapEntry",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Marshal(c.node)
+			if err == nil || err.Error() != c.msg {
+				t.Errorf("Marshal() = %v; want %q", err, c.msg)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		json string
+		msg  string
+	}{
+		{"NotDocument", `{"type": "statement", "name": "a"}`, "jsonc: expected a document; got *codf.Statement"},
+		{"UnknownType", `{"type": "widget"}`, `jsonc: unknown node type "widget"`},
+		{"UnknownKind", `{"type": "document", "children": [{"type": "statement", "name": "a", "params": [{"type": "literal", "kind": "complex"}]}]}`,
+			`jsonc: unknown literal kind "complex"`},
+		{"BadInteger", `{"type": "document", "children": [{"type": "statement", "name": "a", "params": [{"type": "literal", "kind": "integer", "value": "1.5"}]}]}`,
+			`jsonc: invalid value for integer literal: malformed integer "1.5"`},
+		{"BadChild", `{"type": "document", "children": [{"type": "literal", "kind": "word", "value": "a"}]}`,
+			"jsonc: literal is not a valid child"},
+		{"BadName", `{"type": "document", "children": [{"type": "statement", "name": "a", "nameLiteral": {"type": "literal", "kind": "integer", "value": "1"}}]}`,
+			"jsonc: statement name must be a word or string; got integer"},
+		{"BadParam", `{"type": "document", "children": [{"type": "statement", "name": "a", "params": [{"type": "statement", "name": "b"}]}]}`,
+			"jsonc: statement is not a valid parameter or value"},
+		{"BadKey", `{"type": "document", "children": [{"type": "statement", "name": "a", "params": [{"type": "map", "entries": [{"ord": 0, "key": {"type": "literal", "kind": "integer", "value": "1"}, "value": {"type": "literal", "kind": "word", "value": "b"}}]}]}]}`,
+			"jsonc: map key must be a word or string; got integer"},
+		{"MissingValue", `{"type": "document", "children": [{"type": "statement", "name": "a", "params": [{"type": "map", "entries": [{"ord": 0, "key": {"type": "literal", "kind": "word", "value": "a"}}]}]}]}`,
+			"jsonc: map entry must have a key and value"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Unmarshal([]byte(c.json))
+			if err == nil || err.Error() != c.msg {
+				t.Errorf("Unmarshal() = %v; want %q", err, c.msg)
+			}
+		})
+	}
+}
+
+// clearLocations sets the locations of every token in node to zero locations.
+func clearLocations(node codf.Node) {
+	clearComments := func(c *codf.Comments) {
+		for _, lc := range append(append([]*codf.Comment{c.Trailing}, c.Leading...), c.Dangling...) {
+			if lc != nil {
+				lc.Tok.Start, lc.Tok.End = codf.Location{}, codf.Location{}
+			}
+		}
+	}
+	clear := func(tok *codf.Token) {
+		tok.Start, tok.End = codf.Location{}, codf.Location{}
+	}
+
+	switch node := node.(type) {
+	case *codf.Document:
+		clearComments(&node.Comments)
+		for _, child := range node.Children {
+			clearLocations(child)
+		}
+	case *codf.Statement:
+		clearComments(&node.Comments)
+		clear(&node.NameTok.Tok)
+		clear(&node.EndTok)
+		for _, p := range node.Params {
+			clearLocations(p)
+		}
+	case *codf.Section:
+		clearComments(&node.Comments)
+		clear(&node.NameTok.Tok)
+		clear(&node.StartTok)
+		clear(&node.EndTok)
+		for _, p := range node.Params {
+			clearLocations(p)
+		}
+		for _, child := range node.Children {
+			clearLocations(child)
+		}
+	case *codf.Array:
+		clearComments(&node.Comments)
+		clear(&node.StartTok)
+		clear(&node.EndTok)
+		for _, elem := range node.Elems {
+			clearLocations(elem)
+		}
+	case *codf.Map:
+		clearComments(&node.Comments)
+		clear(&node.StartTok)
+		clear(&node.EndTok)
+		for _, pair := range node.Elems {
+			clearLocations(pair.Key)
+			clearLocations(pair.Val)
+		}
+	case *codf.Literal:
+		clear(&node.Tok)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
+	"net/netip"
 	"strconv"
 	"strings"
 	"time"
@@ -70,14 +72,29 @@ const (
 	// Leading zeroes are only permitted on octal numbers or following the 'b', 'x', or '#' of
 	// a base number. For example, 10#00001 is the integer 1.
 
-	TInteger  // '0' | [1-9] [0-9]*
-	TFloat    // Integer '.' Integer Exponent? | Integer Exponent
-	THex      // '0' [Xx] [a-fA-F0-9]+
-	TOctal    // '0' [0-7]+
-	TBinary   // '0' [bB] [01]+
-	TBaseInt  // 2-36 '#' [a-zA-Z0-9]+ (corresponding to base)
-	TDuration // 1m1.033s1h...
-	TRational // Integer '/' Integer
+	TInteger   // '0' | [1-9] [0-9]*
+	TFloat     // Integer '.' Integer Exponent? | Integer Exponent
+	THex       // '0' [Xx] [a-fA-F0-9]+
+	TOctal     // '0' [0-7]+
+	TBinary    // '0' [bB] [01]+
+	TBaseInt   // 2-36 '#' [a-zA-Z0-9]+ (corresponding to base)
+	TDuration  // 1m1.033s1h1d1w...
+	TRational  // Integer '/' Integer
+	TByteSize  // Integer ( '.' [0-9]+ )? ByteSizeUnit
+	TDate      // [0-9]{4} '-' [0-9]{2} '-' [0-9]{2}
+	TTimestamp // Date 'T' [0-9]{2} ':' [0-9]{2} ':' [0-9]{2} ( '.' [0-9]+ )? ( 'Z' | [+-] [0-9]{2} ':' [0-9]{2} )
+	TPeriod    // 'P' ( [0-9]+ [YMWD] )* ( 'T' ( [0-9]+ ( '.' [0-9]+ )? [HMS] )+ )?
+
+	// Network addresses are only produced with the LexNetAddrs flag, which converts words that
+	// are IP addresses, prefixes, or address and port pairs to these kinds.
+
+	TAddr     // IPv4 | IPv6 ( '%' Zone )?
+	TPrefix   // Addr '/' [0-9]+
+	TAddrPort // ( IPv4 | '[' IPv6 ']' ) ':' [0-9]+
+
+	// THeredoc is a multi-line string whose lines follow a "<<NAME" line and end at a line
+	// holding only NAME. The indentation before the closing NAME is removed from every line.
+	THeredoc // '<<' Name '\n' ( Line '\n' )* Indent Name
 )
 
 var tokenNames = []string{
@@ -104,14 +121,22 @@ var tokenNames = []string{
 
 	TBoolean: "bool",
 
-	TInteger:  "integer",
-	TFloat:    "float",
-	THex:      "hex integer",
-	TOctal:    "octal integer",
-	TBinary:   "binary integer",
-	TBaseInt:  "base integer",
-	TDuration: "duration",
-	TRational: "rational",
+	TInteger:   "integer",
+	TFloat:     "float",
+	THex:       "hex integer",
+	TOctal:     "octal integer",
+	TBinary:    "binary integer",
+	TBaseInt:   "base integer",
+	TDuration:  "duration",
+	TRational:  "rational",
+	TByteSize:  "byte size",
+	TDate:      "date",
+	TTimestamp: "timestamp",
+	TPeriod:    "period",
+	TAddr:      "address",
+	TPrefix:    "prefix",
+	TAddrPort:  "address port",
+	THeredoc:   "heredoc",
 }
 
 // Token is a token with a kind and a start and end location.
@@ -122,20 +147,28 @@ var tokenNames = []string{
 // Depending on the Kind, the Token must have a Value of the types described below. For all other
 // TokenKinds not in the table below, a Value is not expected.
 //
-//	| Kind      | Value Type     |
-//	|-----------+----------------|
-//	| TWord     | string         |
-//	| TString   | string         |
-//	| TRegexp   | *regexp.Regexp |
-//	| TBoolean  | bool           |
-//	| TFloat    | *big.Float     |
-//	| TRational | *big.Rat       |
-//	| TInteger  | *big.Int       |
-//	| THex      | *big.Int       |
-//	| TOctal    | *big.Int       |
-//	| TBinary   | *big.Int       |
-//	| TBaseInt  | *big.Int       |
-//	| TDuration | time.Duration  |
+//	| Kind       | Value Type     |
+//	|------------+----------------|
+//	| TWord      | string         |
+//	| TString    | string         |
+//	| THeredoc   | string         |
+//	| TRegexp    | *regexp.Regexp |
+//	| TBoolean   | bool           |
+//	| TFloat     | *big.Float     |
+//	| TRational  | *big.Rat       |
+//	| TInteger   | *big.Int       |
+//	| THex       | *big.Int       |
+//	| TOctal     | *big.Int       |
+//	| TBinary    | *big.Int       |
+//	| TBaseInt   | *big.Int       |
+//	| TDuration  | time.Duration  |
+//	| TByteSize  | *big.Int       |
+//	| TDate      | time.Time      |
+//	| TTimestamp | time.Time      |
+//	| TPeriod    | Period         |
+//	| TAddr      | netip.Addr     |
+//	| TPrefix    | netip.Prefix   |
+//	| TAddrPort  | netip.AddrPort |
 type Token struct {
 	Start, End Location
 	Kind       TokenKind
@@ -196,6 +229,7 @@ const (
 	rBracketOpen  = '['
 	rBracketClose = ']'
 	rDoubleQuote  = '"'
+	rSingleQuote  = '\''
 	rBackQuote    = '`'
 	rSpecial      = '#'
 	rComment      = '/'
@@ -223,7 +257,9 @@ const (
 		LexNoRationals |
 		LexNoFloats |
 		LexNoBaseInts |
-		LexNoNumbers
+		LexNoNumbers |
+		LexNoByteSizes |
+		LexNoTimestamps
 )
 
 const (
@@ -233,7 +269,7 @@ const (
 	// LexNoBools disables true/false/yes/no parsing.
 	LexNoBools
 
-	// LexNoDurations disables durations.
+	// LexNoDurations disables durations, including ISO 8601 durations (periods).
 	LexNoDurations
 
 	// LexNoRationals disables rationals.
@@ -246,10 +282,54 @@ const (
 	LexNoBaseInts
 
 	// LexNoNumbers disables all numbers.
-	// Implies NoBaseInts, NoFloats, NoRationals, and NoDurations
+	// Implies NoBaseInts, NoFloats, NoRationals, NoDurations, NoByteSizes, and NoTimestamps
 	LexNoNumbers
+
+	// LexHashComments makes '#' at the start of a token begin a comment that runs to the end of
+	// the line, instead of a map or regexp. Comments beginning with '//' are disabled, so that
+	// '//' may begin a word.
+	LexHashComments
+
+	// LexSingleQuotes enables single-quoted strings, such as 'a "b" c'. Single-quoted strings
+	// have the same escapes as double-quoted strings, except that \' is an escape instead of \".
+	// Both produce TString tokens.
+	LexSingleQuotes
+
+	// LexNginxEscapes uses nginx's escapes in quoted strings: only \", \', \\, \t, \r, and \n
+	// are escapes, and a backslash followed by any other character is kept as-is, so that strings
+	// such as "^/(\d+)$" need no extra escaping.
+	LexNginxEscapes
+
+	// LexBracketWords treats '[' and ']' as word characters instead of array delimiters, so that
+	// words such as [::]:80 and [a-z]+ are read as single words.
+	LexBracketWords
+
+	// LexNoByteSizes disables byte sizes.
+	LexNoByteSizes
+
+	// LexNoTimestamps disables dates and timestamps.
+	LexNoTimestamps
+
+	// LexNetAddrs enables network addresses. Words that are IPv4 or IPv6 addresses, such as
+	// 10.0.0.1 and ::1, become TAddr tokens, CIDR prefixes, such as 10.0.0.0/8, become TPrefix
+	// tokens, and address and port pairs, such as 0.0.0.0:80, become TAddrPort tokens. IPv6
+	// address and port pairs, such as [::1]:80, also require LexBracketWords. Without this flag,
+	// all of these are read as words.
+	LexNetAddrs
+
+	// LexNoHeredocs disables heredocs, so that a word such as <<EOF at the end of a line is read as
+	// a word.
+	LexNoHeredocs
 )
 
+// LexNginx is the set of Lex flags needed to read nginx configuration files, which use '#'
+// comments, single-quoted strings, nginx's string escapes, and brackets in words such as IPv6
+// addresses and regular expressions. Variables, such as $host and ${host}, and regular expression
+// locations, such as "location ~* \.(gif|jpg)$ { ... }", are read as words with or without these
+// flags. Map blocks, whose entries may be named by quoted strings, also need the Parser's
+// ParseQuotedNames flag.
+const LexNginx = LexHashComments | LexSingleQuotes | LexNginxEscapes | LexBracketWords
+
 func (f LexerFlag) none(bits LexerFlag) bool {
 	return f&bits == 0
 }
@@ -289,6 +369,17 @@ type Lexer struct {
 
 	next consumerFunc
 
+	// quote is the rune that opened the quoted string being lexed.
+	quote rune
+
+	// heredoc is the delimiter of the heredoc being lexed. heredocMatch is the number of bytes
+	// of the delimiter matched at the start of the current line after its indentation, or -1
+	// if the line cannot be the closing delimiter.
+	heredoc      string
+	heredocMatch int
+	// heredocSpace is the location of the spaces or tabs following a heredoc's delimiter.
+	heredocSpace Location
+
 	buf    bytes.Buffer
 	strbuf bytes.Buffer
 }
@@ -347,6 +438,11 @@ func (l *Lexer) ReadToken() (tok Token, err error) {
 		}
 
 		tok, l.next, err = l.next(r)
+		if err != nil && l.next == nil && l.buf.Len() > 0 && isStatementSep(r) {
+			// The rune that ended a malformed token is read again so that whatever it
+			// begins or ends (a statement, section, or string) is not lost.
+			l.unread()
+		}
 		if err != nil || tok.Kind != tEmpty {
 			return tok, err
 		}
@@ -358,7 +454,9 @@ type convertFunc func(Token) (Token, error)
 func (l *Lexer) valueToken(kind TokenKind, convert convertFunc) (tok Token, err error) {
 	tok = l.token(kind, true)
 	if convert != nil {
-		tok, err = convert(tok)
+		if tok, err = convert(tok); err != nil {
+			err = valueError(tok, err)
+		}
 	}
 	return tok, err
 }
@@ -398,6 +496,8 @@ func (l *Lexer) readRune() (r rune, err error) {
 	r, size, err = l.scanner.ReadRune()
 	if err == io.EOF {
 		r, size, err = eof, 0, nil
+	} else if err != nil {
+		err = l.readError(err)
 	}
 	res := scanResult{r: r, size: size, err: err}
 	l.lastScan, l.lastPos = res, l.pos
@@ -406,7 +506,7 @@ func (l *Lexer) readRune() (r rune, err error) {
 	}
 
 	if r == invalid && err == nil {
-		err = fmt.Errorf("invalid UTF-8 at %v", l.pos)
+		err = l.invalidUTF8()
 	}
 
 	return
@@ -510,13 +610,29 @@ func isIntervalInitial(r rune) bool {
 		r == 'h' || // 'h'
 		r == 'm' || // 'ms' | 'm'
 		r == 'u' || // 'us'
-		r == 'μ' // 'μs'
+		r == 'μ' || // 'μs'
+		r == 'd' || // 'd'
+		r == 'w' // 'w'
 }
 
 func isMaybeLongIntervalInitial(r rune) bool {
 	return r == 'm' // 'ms' | 'm'
 }
 
+// isByteSizeInitial returns true if r may begin a byte size unit. Units beginning with 'm' are
+// only byte sizes if durations are disabled or 'm' is followed by 'b' or 'i'.
+func isByteSizeInitial(r rune) bool {
+	switch r {
+	case 'b', 'B', 'k', 'K', 'm', 'M', 'g', 'G', 't', 'T':
+		return true
+	}
+	return false
+}
+
+func isByteSizeUnitRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
 func isSign(r rune) bool {
 	return r == '-' || r == '+'
 }
@@ -573,8 +689,14 @@ func (l *Lexer) lexCommentStart(next consumerFunc) consumerFunc {
 }
 
 func (l *Lexer) lexComment(next consumerFunc) consumerFunc {
-	var commentConsumer consumerFunc
 	l.buffer(rComment, -1)
+	return l.lexCommentText(next)
+}
+
+// lexCommentText consumes the text of a comment, following its opening '//' or '#', up to the end
+// of the line.
+func (l *Lexer) lexCommentText(next consumerFunc) consumerFunc {
+	var commentConsumer consumerFunc
 	commentConsumer = func(r rune) (Token, consumerFunc, error) {
 		if r == '\n' || r == eof {
 			l.unread()
@@ -607,12 +729,19 @@ func (l *Lexer) lexSegment(r rune) (Token, consumerFunc, error) {
 		return l.token(TCurlClose, false), l.lexSegment, nil
 
 	// Brackets
+	case (r == rBracketOpen || r == rBracketClose) && l.Flags.any(LexBracketWords):
+		return l.lexBecomeWord(r)
 	case r == rBracketOpen:
 		return l.token(TBracketOpen, false), l.lexSegment, nil
 	case r == rBracketClose:
 		return l.token(TBracketClose, false), l.lexSegment, nil
 
 	// Comment
+	case r == rSpecial && l.Flags.any(LexHashComments):
+		l.buffer(r, -1)
+		return noToken, l.lexCommentText(l.lexSegment), nil
+	case r == rComment && l.Flags.any(LexHashComments):
+		return l.lexBecomeWord(r)
 	case r == rComment:
 		return noToken, l.lexCommentStart(l.lexSegment), nil
 
@@ -635,12 +764,22 @@ func (l *Lexer) lexSegment(r rune) (Token, consumerFunc, error) {
 		return noToken, l.lexNonZero, nil
 	}
 
+	// Period (ISO 8601 duration)
+	if r == 'P' && l.Flags.none(LexNoDurations|LexNoNumbers) {
+		l.buffer(r, r)
+		return noToken, l.lexPeriod, nil
+	}
+
 	// String
-	switch r {
-	case rDoubleQuote:
+	switch {
+	case r == '<' && l.Flags.none(LexNoHeredocs):
+		l.buffer(r, r)
+		return noToken, l.lexHeredocStart, nil
+	case r == rDoubleQuote, r == rSingleQuote && l.Flags.any(LexSingleQuotes):
 		l.buffer(r, -1)
+		l.quote = r
 		return noToken, l.lexString, nil
-	case rBackQuote:
+	case r == rBackQuote:
 		l.buffer(r, -1)
 		return noToken, l.lexRawString, nil
 	}
@@ -649,7 +788,7 @@ func (l *Lexer) lexSegment(r rune) (Token, consumerFunc, error) {
 	if isBarewordRune(r) {
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q at %v", r, l.pos)
+	return noToken, nil, l.unexpected(r, "expected a token")
 }
 
 func (l *Lexer) lexWordTail(next consumerFunc) consumerFunc {
@@ -657,6 +796,9 @@ func (l *Lexer) lexWordTail(next consumerFunc) consumerFunc {
 	var braces int
 	wordConsumer = func(r rune) (Token, consumerFunc, error) {
 		switch {
+		case (r == rBracketOpen || r == rBracketClose) && l.Flags.any(LexBracketWords):
+			l.buffer(r, r)
+			return noToken, wordConsumer, nil
 		case r == rCurlOpen || r == rBracketOpen:
 			braces++
 			l.buffer(r, r)
@@ -678,6 +820,9 @@ func (l *Lexer) lexWordTail(next consumerFunc) consumerFunc {
 		if l.Flags.none(LexNoBools) {
 			tok = wordToBool(tok)
 		}
+		if l.Flags.any(LexNetAddrs) {
+			tok = wordToNetAddr(tok)
+		}
 
 		return tok, next, nil
 	}
@@ -699,7 +844,7 @@ func (l *Lexer) lexSegmentTail(r rune) (Token, consumerFunc, error) {
 	case isStatementSep(r):
 		return noToken, l.lexSegment, nil
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected a name character", r)
+	return noToken, nil, l.unexpected(r, "expected a name character")
 }
 
 func (l *Lexer) lexSignedNumber(r rune) (Token, consumerFunc, error) {
@@ -716,7 +861,7 @@ func (l *Lexer) lexSignedNumber(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected number after sign", r)
+	return noToken, nil, l.unexpected(r, "expected number after sign")
 }
 
 func parseBaseInt(base int) convertFunc {
@@ -742,16 +887,16 @@ func (l *Lexer) lexOctalNumber(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected octal digit or separator", r)
+	return noToken, nil, l.unexpected(r, "expected octal digit or separator")
 }
 
 func (l *Lexer) lexNoTerminate(next consumerFunc, expect string) consumerFunc {
 	return func(r rune) (Token, consumerFunc, error) {
 		switch {
 		case r == eof:
-			return noToken, l.lexNoTerminate(next, expect), fmt.Errorf("expected %s: %v", expect, ErrUnexpectedEOF)
+			return noToken, l.lexNoTerminate(next, expect), l.unexpected(r, "expected %s", expect)
 		case isStatementSep(r):
-			return noToken, nil, fmt.Errorf("unexpected character %q: expect %s", r, expect)
+			return noToken, nil, l.unexpected(r, "expected %s", expect)
 		}
 		return next(r)
 	}
@@ -769,7 +914,7 @@ func (l *Lexer) lexHexNum(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected hex digit or separator", r)
+	return noToken, nil, l.unexpected(r, "expected hex digit or separator")
 }
 
 func (l *Lexer) lexBinNum(r rune) (Token, consumerFunc, error) {
@@ -784,7 +929,7 @@ func (l *Lexer) lexBinNum(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected binary digit or separator", r)
+	return noToken, nil, l.unexpected(r, "expected binary digit or separator")
 }
 
 func parseRational(t Token) (Token, error) {
@@ -808,7 +953,7 @@ func (l *Lexer) lexRationalDenomInitial(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected positive number", r)
+	return noToken, nil, l.unexpected(r, "expected positive number")
 }
 
 func (l *Lexer) lexRationalDenomTail(r rune) (Token, consumerFunc, error) {
@@ -823,7 +968,7 @@ func (l *Lexer) lexRationalDenomTail(r rune) (Token, consumerFunc, error) {
 		tok, err := l.valueToken(TRational, parseRational)
 		return tok, l.lexSegment, err
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected rational number", r)
+	return noToken, nil, l.unexpected(r, "expected rational number")
 }
 
 func parseBigFloat(prec uint) convertFunc {
@@ -870,7 +1015,7 @@ func (l *Lexer) lexFloatExponentUnsigned(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected sign or digit", r)
+	return noToken, nil, l.unexpected(r, "expected sign or digit")
 }
 
 func (l *Lexer) lexFloatExponentSignedTail(r rune) (Token, consumerFunc, error) {
@@ -889,7 +1034,7 @@ func (l *Lexer) lexFloatExponentSignedTail(r rune) (Token, consumerFunc, error)
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected digit or separator", r)
+	return noToken, nil, l.unexpected(r, "expected digit or separator")
 }
 
 func (l *Lexer) lexFloatExponentSignedInitial(r rune) (Token, consumerFunc, error) {
@@ -929,7 +1074,7 @@ func (l *Lexer) lexFloatEnd(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected separator", r)
+	return noToken, nil, l.unexpected(r, "expected separator")
 }
 
 func (l *Lexer) lexFloatPointInitial(r rune) (Token, consumerFunc, error) {
@@ -958,6 +1103,7 @@ func (l *Lexer) lexFloatPoint(r rune) (Token, consumerFunc, error) {
 	//
 	// [Ee]         -> lex float from exponent
 	// IntervalUnit -> lex interval unit (lexed as interval from then on)
+	// ByteSizeUnit -> lex byte size unit
 	// [0-9]        -> continue
 	// Sep          -> Float
 	// BarewordRune -> lex bareword
@@ -972,6 +1118,9 @@ func (l *Lexer) lexFloatPoint(r rune) (Token, consumerFunc, error) {
 		return noToken, l.lexFloatExponentUnsigned, nil
 	case allowDurations && isIntervalInitial(r):
 		return l.lexIntervalConsumer(r)
+	case l.Flags.none(LexNoByteSizes) && isByteSizeInitial(r):
+		l.buffer(r, r)
+		return noToken, l.lexByteSizeUnit, nil
 	case isDecimal(r):
 		l.buffer(r, r)
 		return noToken, l.lexFloatPoint, nil
@@ -985,19 +1134,128 @@ func (l *Lexer) lexFloatPoint(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected digit, exponent, or separator", r)
+	return noToken, nil, l.unexpected(r, "expected digit, exponent, or separator")
 }
 
 func parseDuration(tok Token) (Token, error) {
 	text := tok.Value.(string)
-	d, err := time.ParseDuration(text)
+	d, err := parseDurationText(text)
 	if err != nil {
-		return tok, fmt.Errorf("malformed duration %q: %v", text, err)
+		return tok, fmt.Errorf("malformed duration %q: %w", text, err)
 	}
 	tok.Value = d
 	return tok, nil
 }
 
+// errDurationRange is returned by parseDurationText for a duration that does not fit in
+// a time.Duration.
+var errDurationRange = errors.New("duration out of range")
+
+// parseDurationText parses text as time.ParseDuration does, but also accepts days ('d') and weeks
+// ('w'), which are 24 and 168 hours long.
+func parseDurationText(text string) (time.Duration, error) {
+	if !strings.ContainsAny(text, "dw") {
+		return time.ParseDuration(text)
+	}
+
+	neg := strings.HasPrefix(text, "-")
+	body := strings.TrimLeft(text, "-+")
+	isUnit := func(r rune) bool { return !isDecimal(r) && r != rDot }
+	// Errors from time.ParseDuration would quote the rewritten text, so they name text instead.
+	invalid := func() error { return fmt.Errorf("time: invalid duration %q", text) }
+
+	// Days and weeks are converted to hours and summed separately from the other units, which
+	// are left to time.ParseDuration.
+	var rest strings.Builder
+	var total time.Duration
+	for body != "" {
+		i := strings.IndexFunc(body, isUnit)
+		if i <= 0 {
+			return 0, errors.New("expected number and unit")
+		}
+		j := strings.IndexFunc(body[i:], func(r rune) bool { return !isUnit(r) })
+		if j < 0 {
+			j = len(body) - i
+		}
+		num, unit := body[:i], body[i:i+j]
+		body = body[i+j:]
+
+		var hours time.Duration
+		switch unit {
+		case "d":
+			hours = 24
+		case "w":
+			hours = 7 * 24
+		default:
+			rest.WriteString(num + unit)
+			continue
+		}
+		d, err := time.ParseDuration(num + "h")
+		if err != nil {
+			return 0, invalid()
+		}
+		if d > (math.MaxInt64-total)/hours {
+			return 0, errDurationRange
+		}
+		total += d * hours
+	}
+
+	if rest.Len() > 0 {
+		d, err := time.ParseDuration(rest.String())
+		if err != nil {
+			return 0, invalid()
+		}
+		if d > math.MaxInt64-total {
+			return 0, errDurationRange
+		}
+		total += d
+	}
+	if neg {
+		total = -total
+	}
+	return total, nil
+}
+
+func parsePeriod(tok Token) (Token, error) {
+	text := tok.Value.(string)
+	p, err := ParsePeriod(text)
+	if err != nil {
+		return tok, fmt.Errorf("malformed period %q: %w", text, err)
+	}
+	tok.Value = p
+	return tok, nil
+}
+
+func isPeriodRune(r rune) bool {
+	return isDecimal(r) || r == rDot || strings.ContainsRune("YMWDTHS", r)
+}
+
+func (l *Lexer) lexPeriod(r rune) (Token, consumerFunc, error) {
+	//
+	// Occurs after a 'P' begins a token. The token is a period if it is an ISO 8601 duration
+	// once it ends, and a bareword otherwise, so that words such as "PUT" are unaffected.
+	//
+	// PeriodRune   -> continue
+	// Sep          -> Period (or Bareword)
+	// BarewordRune -> lex bareword
+	//
+	switch {
+	case isPeriodRune(r):
+		l.buffer(r, r)
+		return noToken, l.lexPeriod, nil
+	case isStatementSep(r) || r == eof:
+		l.unread()
+		if !isPeriodText(l.strbuf.String()) {
+			return l.lexBecomeWord(-1)
+		}
+		tok, err := l.valueToken(TPeriod, parsePeriod)
+		return tok, l.lexSegment, err
+	case isBarewordTransition(r):
+		return l.lexBecomeWord(r)
+	}
+	return noToken, nil, l.unexpected(r, "expected period component or separator")
+}
+
 // lexIntervalConsumer returns the next consumerFunc for a given interval unit, depending on whether
 // the unit is necessarily long (two runes), maybe long (one to two runes), or short (one rune).
 func (l *Lexer) lexIntervalConsumer(r rune) (Token, consumerFunc, error) {
@@ -1017,6 +1275,7 @@ func (l *Lexer) lexIntervalUnitMaybeLong(r rune) (Token, consumerFunc, error) {
 	// It follows at least one digit.
 	//
 	// 's' | [0-9]  -> lex interval initial
+	// [BbIi]       -> lex byte size unit (only if 'm' follows a plain number, as in "64mb")
 	// Sep          -> Interval
 	// BarewordRune -> lex bareword
 	//
@@ -1024,12 +1283,15 @@ func (l *Lexer) lexIntervalUnitMaybeLong(r rune) (Token, consumerFunc, error) {
 	case r == 's' || isDecimal(r):
 		l.buffer(r, r)
 		return noToken, l.lexIntervalInitial, nil
+	case l.Flags.none(LexNoByteSizes) && strings.ContainsRune("bBiI", r) && l.isByteSizeNumber():
+		l.buffer(r, r)
+		return noToken, l.lexByteSizeUnit, nil
 	case isStatementSep(r) || r == eof:
 		return l.lexIntervalInitial(r)
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected digit or 's'", r)
+	return noToken, nil, l.unexpected(r, "expected digit or 's'")
 }
 
 func (l *Lexer) lexIntervalUnitLong(r rune) (Token, consumerFunc, error) {
@@ -1051,7 +1313,7 @@ func (l *Lexer) lexIntervalUnitLong(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected 's'", r)
+	return noToken, nil, l.unexpected(r, "expected 's'")
 }
 
 func (l *Lexer) lexIntervalFloatInitial(r rune) (Token, consumerFunc, error) {
@@ -1092,7 +1354,7 @@ func (l *Lexer) lexIntervalFloatTail(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, l.lexIntervalFloatTail, fmt.Errorf("unexpected character %s: expected digit or interval unit", TDuration)
+	return noToken, l.lexIntervalFloatTail, l.unexpected(r, "expected digit or interval unit")
 }
 
 func (l *Lexer) lexIntervalInitial(r rune) (Token, consumerFunc, error) {
@@ -1133,7 +1395,97 @@ func (l *Lexer) lexInterval(r rune) (Token, consumerFunc, error) {
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected number or interval unit", r)
+	return noToken, nil, l.unexpected(r, "expected number or interval unit")
+}
+
+// byteSizeUnits maps byte size units, in lowercase, to the number of bytes in each unit.
+var byteSizeUnits = map[string]int64{
+	"b":   1,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"tb":  1e12,
+	"tib": 1 << 40,
+}
+
+// splitByteSize splits the text of a byte size into its number and unit.
+func splitByteSize(text string) (num, unit string) {
+	i := strings.IndexFunc(text, isByteSizeUnitRune)
+	if i < 0 {
+		return text, ""
+	}
+	return text[:i], text[i:]
+}
+
+func parseByteSize(tok Token) (Token, error) {
+	text := tok.Value.(string)
+	num, unit := splitByteSize(text)
+	scale, ok := byteSizeUnits[strings.ToLower(unit)]
+	if !ok {
+		return tok, fmt.Errorf("malformed byte size %q: unknown unit %q", text, unit)
+	}
+	var x big.Rat
+	if _, ok := x.SetString(num); !ok {
+		return tok, fmt.Errorf("malformed byte size %q", text)
+	}
+	x.Mul(&x, new(big.Rat).SetInt64(scale))
+	if !x.IsInt() {
+		return tok, fmt.Errorf("byte size %q is not a whole number of bytes", text)
+	}
+	tok.Value = new(big.Int).Set(x.Num())
+	return tok, nil
+}
+
+// isByteSizeNumber returns true if the text lexed so far, other than its last rune, is a number
+// without units that may begin a byte size.
+func (l *Lexer) isByteSizeNumber() bool {
+	text := l.strbuf.String()
+	return strings.IndexFunc(text[:len(text)-1], func(r rune) bool {
+		return !isDecimal(r) && !isSign(r) && r != rDot
+	}) < 0
+}
+
+func (l *Lexer) lexByteSizeUnit(r rune) (Token, consumerFunc, error) {
+	//
+	// Occurs after the first rune of a byte size unit. The unit is only checked once the token
+	// ends, so that a number followed by anything other than a byte size unit becomes a bareword.
+	//
+	// [A-Za-z]     -> continue
+	// Sep          -> ByteSize (or Bareword, if the unit is not a byte size unit)
+	// BarewordRune -> lex bareword
+	//
+	switch {
+	case isByteSizeUnitRune(r):
+		l.buffer(r, r)
+		return noToken, l.lexByteSizeUnit, nil
+	case isStatementSep(r) || r == eof:
+		l.unread()
+		if _, unit := splitByteSize(l.strbuf.String()); byteSizeUnits[strings.ToLower(unit)] == 0 {
+			return l.lexBecomeWord(-1)
+		}
+		tok, err := l.valueToken(TByteSize, parseByteSize)
+		return tok, l.lexSegment, err
+	case isBarewordTransition(r):
+		return l.lexBecomeWord(r)
+	}
+	return noToken, nil, l.unexpected(r, "expected byte size unit or separator")
+}
+
+// lexZeroBytes returns a consumerFunc for the rune following "0b", which is zero bytes if it is
+// followed by a separator and a binary integer otherwise.
+func (l *Lexer) lexZeroBytes(b rune) consumerFunc {
+	return func(r rune) (Token, consumerFunc, error) {
+		if isStatementSep(r) || r == eof {
+			l.unread()
+			l.buffer(-1, b)
+			tok, err := l.valueToken(TByteSize, parseByteSize)
+			return tok, l.lexSegment, err
+		}
+		return l.lexBinNum(r)
+	}
 }
 
 func (l *Lexer) lexZero(r rune) (Token, consumerFunc, error) {
@@ -1144,9 +1496,11 @@ func (l *Lexer) lexZero(r rune) (Token, consumerFunc, error) {
 	// EOF          -> Integer
 	// [0-7]        -> lex octal
 	// '/'          -> lex rational
-	// [Bb]         -> lex binary
+	// [Bb]         -> lex binary (or zero bytes, if followed by a separator)
 	// [Xx]         -> lex hexadecimal
 	// '.'          -> lex float
+	// IntervalUnit -> lex interval
+	// ByteSizeUnit -> lex byte size unit
 	// 'Ee'         -> lex float from exponent (necessarily zero)
 	// BarewordRune -> lex bareword
 	//
@@ -1166,23 +1520,90 @@ func (l *Lexer) lexZero(r rune) (Token, consumerFunc, error) {
 		return noToken, l.lexRationalDenomInitial, nil
 	case allowBaseInts && (r == 'b' || r == 'B'):
 		l.buffer(r, -1)
+		if l.Flags.none(LexNoByteSizes) {
+			return noToken, l.lexZeroBytes(r), nil
+		}
 		return noToken, l.lexNoTerminate(l.lexBinNum, "binary digit"), nil
 	case allowBaseInts && (r == 'x' || r == 'X'):
 		l.buffer(r, -1)
 		return noToken, l.lexNoTerminate(l.lexHexNum, "hex digit"), nil
-	case !l.Flags.all(LexNoDurations|LexNoFloats) && r == rDot:
-		// Continue parsing here unless both floats and durations are disabled
+	case !l.Flags.all(LexNoDurations|LexNoFloats|LexNoByteSizes) && r == rDot:
+		// Continue parsing here unless floats, durations, and byte sizes are disabled
 		l.buffer(r, r)
 		return noToken, l.lexFloatPointInitial, nil
 	case l.Flags.none(LexNoDurations) && isIntervalInitial(r):
 		return l.lexIntervalConsumer(r)
+	case l.Flags.none(LexNoByteSizes) && isByteSizeInitial(r):
+		l.buffer(r, r)
+		return noToken, l.lexByteSizeUnit, nil
 	case l.Flags.none(LexNoFloats) && (r == 'E' || r == 'e'):
 		l.buffer(r, r)
 		return noToken, l.lexFloatExponentUnsigned, nil
 	case isBarewordTransition(r):
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected b, x, X, octal, duration unit, or separator", r)
+	return noToken, nil, l.unexpected(r, "expected b, x, X, octal, duration unit, or separator")
+}
+
+// timestampPattern matches the text of a date or timestamp. Text that begins like a date but does
+// not match it is a bareword.
+var timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2}))?$`)
+
+const dateLayout = "2006-01-02"
+
+func isTimestampRune(r rune) bool {
+	return isDecimal(r) || r == '-' || r == '+' || r == ':' || r == rDot || r == 'T' || r == 'Z'
+}
+
+func parseTimestamp(tok Token) (Token, error) {
+	text := tok.Value.(string)
+	layout := time.RFC3339Nano
+	if tok.Kind == TDate {
+		layout = dateLayout
+	}
+	t, err := time.Parse(layout, text)
+	if err != nil {
+		return tok, fmt.Errorf("malformed %v %q: %w", tok.Kind, text, err)
+	}
+	tok.Value = t
+	return tok, nil
+}
+
+// isYear returns true if the text lexed so far is four digits without a sign.
+func (l *Lexer) isYear() bool {
+	text := l.strbuf.String()
+	return len(text) == 4 && strings.IndexFunc(text, func(r rune) bool { return !isDecimal(r) }) < 0
+}
+
+func (l *Lexer) lexTimestamp(r rune) (Token, consumerFunc, error) {
+	//
+	// Occurs after the '-' following the year of a date. The token is a date or timestamp if it
+	// matches timestampPattern once it ends, and a bareword otherwise.
+	//
+	// TimestampRune -> continue
+	// Sep           -> Date | Timestamp (or Bareword)
+	// BarewordRune  -> lex bareword
+	//
+	switch {
+	case isTimestampRune(r):
+		l.buffer(r, r)
+		return noToken, l.lexTimestamp, nil
+	case isStatementSep(r) || r == eof:
+		l.unread()
+		text := l.strbuf.String()
+		if !timestampPattern.MatchString(text) {
+			return l.lexBecomeWord(-1)
+		}
+		kind := TTimestamp
+		if len(text) == len(dateLayout) {
+			kind = TDate
+		}
+		tok, err := l.valueToken(kind, parseTimestamp)
+		return tok, l.lexSegment, err
+	case isBarewordTransition(r):
+		return l.lexBecomeWord(r)
+	}
+	return noToken, nil, l.unexpected(r, "expected date, time, or separator")
 }
 
 func (l *Lexer) lexNonZero(r rune) (Token, consumerFunc, error) {
@@ -1193,6 +1614,8 @@ func (l *Lexer) lexNonZero(r rune) (Token, consumerFunc, error) {
 	// EOF          -> Integer
 	// [0-9]        -> repeat
 	// IntervalUnit -> lex interval
+	// ByteSizeUnit -> lex byte size unit
+	// '-'          -> lex date or timestamp (only after four digits, as in "2006-01-02")
 	// '#'          -> lex base number (base '#' {base-digit})
 	// '/'          -> lex rational (integer '/' integer)
 	// '.'          -> lex float from fraction (integer '.' digit {digit} [exponent])
@@ -1209,6 +1632,12 @@ func (l *Lexer) lexNonZero(r rune) (Token, consumerFunc, error) {
 		return noToken, l.lexNonZero, nil
 	case l.Flags.none(LexNoDurations) && isIntervalInitial(r):
 		return l.lexIntervalConsumer(r)
+	case l.Flags.none(LexNoByteSizes) && isByteSizeInitial(r):
+		l.buffer(r, r)
+		return noToken, l.lexByteSizeUnit, nil
+	case l.Flags.none(LexNoTimestamps) && r == '-' && l.isYear():
+		l.buffer(r, r)
+		return noToken, l.lexTimestamp, nil
 	}
 
 	switch {
@@ -1230,7 +1659,7 @@ func (l *Lexer) lexNonZero(r rune) (Token, consumerFunc, error) {
 	case l.Flags.none(LexNoRationals) && r == rFracSep:
 		l.buffer(r, r)
 		return noToken, l.lexRationalDenomInitial, nil
-	case !l.Flags.all(LexNoDurations|LexNoFloats) && r == rDot:
+	case !l.Flags.all(LexNoDurations|LexNoFloats|LexNoByteSizes) && r == rDot:
 		l.buffer(r, r)
 		return noToken, l.lexFloatPointInitial, nil
 	case l.Flags.none(LexNoFloats) && (r == 'E' || r == 'e'):
@@ -1242,7 +1671,7 @@ func (l *Lexer) lexNonZero(r rune) (Token, consumerFunc, error) {
 		return l.lexBecomeWord(r)
 	}
 
-	return noToken, nil, fmt.Errorf("unexpected character %q: expected #, decimal point, or separator", r)
+	return noToken, nil, l.unexpected(r, "expected #, decimal point, or separator")
 }
 
 func isBaseDigit(base int, r rune) bool {
@@ -1282,7 +1711,7 @@ func (l *Lexer) lexBaseNumber(neg bool, base int) (consumer consumerFunc) {
 		if !(isStatementSep(r) || r == eof) {
 			// An example for this case is "4#\x00", since a NUL won't cover any valid
 			// case for this.
-			return noToken, nil, fmt.Errorf("unexpected character %q: expected base-%d digit", r, base)
+			return noToken, nil, l.unexpected(r, "expected base-%d digit", base)
 		}
 
 		tok, err := l.valueToken(TBaseInt, parseBaseInt(base))
@@ -1295,6 +1724,172 @@ func (l *Lexer) lexBaseNumber(neg bool, base int) (consumer consumerFunc) {
 	return consumer
 }
 
+func (l *Lexer) lexHeredocStart(r rune) (Token, consumerFunc, error) {
+	//
+	// Occurs after a '<' at the start of a token and expects a second '<' to begin a heredoc.
+	// Otherwise, the '<' begins a word.
+	//
+	if r == '<' {
+		l.buffer(r, r)
+		return noToken, l.lexHeredocName, nil
+	}
+	l.unread()
+	return l.lexBecomeWord(-1)
+}
+
+func (l *Lexer) lexHeredocName(r rune) (Token, consumerFunc, error) {
+	//
+	// Consume the heredoc's delimiter up to the end of its line. If the "<<" is not followed
+	// by a delimiter and a newline, such as in "<<=" or "<<EOF;", the token is a word. Spaces
+	// and tabs may follow the delimiter.
+	//
+	name := l.strbuf.String()[2:]
+	switch {
+	case isHeredocNameRune(r) && (name != "" || !isDecimal(r)):
+		l.buffer(r, r)
+		return noToken, l.lexHeredocName, nil
+	case (r == ' ' || r == '\t') && name != "":
+		l.heredocSpace = l.lastPos
+		l.buffer(r, -1)
+		return noToken, l.lexHeredocNameSpace, nil
+	case r == '\r' && name != "":
+		l.buffer(r, -1)
+		return noToken, l.lexHeredocNameCR, nil
+	case r == '\n' && name != "":
+		return l.lexHeredocNameCR(r)
+	}
+	l.unread()
+	return l.lexBecomeWord(-1)
+}
+
+func (l *Lexer) lexHeredocNameSpace(r rune) (Token, consumerFunc, error) {
+	//
+	// Consume spaces and tabs following the heredoc's delimiter. If anything other than a
+	// newline follows them, such as in "<<EOF x", the delimiter is a word followed by
+	// whitespace.
+	//
+	switch r {
+	case ' ', '\t':
+		l.buffer(r, -1)
+		return noToken, l.lexHeredocNameSpace, nil
+	case '\r':
+		l.buffer(r, -1)
+		return noToken, l.lexHeredocNameCR, nil
+	case '\n':
+		return l.lexHeredocNameCR(r)
+	}
+	l.unread()
+
+	n := l.strbuf.Len()
+	space := Token{
+		Start: l.heredocSpace,
+		End:   l.scanPos(),
+		Kind:  TWhitespace,
+		Raw:   append([]byte(nil), l.buf.Bytes()[n:]...),
+		Value: "",
+	}
+	l.buf.Truncate(n)
+	tok := l.token(TWord, true)
+	tok.End = l.heredocSpace
+	return tok, func(r rune) (Token, consumerFunc, error) {
+		l.unread()
+		return space, l.lexSegment, nil
+	}, nil
+}
+
+func (l *Lexer) lexHeredocNameCR(r rune) (Token, consumerFunc, error) {
+	//
+	// Occurs at the end of the heredoc's first line and expects a newline.
+	//
+	if r != '\n' {
+		return noToken, nil, l.unexpected(r, "expected newline after heredoc delimiter")
+	}
+	l.heredoc = l.strbuf.String()[2:]
+	l.heredocMatch = 0
+	l.strbuf.Reset()
+	l.buffer(r, -1)
+	return noToken, l.lexHeredocBody, nil
+}
+
+func (l *Lexer) lexHeredocBody(r rune) (Token, consumerFunc, error) {
+	//
+	// Consume lines until a line holding only indentation and the delimiter.
+	//
+	if r == eof {
+		return noToken, nil, l.unexpected(r, "expected heredoc delimiter %s", l.heredoc)
+	}
+	l.buffer(r, -1)
+
+	switch m := l.heredocMatch; {
+	case r == '\n':
+		l.heredocMatch = 0
+	case m < 0:
+	case m == 0 && (r == ' ' || r == '\t'):
+	case r == rune(l.heredoc[m]):
+		l.heredocMatch++
+		if l.heredocMatch == len(l.heredoc) {
+			return noToken, l.lexHeredocEnd, nil
+		}
+	default:
+		l.heredocMatch = -1
+	}
+	return noToken, l.lexHeredocBody, nil
+}
+
+func (l *Lexer) lexHeredocEnd(r rune) (Token, consumerFunc, error) {
+	//
+	// Occurs after the delimiter at the start of a line. If the delimiter continues, such as
+	// "EOFX" for the delimiter "EOF", the line is part of the heredoc's text. Otherwise, the
+	// heredoc ends.
+	//
+	if isHeredocNameRune(r) {
+		l.heredocMatch = -1
+		return l.lexHeredocBody(r)
+	}
+	l.unread()
+
+	tok := l.token(THeredoc, true)
+	text, bad := heredocText(tok.Raw, l.heredoc)
+	if bad >= 0 {
+		return noToken, nil, heredocIndentError(tok, bad)
+	}
+	tok.Value = text
+	return tok, l.lexSegment, nil
+}
+
+// isHeredocNameRune returns true if r may appear in a heredoc delimiter.
+func isHeredocNameRune(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || isDecimal(r)
+}
+
+// heredocText returns the text of the heredoc raw with the delimiter delim. Each line has the
+// indentation of the closing delimiter removed, and the newline before the closing delimiter is
+// not part of the text. Lines with only spaces and tabs may have less indentation and are empty.
+// If any other line does not begin with the closing delimiter's indentation, heredocText returns
+// the byte offset of that line in raw. Otherwise, it returns -1.
+func heredocText(raw []byte, delim string) (string, int) {
+	off := bytes.IndexByte(raw, '\n') + 1
+	last := bytes.LastIndexByte(raw, '\n') + 1
+	indent := raw[last : len(raw)-len(delim)]
+
+	var sb strings.Builder
+	for off < last {
+		end := off + bytes.IndexByte(raw[off:], '\n')
+		line := bytes.TrimSuffix(raw[off:end], []byte("\r"))
+		switch {
+		case bytes.HasPrefix(line, indent):
+			sb.Write(line[len(indent):])
+		case len(bytes.TrimLeft(line, " \t")) > 0:
+			return "", off
+		}
+		if end+1 < last {
+			sb.WriteByte('\n')
+		}
+		off = end + 1
+	}
+	return sb.String(), -1
+}
+
 func (l *Lexer) lexRawString(r rune) (Token, consumerFunc, error) {
 	//
 	// Consume any rune between `s without filtering except for `` (which is an escaped `).
@@ -1302,7 +1897,7 @@ func (l *Lexer) lexRawString(r rune) (Token, consumerFunc, error) {
 	l.buffer(r, -1)
 	switch r {
 	case eof:
-		return noToken, l.lexRawString, fmt.Errorf("expected ending backquote: %v", ErrUnexpectedEOF)
+		return noToken, l.lexRawString, l.unexpected(r, "expected ending backquote")
 	case rBackQuote:
 		return noToken, l.lexRawStringEscape, nil
 	}
@@ -1325,15 +1920,18 @@ func (l *Lexer) lexRawStringEscape(r rune) (Token, consumerFunc, error) {
 
 func (l *Lexer) lexString(r rune) (Token, consumerFunc, error) {
 	//
-	// Consume runes until an ending double-quote or backslash for escapes is found.
+	// Consume runes until the closing quote or backslash for escapes is found.
 	//
 	l.buffer(r, -1)
 	switch r {
 	case eof:
-		return noToken, l.lexString, fmt.Errorf("expected close of string: %v", ErrUnexpectedEOF)
+		return noToken, l.lexString, l.unexpected(r, "expected close of string")
 	case '\\':
+		if l.Flags.any(LexNginxEscapes) {
+			return noToken, l.lexNginxStringEscape, nil
+		}
 		return noToken, l.lexStringEscape, nil
-	case rDoubleQuote:
+	case l.quote:
 		return l.token(TString, true), l.lexSegment, nil
 	}
 	l.buffer(-1, r)
@@ -1349,7 +1947,7 @@ func (l *Lexer) lexStringEscape(r rune) (Token, consumerFunc, error) {
 	next := l.lexString
 	switch r {
 	case eof:
-		return noToken, l.lexStringEscape, fmt.Errorf("expected string escape code: %v", ErrUnexpectedEOF)
+		return noToken, l.lexStringEscape, l.unexpected(r, "expected string escape code")
 	case 'a':
 		l.buffer(r, '\a')
 	case 'b':
@@ -1366,8 +1964,8 @@ func (l *Lexer) lexStringEscape(r rune) (Token, consumerFunc, error) {
 		l.buffer(r, '\v')
 	case '\\':
 		l.buffer(r, '\\')
-	case rDoubleQuote:
-		l.buffer(r, rDoubleQuote)
+	case l.quote:
+		l.buffer(r, l.quote)
 	case 'x': // 2 hex digits
 		l.buffer(r, -1)
 		next = l.lexHexStringEscape(1, func(u uint32) { l.strbuf.WriteByte(byte(u)) })
@@ -1380,11 +1978,74 @@ func (l *Lexer) lexStringEscape(r rune) (Token, consumerFunc, error) {
 	case '0', '1', '2', '3', '4', '5', '6', '7': // 3 octal digits
 		return l.lexOctalStringEscape()(r)
 	default:
-		return noToken, nil, fmt.Errorf("invalid escape character %q", r)
+		err := l.unexpected(r, "expected escape code")
+		err.Code, err.Msg = LexErrInvalidEscape, fmt.Sprintf("invalid escape character %q", r)
+		return noToken, l.skipString(r), err
 	}
 	return noToken, next, nil
 }
 
+// skipString returns the consumer to resume lexing with after an error at r in a quoted string.
+// The rest of the string is discarded so that lexing does not resume in the middle of it.
+func (l *Lexer) skipString(r rune) consumerFunc {
+	switch r {
+	case l.quote:
+		return l.lexSegment
+	case '\\':
+		return l.lexSkipStringEscape
+	}
+	return l.lexSkipString
+}
+
+func (l *Lexer) lexSkipString(r rune) (Token, consumerFunc, error) {
+	//
+	// Discard runes up to and including the closing quote of a string that had an error.
+	//
+	switch r {
+	case eof:
+		return l.lexSegment(r)
+	case '\\':
+		return noToken, l.lexSkipStringEscape, nil
+	case l.quote:
+		l.startPos = l.scanPos()
+		return noToken, l.lexSegment, nil
+	}
+	return noToken, l.lexSkipString, nil
+}
+
+func (l *Lexer) lexSkipStringEscape(r rune) (Token, consumerFunc, error) {
+	//
+	// Discard the rune following a backslash in a string that had an error.
+	//
+	if r == eof {
+		return l.lexSegment(r)
+	}
+	return noToken, l.lexSkipString, nil
+}
+
+func (l *Lexer) lexNginxStringEscape(r rune) (Token, consumerFunc, error) {
+	//
+	// Consume the rune following a backslash. Unlike lexStringEscape, a backslash followed by
+	// anything other than one of nginx's escapes is kept along with the rune following it.
+	//
+	switch r {
+	case eof:
+		return noToken, l.lexNginxStringEscape, l.unexpected(r, "expected string escape code")
+	case 'n':
+		l.buffer(r, '\n')
+	case 'r':
+		l.buffer(r, '\r')
+	case 't':
+		l.buffer(r, '\t')
+	case '\\', rDoubleQuote, rSingleQuote:
+		l.buffer(r, r)
+	default:
+		l.buffer(r, '\\')
+		l.buffer(-1, r)
+	}
+	return noToken, l.lexString, nil
+}
+
 func (l *Lexer) lexOctalStringEscape() (consumer consumerFunc) {
 	//
 	// Read three octal digits and buffer the resulting byte (after truncating it to 8 bits).
@@ -1395,9 +2056,9 @@ func (l *Lexer) lexOctalStringEscape() (consumer consumerFunc) {
 	)
 	consumer = func(r rune) (Token, consumerFunc, error) {
 		if r == eof {
-			return noToken, consumer, fmt.Errorf("expected octal digit in string escape: %v", ErrUnexpectedEOF)
+			return noToken, consumer, l.unexpected(r, "expected octal digit in string escape")
 		} else if !isOctal(r) {
-			return noToken, nil, fmt.Errorf("unexpected character %q: expected octal digit", r)
+			return noToken, l.skipString(r), l.unexpected(r, "expected octal digit")
 		}
 		l.buffer(r, -1)
 		final = (final << 3) | byte(r-'0')
@@ -1431,9 +2092,9 @@ func (l *Lexer) lexHexStringEscape(numbytes int, write func(final uint32)) (cons
 	want := numbytes * 2
 	consumer = func(r rune) (Token, consumerFunc, error) {
 		if r == eof {
-			return noToken, consumer, fmt.Errorf("expected hex digit in string escape: %v", ErrUnexpectedEOF)
+			return noToken, consumer, l.unexpected(r, "expected hex digit in string escape")
 		} else if !isHex(r) {
-			return noToken, nil, fmt.Errorf("unexpected character %q: expected hex digit", r)
+			return noToken, l.skipString(r), l.unexpected(r, "expected hex digit")
 		}
 		l.buffer(r, -1)
 		final = (final << 4) | uint32(xtoi(r))
@@ -1471,7 +2132,7 @@ func (l *Lexer) lexSpecial(r rune) (Token, consumerFunc, error) {
 		l.buffer(rSpecial, rSpecial)
 		return l.lexBecomeWord(r)
 	}
-	return noToken, nil, fmt.Errorf("unexpected character %q after #: expected { or /", r)
+	return noToken, nil, l.unexpected(r, "expected { or / after #")
 }
 
 func (l *Lexer) lexEscapeRegexp(r rune) (Token, consumerFunc, error) {
@@ -1503,7 +2164,7 @@ func (l *Lexer) lexRegexp(r rune) (Token, consumerFunc, error) {
 	//
 	switch r {
 	case eof:
-		return noToken, l.lexRegexp, fmt.Errorf("expected end of regexp: %v", ErrUnexpectedEOF)
+		return noToken, l.lexRegexp, l.unexpected(r, "expected end of regexp")
 	case '\\':
 		l.buffer(r, -1)
 		return noToken, l.lexEscapeRegexp, nil
@@ -1532,3 +2193,23 @@ func wordToBool(tok Token) Token {
 	}
 	return tok
 }
+
+// wordToNetAddr converts a word that is an IP address, prefix, or address and port pair to a
+// TAddr, TPrefix, or TAddrPort token. Other tokens are returned as-is.
+func wordToNetAddr(tok Token) Token {
+	if tok.Kind != TWord {
+		return tok
+	}
+	s, ok := tok.Value.(string)
+	if !ok || !strings.ContainsAny(s, ".:") {
+		return tok
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		tok.Kind, tok.Value = TAddr, addr
+	} else if prefix, err := netip.ParsePrefix(s); err == nil {
+		tok.Kind, tok.Value = TPrefix, prefix
+	} else if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		tok.Kind, tok.Value = TAddrPort, addrPort
+	}
+	return tok
+}
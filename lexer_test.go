@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
@@ -47,6 +48,46 @@ func durCase(text string) tokenCase {
 	}
 }
 
+func bytesCase(n int64, text string) tokenCase {
+	return tokenCase{
+		Token: Token{
+			Kind:  TByteSize,
+			Raw:   []byte(text),
+			Value: big.NewInt(n),
+		},
+	}
+}
+
+func addrCase(text string) tokenCase {
+	tok := Token{Raw: []byte(text)}
+	if addr, err := netip.ParseAddr(text); err == nil {
+		tok.Kind, tok.Value = TAddr, addr
+	} else if prefix, err := netip.ParsePrefix(text); err == nil {
+		tok.Kind, tok.Value = TPrefix, prefix
+	} else {
+		tok.Kind, tok.Value = TAddrPort, netip.MustParseAddrPort(text)
+	}
+	return tokenCase{Token: tok}
+}
+
+func timeCase(text string) tokenCase {
+	kind, layout := TTimestamp, time.RFC3339Nano
+	if len(text) == len("2006-01-02") {
+		kind, layout = TDate, "2006-01-02"
+	}
+	t, err := time.Parse(layout, text)
+	if err != nil {
+		panic("error creating time: " + err.Error())
+	}
+	return tokenCase{
+		Token: Token{
+			Kind:  kind,
+			Raw:   []byte(text),
+			Value: t,
+		},
+	}
+}
+
 func floatCase(text string, precision uint) tokenCase {
 	if precision <= 0 {
 		precision = DefaultPrecision
@@ -141,6 +182,14 @@ func compareValue(l, r any) bool {
 	case time.Duration:
 		rr, ok := r.(time.Duration)
 		return ok && ll == rr
+	case time.Time:
+		rr, ok := r.(time.Time)
+		return ok && ll.Equal(rr)
+	case Period:
+		rr, ok := r.(Period)
+		return ok && ll == rr
+	case netip.Addr, netip.Prefix, netip.AddrPort:
+		return l == r
 	case *big.Int:
 		rr, ok := r.(*big.Int)
 		return ok && ll.Cmp(rr) == 0
@@ -399,6 +448,185 @@ func TestLexNoRegexpsFlag(t *testing.T) {
 	}.Test)
 }
 
+func TestLexHashCommentsFlag(t *testing.T) {
+	flagTest{
+		Flags: LexHashComments,
+		Seq:   "# a comment\nuri //a#b;",
+		On: tokenSeq{
+			{Token: Token{Kind: TComment, Raw: []byte("# a comment"), Value: " a comment"}},
+			_ws, wordCase("uri"), _ws, wordCase("//a#b"), _semicolon,
+			_eof,
+		},
+		Off: tokenSeq{
+			wordCase("#"), _ws, wordCase("a"), _ws, wordCase("comment"),
+			_ws, wordCase("uri"), _ws, commentCase("a#b;"),
+			_eof,
+		},
+	}.Test(t)
+
+	flagTest{
+		Flags: LexHashComments,
+		Seq:   "#\n// x",
+		On: tokenSeq{
+			{Token: Token{Kind: TComment, Raw: []byte("#"), Value: ""}},
+			_ws, wordCase("//"), _ws, wordCase("x"),
+			_eof,
+		},
+		Off: tokenSeq{wordCase("#"), _ws, commentCase(" x"), _eof},
+	}.Test(t)
+}
+
+func TestLexSingleQuotesFlag(t *testing.T) {
+	flagTest{
+		Flags: LexSingleQuotes,
+		Seq:   `'a "b" \'c\'' it's`,
+		On: tokenSeq{
+			{Token: Token{Kind: TString, Raw: []byte(`'a "b" \'c\''`), Value: `a "b" 'c'`}},
+			_ws, wordCase("it's"),
+			_eof,
+		},
+		Off: tokenSeq{
+			wordCase("'a"), _ws,
+			quoteCase("b"), _ws,
+			wordCase(`\'c\''`), _ws,
+			wordCase("it's"),
+			_eof,
+		},
+	}.Test(t)
+
+	tokenSeq{_error}.RunFlags(t, LexSingleQuotes, `'\"'`)
+	tokenSeq{_error}.RunFlags(t, LexSingleQuotes, `'unterminated`)
+}
+
+func TestLexNginxEscapesFlag(t *testing.T) {
+	flagTest{
+		Flags: LexNginxEscapes,
+		Seq:   `"^/(\d+)\.\"x\"\\\t"`,
+		On: tokenSeq{
+			{Token: Token{Kind: TString, Raw: []byte(`"^/(\d+)\.\"x\"\\\t"`), Value: "^/(\\d+)\\.\"x\"\\\t"}},
+			_eof,
+		},
+		Off: tokenSeq{_error},
+	}.Test(t)
+
+	flagTest{
+		Flags: LexNginxEscapes | LexSingleQuotes,
+		Seq:   `'\'\"\a\n'`,
+		On: tokenSeq{
+			{Token: Token{Kind: TString, Raw: []byte(`'\'\"\a\n'`), Value: "'\"\\a\n"}},
+			_eof,
+		},
+		Off: tokenSeq{wordCase(`'\'\`), _error},
+	}.Test(t)
+}
+
+func TestLexBracketWordsFlag(t *testing.T) {
+	flagTest{
+		Flags: LexBracketWords,
+		Seq:   `listen [::]:80; ~ ^/[a-z]+$`,
+		On: tokenSeq{
+			wordCase("listen"), _ws, wordCase("[::]:80"), _semicolon,
+			_ws, wordCase("~"), _ws, wordCase("^/[a-z]+$"),
+			_eof,
+		},
+		Off: tokenSeq{
+			wordCase("listen"), _ws, _bracketopen, wordCase("::"), _bracketclose, wordCase(":80"), _semicolon,
+			_ws, wordCase("~"), _ws, wordCase("^/[a-z]+$"),
+			_eof,
+		},
+	}.Test(t)
+}
+
+func TestLexNoByteSizesFlag(t *testing.T) {
+	flagTest{
+		Flags: LexNoByteSizes,
+		Seq:   `64mb 1.5KiB 0b`,
+		On:    tokenSeq{wordCase("64mb"), _ws, wordCase("1.5KiB"), _ws, _error},
+		Off:   tokenSeq{bytesCase(64e6, "64mb"), _ws, bytesCase(1536, "1.5KiB"), _ws, bytesCase(0, "0b"), _eof},
+	}.Test(t)
+}
+
+func TestLexNoTimestampsFlag(t *testing.T) {
+	flagTest{
+		Flags: LexNoTimestamps,
+		Seq:   `2026-10-16 2026-10-16T04:00:00Z`,
+		On:    tokenSeq{wordCase("2026-10-16"), _ws, wordCase("2026-10-16T04:00:00Z"), _eof},
+		Off:   tokenSeq{timeCase("2026-10-16"), _ws, timeCase("2026-10-16T04:00:00Z"), _eof},
+	}.Test(t)
+}
+
+func TestLexNetAddrsFlag(t *testing.T) {
+	t.Run("Addrs", flagTest{
+		Flags: LexNetAddrs,
+		Seq:   `allow 10.0.0.1 ::1 fe80::1%eth0 ::ffff:10.0.0.1 10.0.0.0/8 2001:db8::/32 0.0.0.0:80;`,
+		On: tokenSeq{
+			wordCase("allow"),
+			_ws, addrCase("10.0.0.1"),
+			_ws, addrCase("::1"),
+			_ws, addrCase("fe80::1%eth0"),
+			_ws, addrCase("::ffff:10.0.0.1"),
+			_ws, addrCase("10.0.0.0/8"),
+			_ws, addrCase("2001:db8::/32"),
+			_ws, addrCase("0.0.0.0:80"),
+			_semicolon,
+			_eof,
+		},
+		Off: tokenSeq{
+			wordCase("allow"),
+			_ws, wordCase("10.0.0.1"),
+			_ws, wordCase("::1"),
+			_ws, wordCase("fe80::1%eth0"),
+			_ws, wordCase("::ffff:10.0.0.1"),
+			_ws, wordCase("10.0.0.0/8"),
+			_ws, wordCase("2001:db8::/32"),
+			_ws, wordCase("0.0.0.0:80"),
+			_semicolon,
+			_eof,
+		},
+	}.Test)
+
+	// Words that are not valid addresses, such as those with out of range octets, prefix
+	// lengths, or ports, are left as words. Quoted strings are never converted.
+	t.Run("Words", flagTest{
+		Flags: LexNetAddrs,
+		Seq:   `10.0.0.256 10.0.0.0/33 0.0.0.0:65536 1.2.3 a:b "10.0.0.1" [::1]:80`,
+		On: tokenSeq{
+			wordCase("10.0.0.256"),
+			_ws, wordCase("10.0.0.0/33"),
+			_ws, wordCase("0.0.0.0:65536"),
+			_ws, wordCase("1.2.3"),
+			_ws, wordCase("a:b"),
+			_ws, quoteCase("10.0.0.1"),
+			_ws, _bracketopen, addrCase("::1"), _bracketclose, wordCase(":80"),
+			_eof,
+		},
+		Off: tokenSeq{
+			wordCase("10.0.0.256"),
+			_ws, wordCase("10.0.0.0/33"),
+			_ws, wordCase("0.0.0.0:65536"),
+			_ws, wordCase("1.2.3"),
+			_ws, wordCase("a:b"),
+			_ws, quoteCase("10.0.0.1"),
+			_ws, _bracketopen, wordCase("::1"), _bracketclose, wordCase(":80"),
+			_eof,
+		},
+	}.Test)
+
+	t.Run("BracketWords", flagTest{
+		Flags: LexNetAddrs | LexBracketWords,
+		Seq:   `listen [::1]:80 [::]:443`,
+		On: tokenSeq{
+			wordCase("listen"), _ws, addrCase("[::1]:80"), _ws, addrCase("[::]:443"), _eof,
+		},
+		Off: tokenSeq{
+			wordCase("listen"),
+			_ws, _bracketopen, wordCase("::1"), _bracketclose, wordCase(":80"),
+			_ws, _bracketopen, wordCase("::"), _bracketclose, wordCase(":443"),
+			_eof,
+		},
+	}.Test)
+}
+
 func TestLexNoBooleansFlag(t *testing.T) {
 	flagTest{
 		Flags: LexNoBools,
@@ -848,6 +1076,107 @@ func TestString(t *testing.T) {
 		;`)
 }
 
+func TestHeredoc(t *testing.T) {
+	heredoc := func(raw, value string) tokenCase {
+		return tokenCase{Token: Token{Kind: THeredoc, Raw: []byte(raw), Value: value}}
+	}
+
+	t.Run("Locations", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{
+			wordCase("query"),
+			_ws,
+			{Token: Token{
+				Kind:  THeredoc,
+				Raw:   []byte("<<SQL\n\t\tSELECT *\n\t\t  FROM t;\n\n\t\tSQL"),
+				Start: Location{Name: "test.codf", Offset: 6, Line: 1, Column: 7},
+				End:   Location{Name: "test.codf", Offset: 41, Line: 5, Column: 6},
+				Value: "SELECT *\n  FROM t;\n",
+			}},
+			{Token: Token{
+				Kind:  TSemicolon,
+				Start: Location{Name: "test.codf", Offset: 41, Line: 5, Column: 6},
+				End:   Location{Name: "test.codf", Offset: 42, Line: 5, Column: 7},
+			}},
+			_eof,
+		}.Run(t, "query <<SQL\n\t\tSELECT *\n\t\t  FROM t;\n\n\t\tSQL;")
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{
+			heredoc("<<EOF\nEOF", ""),
+			_ws, heredoc("<<EOF\n\nEOF", ""),
+			_ws, heredoc("<<A_1\n  a\n  A_1", "a"),
+			_ws, heredoc("<<EOF\n    a\n\n \n      b\n    EOF", "a\n\n\n  b"),
+			_ws, heredoc("<<EOF\n  EOFX\n  x EOF\n  EOF", "EOFX\nx EOF"),
+			_ws, heredoc("<<EOF\r\n  a\r\n  b\r\n  EOF", "a\nb"),
+			_ws, heredoc("<<EOF\n`\"\\n\"`\nEOF", "`\"\\n\"`"),
+			_bracketopen, heredoc("<<X\n  x\n  X", "x"), _bracketclose,
+			_ws, heredoc("<<EOF \t\n  a\n  EOF", "a"),
+			_ws, heredoc("<<EOF \r\n  a\r\n  EOF", "a"),
+			_eof,
+		}.Run(t, "<<EOF\nEOF <<EOF\n\nEOF <<A_1\n  a\n  A_1 "+
+			"<<EOF\n    a\n\n \n      b\n    EOF <<EOF\n  EOFX\n  x EOF\n  EOF "+
+			"<<EOF\r\n  a\r\n  b\r\n  EOF <<EOF\n`\"\\n\"`\nEOF[<<X\n  x\n  X] "+
+			"<<EOF \t\n  a\n  EOF <<EOF \r\n  a\r\n  EOF")
+	})
+
+	t.Run("Words", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{
+			wordCase("<"),
+			_ws, wordCase("<<"),
+			_ws, wordCase("<="),
+			_ws, wordCase("<<EOF"), _semicolon,
+			_ws, wordCase("<<1"),
+			_ws, wordCase("<<EOF"),
+			_ws, wordCase("x"),
+			_ws, wordCase("<<-EOF"),
+			_ws, wordCase("<<EOF"),
+			_ws, _semicolon,
+			_ws,
+			_eof,
+		}.Run(t, "< << <= <<EOF; <<1\n<<EOF x <<-EOF <<EOF \t;\n")
+	})
+
+	t.Run("WordLocations", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{
+			{Token: Token{
+				Kind:  TWord,
+				Raw:   []byte("<<EOF"),
+				Start: Location{Name: "test.codf", Offset: 0, Line: 1, Column: 1},
+				End:   Location{Name: "test.codf", Offset: 5, Line: 1, Column: 6},
+				Value: "<<EOF",
+			}},
+			{Token: Token{
+				Kind:  TWhitespace,
+				Raw:   []byte(" \t"),
+				Start: Location{Name: "test.codf", Offset: 5, Line: 1, Column: 6},
+				End:   Location{Name: "test.codf", Offset: 7, Line: 1, Column: 8},
+			}},
+			{Token: Token{
+				Kind:  TWord,
+				Raw:   []byte("x"),
+				Start: Location{Name: "test.codf", Offset: 7, Line: 1, Column: 8},
+				End:   Location{Name: "test.codf", Offset: 8, Line: 1, Column: 9},
+				Value: "x",
+			}},
+			_eof,
+		}.Run(t, "<<EOF \tx")
+	})
+
+	t.Run("NoHeredocs", flagTest{
+		Flags: LexNoHeredocs,
+		Seq:   "<<EOF\n  a\n  EOF",
+		On: tokenSeq{
+			wordCase("<<EOF"), _ws, wordCase("a"), _ws, wordCase("EOF"), _eof,
+		},
+		Off: tokenSeq{heredoc("<<EOF\n  a\n  EOF", "a"), _eof},
+	}.Test)
+}
+
 func TestBaseInteger(t *testing.T) {
 	defer setlogf(t)()
 	num := big.NewInt(-12345)
@@ -937,6 +1266,14 @@ func TestInvalidStrings(t *testing.T) {
 		{Name: "Raw/EOF", Input: "stmt ```"},
 		{Name: "Raw/EOF", Input: "stmt ```after"},
 		{Name: "Raw/BadUTF8", Input: "stmt `\xff"},
+		// Heredocs
+		{Name: "Heredoc/EOF", Input: "stmt <<EOF\n"},
+		{Name: "Heredoc/EOF", Input: "stmt <<EOF\nEOFX"},
+		{Name: "Heredoc/EOF", Input: "stmt <<EOF\n  a EOF"},
+		{Name: "Heredoc/Indent", Input: "stmt <<EOF\n a\n  EOF"},
+		{Name: "Heredoc/Indent", Input: "stmt <<EOF\n\ta\n  EOF"},
+		{Name: "Heredoc/CR", Input: "stmt <<EOF\r;"},
+		{Name: "Heredoc/BadUTF8", Input: "stmt <<EOF\n\xff"},
 	}
 
 	for i, c := range cases {
@@ -1034,8 +1371,10 @@ func TestIntegers(t *testing.T) {
 		`4#`, `4#;`,
 		`4x`, `4x;`,
 		`4X`, `4X;`,
-		`4b`, `4b;`,
-		`4B`, `4B;`,
+		`4bq`, `4bq;`,
+		`4Kx`, `4Kx;`,
+		`4mbps`, `4mbps;`,
+		`1h5mb`,
 		`06z`,
 		`0xfg`,
 		`4#15`,
@@ -1195,6 +1534,130 @@ func TestFloats(t *testing.T) {
 	}
 }
 
+func TestByteSizes(t *testing.T) {
+	defer setlogf(t)()
+
+	t.Run("Valid", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{
+			bytesCase(1, "1b"),
+			_ws, bytesCase(0, "0B"),
+			_ws, bytesCase(0, "0kb"),
+			_ws, bytesCase(64e3, "64kb"),
+			_ws, bytesCase(64<<10, "64KiB"),
+			_ws, bytesCase(64e6, "64mb"),
+			_ws, bytesCase(64<<20, "64mib"),
+			_ws, bytesCase(64e6, "64MB"),
+			_ws, bytesCase(2e9, "2gb"),
+			_ws, bytesCase(2<<30, "2GiB"),
+			_ws, bytesCase(3e12, "3tb"),
+			_ws, bytesCase(3<<40, "3tib"),
+			_ws, bytesCase(1500, "1.5kb"),
+			_ws, bytesCase(1536, "1.5kib"),
+			_ws, bytesCase(250e6, "0.25gb"),
+			_ws, bytesCase(-1024, "-1kib"),
+			_ws, bytesCase(1024, "+1kib"),
+			_semicolon,
+			_eof,
+		}.Run(t, `1b 0B 0kb 64kb 64KiB 64mb 64mib 64MB 2gb 2GiB 3tb 3tib 1.5kb 1.5kib 0.25gb -1kib +1kib;`)
+	})
+
+	t.Run("Durations", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{
+			durCase("64m"), _ws, bytesCase(64e6, "64mb"), _ws, durCase("64ms"), _ws, wordCase("1m5mb"),
+			_eof,
+		}.Run(t, `64m 64mb 64ms 1m5mb`)
+	})
+
+	t.Run("Words", func(t *testing.T) {
+		for _, c := range []string{`64k`, `64M`, `64kbit`, `64mbps`, `64kb/s`, `1.kb`, `1e3kb`, `0x10kb`} {
+			t.Run(c, func(t *testing.T) {
+				defer setlogf(t)()
+				tokenSeq{wordCase(c), _eof}.Run(t, c)
+			})
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, c := range []string{`0.5b`, `1.0001kb`, "64kb\x00"} {
+			t.Run(c, func(t *testing.T) {
+				defer setlogf(t)()
+				tokenSeq{_error}.Run(t, c)
+			})
+		}
+	})
+
+	t.Run("NoFloats", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{bytesCase(1500, "1.5kb"), _ws, wordCase("1.5"), _eof}.RunFlags(t, LexNoFloats, `1.5kb 1.5`)
+	})
+
+	t.Run("NoDurations", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{bytesCase(64e6, "64mb"), _ws, wordCase("64m"), _eof}.RunFlags(t, LexNoDurations, `64mb 64m`)
+	})
+}
+
+func TestTimestamps(t *testing.T) {
+	defer setlogf(t)()
+
+	t.Run("Valid", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{
+			timeCase("2026-10-16"),
+			_ws, timeCase("1999-12-31"),
+			_ws, timeCase("2026-10-16T04:00:00Z"),
+			_ws, timeCase("2026-10-16T04:00:00.123456789Z"),
+			_ws, timeCase("2026-10-16T04:00:00+02:00"),
+			_ws, timeCase("2026-10-16T04:00:00.5-07:30"),
+			_semicolon,
+			_eof,
+		}.Run(t, `2026-10-16 1999-12-31 2026-10-16T04:00:00Z 2026-10-16T04:00:00.123456789Z
+			2026-10-16T04:00:00+02:00 2026-10-16T04:00:00.5-07:30;`)
+	})
+
+	t.Run("Values", func(t *testing.T) {
+		defer setlogf(t)()
+		doc := mustParse(t, `at 2026-10-16 2026-10-16T04:00:00+02:00;`)
+		params := doc.Children[0].(*Statement).Params
+		if got, want := params[0].Value(), time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC); got != want {
+			t.Errorf("date = %v; want %v", got, want)
+		}
+		if got, want := params[1].Value().(time.Time), time.Date(2026, 10, 16, 2, 0, 0, 0, time.UTC); !got.Equal(want) {
+			t.Errorf("timestamp = %v; want %v", got, want)
+		}
+	})
+
+	t.Run("Words", func(t *testing.T) {
+		for _, c := range []string{
+			`2026-10`,
+			`2026-1-16`,
+			`2026-10-16T04:00`,
+			`2026-10-16T04:00:00`,
+			`2026-10-16t04:00:00z`,
+			`2026-10-16_backup`,
+			`20261-10-16`,
+			`-2026-10-16`,
+			`1234-5678`,
+		} {
+			t.Run(c, func(t *testing.T) {
+				defer setlogf(t)()
+				tokenSeq{wordCase(c), _eof}.Run(t, c)
+			})
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, c := range []string{`2026-13-01`, `2026-02-30`, `2026-10-16T25:00:00Z`, "2026-10-16\x00"} {
+			t.Run(c, func(t *testing.T) {
+				defer setlogf(t)()
+				tokenSeq{_error}.Run(t, c)
+			})
+		}
+	})
+}
+
 func TestDurations(t *testing.T) {
 	defer setlogf(t)()
 
@@ -1297,6 +1760,60 @@ func TestDurations(t *testing.T) {
 	}
 }
 
+func TestCalendarDurations(t *testing.T) {
+	defer setlogf(t)()
+
+	day := 24 * time.Hour
+	dur := func(d time.Duration, text string) tokenCase {
+		return tokenCase{Token: Token{Kind: TDuration, Raw: []byte(text), Value: d}}
+	}
+	period := func(p Period, text string) tokenCase {
+		return tokenCase{Token: Token{Kind: TPeriod, Raw: []byte(text), Value: p}}
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{
+			dur(30*day, "30d"),
+			_ws, dur(14*day, "2w"),
+			_ws, dur(-day, "-1d"),
+			_ws, dur(day/2, "0.5d"),
+			_ws, dur(9*day+36*time.Hour+30*time.Minute, "1w2d36h30m"),
+			_ws, dur(day+500*time.Millisecond, "1d500ms"),
+			_ws, period(Period{Years: 1, Months: 2}, "P1Y2M"),
+			_ws, period(Period{Days: 14}, "P2W"),
+			_ws, period(Period{Days: 3, Time: 4 * time.Hour}, "P3DT4H"),
+			_ws, period(Period{Time: 90*time.Minute + 1500*time.Millisecond}, "PT1H30M1.5S"),
+			_ws, period(Period{Time: 36 * time.Hour}, "PT36H"),
+			_semicolon,
+			_eof,
+		}.Run(t, `30d 2w -1d 0.5d 1w2d36h30m 1d500ms P1Y2M P2W P3DT4H PT1H30M1.5S PT36H;`)
+	})
+
+	t.Run("Words", func(t *testing.T) {
+		for _, c := range []string{`1d0`, `P`, `PT`, `PUT`, `P1`, `P1.5D`, `P1D2Y`, `PT1D`, `P1Dx`, `Pw`} {
+			t.Run(c, func(t *testing.T) {
+				defer setlogf(t)()
+				tokenSeq{wordCase(c), _eof}.Run(t, c)
+			})
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, c := range []string{`1dw`, `107000d`, `16000w`, `PT9999999999H`, `P99999999999999999999D`} {
+			t.Run(c, func(t *testing.T) {
+				defer setlogf(t)()
+				tokenSeq{_error}.Run(t, c)
+			})
+		}
+	})
+
+	t.Run("NoDurations", func(t *testing.T) {
+		defer setlogf(t)()
+		tokenSeq{wordCase("30d"), _ws, wordCase("P1D"), _eof}.RunFlags(t, LexNoDurations, `30d P1D`)
+	})
+}
+
 func TestReaderWrapping(t *testing.T) {
 	const path = "_test/simple-file"
 	const noname = "no-name"
@@ -0,0 +1,166 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// LexErrorCode identifies the kind of a LexError. Codes are stable and may be used to match errors
+// programmatically, unlike error messages.
+type LexErrorCode string
+
+const (
+	// LexErrRead is the code of errors returned by the Lexer's underlying reader.
+	LexErrRead LexErrorCode = "read"
+	// LexErrInvalidUTF8 is the code of errors for input that is not valid UTF-8.
+	LexErrInvalidUTF8 LexErrorCode = "invalid-utf8"
+	// LexErrUnexpectedRune is the code of errors for a rune that is not valid where it occurs.
+	LexErrUnexpectedRune LexErrorCode = "unexpected-rune"
+	// LexErrUnexpectedEOF is the code of errors for EOF occurring in the middle of a token.
+	LexErrUnexpectedEOF LexErrorCode = "unexpected-eof"
+	// LexErrInvalidEscape is the code of errors for an invalid escape sequence in a string.
+	LexErrInvalidEscape LexErrorCode = "invalid-escape"
+	// LexErrMalformedNumber is the code of errors for a number or duration that cannot be
+	// converted to its value.
+	LexErrMalformedNumber LexErrorCode = "malformed-number"
+	// LexErrMalformedTime is the code of errors for a date or timestamp that cannot be converted
+	// to its value, such as one with a day out of range.
+	LexErrMalformedTime LexErrorCode = "malformed-time"
+	// LexErrMalformedPeriod is the code of errors for a period that cannot be converted to its
+	// value.
+	LexErrMalformedPeriod LexErrorCode = "malformed-period"
+	// LexErrMalformedByteSize is the code of errors for a byte size that cannot be converted to
+	// its value, such as one that is not a whole number of bytes.
+	LexErrMalformedByteSize LexErrorCode = "malformed-byte-size"
+	// LexErrInvalidRegexp is the code of errors for a regexp that cannot be compiled.
+	LexErrInvalidRegexp LexErrorCode = "invalid-regexp"
+	// LexErrHeredocIndent is the code of errors for a line of a heredoc that is indented less
+	// than its closing delimiter.
+	LexErrHeredocIndent LexErrorCode = "heredoc-indent"
+)
+
+// LexError is returned by a Lexer when it cannot read a token.
+type LexError struct {
+	// Start and End are the locations of the start and end of the text that caused the error.
+	// For an unexpected rune, this is the rune itself. For a token that cannot be converted to
+	// its value, such as a malformed number, this is the whole token. For EOF, Start and End are
+	// both the location of EOF.
+	Start, End Location
+
+	// Code identifies the kind of error.
+	Code LexErrorCode
+
+	// Rune is the rune that caused the error. It is -1 if the error was caused by EOF or was not
+	// caused by a single rune.
+	Rune rune
+
+	// Msg is a message describing the error.
+	Msg string
+
+	// Err is the underlying error, if any. For LexErrUnexpectedEOF, it is ErrUnexpectedEOF.
+	Err error
+}
+
+func (e *LexError) Error() string {
+	return "[" + e.Start.String() + "] " + e.Msg
+}
+
+func (e *LexError) Unwrap() error {
+	return e.Err
+}
+
+// unexpected returns a LexError for r, the last rune read by the lexer, where r is not valid. If r
+// is EOF, the error has the code LexErrUnexpectedEOF. The message is formatted from format and
+// args and describes what was expected instead of r.
+func (l *Lexer) unexpected(r rune, format string, args ...any) *LexError {
+	expected := fmt.Sprintf(format, args...)
+	if r == eof {
+		return &LexError{
+			Start: l.pos,
+			End:   l.pos,
+			Code:  LexErrUnexpectedEOF,
+			Rune:  eof,
+			Msg:   "unexpected EOF: " + expected,
+			Err:   ErrUnexpectedEOF,
+		}
+	}
+	return &LexError{
+		Start: l.lastPos,
+		End:   l.pos,
+		Code:  LexErrUnexpectedRune,
+		Rune:  r,
+		Msg:   fmt.Sprintf("unexpected character %q: %s", r, expected),
+	}
+}
+
+// invalidUTF8 returns a LexError for the last rune read by the lexer, which is not valid UTF-8.
+func (l *Lexer) invalidUTF8() *LexError {
+	return &LexError{
+		Start: l.lastPos,
+		End:   l.pos,
+		Code:  LexErrInvalidUTF8,
+		Rune:  utf8.RuneError,
+		Msg:   "invalid UTF-8",
+	}
+}
+
+// readError returns a LexError wrapping err, an error returned by the lexer's reader.
+func (l *Lexer) readError(err error) *LexError {
+	return &LexError{
+		Start: l.pos,
+		End:   l.pos,
+		Code:  LexErrRead,
+		Rune:  eof,
+		Msg:   err.Error(),
+		Err:   err,
+	}
+}
+
+// heredocIndentError returns a LexError for the line of the heredoc tok that begins at the byte
+// offset off in its Raw text and is indented less than the heredoc's closing delimiter. The error
+// spans the line's indentation.
+func heredocIndentError(tok Token, off int) *LexError {
+	start := tok.Start
+	start.Line += bytes.Count(tok.Raw[:off], []byte("\n"))
+	start.Offset += off
+	start.Column = 1
+
+	end := start
+	for _, r := range string(tok.Raw[off:]) {
+		if r != ' ' && r != '\t' {
+			break
+		}
+		end = end.add(r, utf8.RuneLen(r))
+	}
+	return &LexError{
+		Start: start,
+		End:   end,
+		Code:  LexErrHeredocIndent,
+		Rune:  eof,
+		Msg:   "heredoc line is indented less than its closing delimiter",
+	}
+}
+
+// valueError returns a LexError for tok, whose text cannot be converted to a value.
+func valueError(tok Token, err error) *LexError {
+	code := LexErrMalformedNumber
+	switch tok.Kind {
+	case TDate, TTimestamp:
+		code = LexErrMalformedTime
+	case TPeriod:
+		code = LexErrMalformedPeriod
+	case TByteSize:
+		code = LexErrMalformedByteSize
+	case TRegexp:
+		code = LexErrInvalidRegexp
+	}
+	return &LexError{
+		Start: tok.Start,
+		End:   tok.End,
+		Code:  code,
+		Rune:  eof,
+		Msg:   err.Error(),
+		Err:   err,
+	}
+}
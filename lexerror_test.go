@@ -0,0 +1,99 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// lexError reads tokens from in until the lexer returns an error.
+func lexError(t *testing.T, in string) error {
+	lex := NewLexer(strings.NewReader(in))
+	for {
+		tok, err := lex.ReadToken()
+		if err != nil {
+			return err
+		}
+		if tok.Kind == TEOF {
+			t.Fatalf("ReadToken(%q) reached EOF; want error", in)
+		}
+	}
+}
+
+func TestLexError(t *testing.T) {
+	cases := []struct {
+		in         string
+		code       LexErrorCode
+		start, end string
+		r          rune
+		msg        string
+	}{
+		{"a \x00", LexErrUnexpectedRune, "1:3:2", "1:4:3", 0, `[1:3:2] unexpected character '\x00': expected a token`},
+		{"a\n  0x;", LexErrUnexpectedRune, "2:5:6", "2:6:7", ';', "[2:5:6] unexpected character ';': expected hex digit"},
+		{"0x", LexErrUnexpectedEOF, "1:3:2", "1:3:2", -1, "[1:3:2] unexpected EOF: expected hex digit"},
+		{"07\x00", LexErrUnexpectedRune, "1:3:2", "1:4:3", 0, `[1:3:2] unexpected character '\x00': expected octal digit or separator`},
+		{"4#\x00", LexErrUnexpectedRune, "1:3:2", "1:4:3", 0, `[1:3:2] unexpected character '\x00': expected base-4 digit`},
+		{"#\x00", LexErrUnexpectedRune, "1:2:1", "1:3:2", 0, `[1:2:1] unexpected character '\x00': expected { or / after #`},
+		{`"abc`, LexErrUnexpectedEOF, "1:5:4", "1:5:4", -1, "[1:5:4] unexpected EOF: expected close of string"},
+		{"`abc", LexErrUnexpectedEOF, "1:5:4", "1:5:4", -1, "[1:5:4] unexpected EOF: expected ending backquote"},
+		{`"a\qb"`, LexErrInvalidEscape, "1:4:3", "1:5:4", 'q', `[1:4:3] invalid escape character 'q'`},
+		{`"\xZZ"`, LexErrUnexpectedRune, "1:4:3", "1:5:4", 'Z', "[1:4:3] unexpected character 'Z': expected hex digit"},
+		{`"\18"`, LexErrUnexpectedRune, "1:4:3", "1:5:4", '8', "[1:4:3] unexpected character '8': expected octal digit"},
+		{"#/a", LexErrUnexpectedEOF, "1:4:3", "1:4:3", -1, "[1:4:3] unexpected EOF: expected end of regexp"},
+		{"a #/(/", LexErrInvalidRegexp, "1:3:2", "1:7:6", -1, "[1:3:2] error parsing regexp: missing closing ): `(`"},
+		{"x 9999999999h", LexErrMalformedNumber, "1:3:2", "1:14:13", -1, `[1:3:2] malformed duration "9999999999h": time: invalid duration "9999999999h"`},
+		{"x 9223372036854775807d", LexErrMalformedNumber, "1:3:2", "1:23:22", -1, `[1:3:2] malformed duration "9223372036854775807d": time: invalid duration "9223372036854775807d"`},
+		{"x 1d9999999999h", LexErrMalformedNumber, "1:3:2", "1:16:15", -1, `[1:3:2] malformed duration "1d9999999999h": time: invalid duration "1d9999999999h"`},
+		{"x 2023-02-30", LexErrMalformedTime, "1:3:2", "1:13:12", -1, `[1:3:2] malformed date "2023-02-30": parsing time "2023-02-30": day out of range`},
+		{"x 2023-02-28T25:00:00Z", LexErrMalformedTime, "1:3:2", "1:23:22", -1, `[1:3:2] malformed timestamp "2023-02-28T25:00:00Z": parsing time "2023-02-28T25:00:00Z": hour out of range`},
+		{"x P99999999999999999999Y", LexErrMalformedPeriod, "1:3:2", "1:25:24", -1, `[1:3:2] malformed period "P99999999999999999999Y": component out of range`},
+		{"x 1.5B", LexErrMalformedByteSize, "1:3:2", "1:7:6", -1, `[1:3:2] byte size "1.5B" is not a whole number of bytes`},
+		{"<<EOF\n  a\n", LexErrUnexpectedEOF, "3:1:10", "3:1:10", -1, "[3:1:10] unexpected EOF: expected heredoc delimiter EOF"},
+		{"<<EOF\n   a\n b\n  EOF", LexErrHeredocIndent, "3:1:11", "3:2:12", -1, "[3:1:11] heredoc line is indented less than its closing delimiter"},
+		{"<<EOF\rx", LexErrUnexpectedRune, "1:7:6", "1:8:7", 'x', "[1:7:6] unexpected character 'x': expected newline after heredoc delimiter"},
+		{"\xff", LexErrInvalidUTF8, "1:1:0", "1:2:1", utf8.RuneError, "[1:1:0] invalid UTF-8"},
+	}
+
+	for _, c := range cases {
+		err := lexError(t, c.in)
+		var le *LexError
+		if !errors.As(err, &le) {
+			t.Errorf("ReadToken(%q) error = %v; want *LexError", c.in, err)
+			continue
+		}
+		if le.Code != c.code {
+			t.Errorf("ReadToken(%q) Code = %q; want %q", c.in, le.Code, c.code)
+		}
+		if got := le.Start.String(); got != c.start {
+			t.Errorf("ReadToken(%q) Start = %s; want %s", c.in, got, c.start)
+		}
+		if got := le.End.String(); got != c.end {
+			t.Errorf("ReadToken(%q) End = %s; want %s", c.in, got, c.end)
+		}
+		if le.Rune != c.r {
+			t.Errorf("ReadToken(%q) Rune = %q; want %q", c.in, le.Rune, c.r)
+		}
+		if err.Error() != c.msg {
+			t.Errorf("ReadToken(%q) error = %q; want %q", c.in, err, c.msg)
+		}
+		if wantEOF := c.code == LexErrUnexpectedEOF; errors.Is(err, ErrUnexpectedEOF) != wantEOF {
+			t.Errorf("errors.Is(%v, ErrUnexpectedEOF) = %t; want %t", err, !wantEOF, wantEOF)
+		}
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestLexErrorRead(t *testing.T) {
+	_, err := NewLexer(failingReader{}).ReadToken()
+	var le *LexError
+	if !errors.As(err, &le) || le.Code != LexErrRead || !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("ReadToken() error = %v; want LexError wrapping io.ErrClosedPipe", err)
+	}
+}
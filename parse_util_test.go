@@ -32,6 +32,15 @@ func mkrat(a, b int64) *Literal {
 	}
 }
 
+func mkbytes(n int64) *Literal {
+	return &Literal{
+		Tok: Token{
+			Kind:  TByteSize,
+			Value: big.NewInt(n),
+		},
+	}
+}
+
 func mkregex(str string) *Literal {
 	return &Literal{
 		Tok: Token{
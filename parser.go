@@ -10,6 +10,36 @@ var ErrTooManyExprs = errors.New("too many expresssions")
 
 type tokenConsumer func(Token) (tokenConsumer, error)
 
+// ParserFlag is a bitset representing a combination of zero or more Parse flags, such as
+// ParseComments. These Parse flags affect the Parser's output.
+type ParserFlag uint64
+
+const (
+	// ParseDefaultFlags is the empty flag set (the default).
+	ParseDefaultFlags ParserFlag = 0
+)
+
+const (
+	// ParseComments keeps comments in the parsed document by attaching them to nodes as leading,
+	// trailing, and dangling comments. See Comments for how comments are attached.
+	ParseComments ParserFlag = 1 << iota
+
+	// ParseRecover makes Parse continue after a syntax error instead of stopping at it. After an
+	// error, the parser discards tokens up to the next ';' or '}' that ends the statement or
+	// section in which the error occurred and resumes parsing there, so that the Document holds
+	// everything that could be parsed. Parse returns all errors encountered as an ErrorList.
+	ParseRecover
+
+	// ParseQuotedNames allows quoted strings as statement and section names, such as the entries
+	// of an nginx map block ('' close; "~*bot" 1;). The name of such a statement or section is the
+	// string's value.
+	ParseQuotedNames
+)
+
+func (f ParserFlag) none(bits ParserFlag) bool {
+	return f&bits == 0
+}
+
 // TokenReader is anything capable of reading a token and returning either it or an error.
 type TokenReader interface {
 	ReadToken() (Token, error)
@@ -20,9 +50,19 @@ type TokenReader interface {
 // The Document produced by the Parser is kept for the duration of the parser's lifetime, so it is
 // possible to read multiple TokenReaders into a Parser and produce a combined document.
 type Parser struct {
+	// Flags is a set of Parse flags that can be used to change parser behavior.
+	Flags ParserFlag
+
 	doc  *Document
 	next tokenConsumer
 
+	// pending holds comments that have not yet been attached to a node, when parsing with
+	// ParseComments.
+	pending []*Comment
+	// prev is the last statement or section ended in the current context, if a comment may still
+	// trail it.
+	prev commented
+
 	lastToken Token
 	lastErr   error
 
@@ -30,6 +70,10 @@ type Parser struct {
 	// subsequent calls to Parse will return this.
 	parseErr error
 
+	// errs holds the errors recovered from by the current call to Parse, when parsing with
+	// ParseRecover.
+	errs ErrorList
+
 	ctx  []parseNode
 	_ctx [6]parseNode
 }
@@ -40,8 +84,9 @@ func NewParser() *Parser {
 		Children: []Node{},
 	}
 	p := &Parser{
-		doc:  doc,
-		next: nil,
+		Flags: ParseDefaultFlags,
+		doc:   doc,
+		next:  nil,
 	}
 
 	p.ctx = p._ctx[:0]
@@ -59,11 +104,20 @@ func (p *Parser) nextToken(tr TokenReader) (tok Token, err error) {
 //
 // If an error occurs during parsing, Parse will return that error for all subsequent calls to
 // Parse, as the parser has been left in a middle-of-parsing state.
+//
+// If the ParseRecover flag is set, Parse instead records syntax errors and continues parsing, and
+// returns an ErrorList of the errors once it reaches EOF. Errors returned by the TokenReader are
+// also recorded, but Parse stops if the TokenReader returns two errors in a row, since it may be
+// unable to make progress. In that case, the ErrorList is returned by subsequent calls to Parse.
 func (p *Parser) Parse(tr TokenReader) (err error) {
 	if p.parseErr != nil {
 		return p.parseErr
 	}
 
+	if p.Flags&ParseRecover != 0 {
+		return p.parseRecover(tr)
+	}
+
 	defer func() {
 		if err != nil {
 			p.parseErr = err
@@ -107,7 +161,8 @@ func (p *Parser) ParseExpr(tr TokenReader) (ExprNode, error) {
 	exp := exprParser{}
 	p.ctx = []parseNode{&exp}
 	p.parseErr = nil
-	p.next = skipWhitespace(p.parseStatement)
+	p.Flags &^= ParseRecover
+	p.next = p.skipWhitespace(p.parseStatement)
 	if err := p.Parse(tr); err != nil {
 		return nil, err
 	}
@@ -134,9 +189,42 @@ func (p *Parser) Document() *Document {
 	return p.doc
 }
 
-// TODO: Add ParseInContext() method to begin parsing while inside of a specific section or
-// document. Useful for handling, for example, `include file.conf;` inside of a config file as
-// a part of walking an AST.
+// ParseInContext consumes tokens from a TokenReader and adds the statements and sections parsed
+// from them to the end of parent, which must be a *Document or *Section. This is useful for
+// handling, for example, `include file.conf;` inside of a config file as a part of walking an AST.
+//
+// Parent is only modified if all of the tokens are parsed successfully. As with ParseExpr, errors
+// returned by ParseInContext have no effect on subsequent calls to Parse, and the Parser's own
+// Document is not modified.
+func (p *Parser) ParseInContext(tr TokenReader, parent ParentNode) error {
+	var target parentNode
+	switch parent := parent.(type) {
+	case *Document:
+		target = parent
+	case *Section:
+		target = parent
+	default:
+		return fmt.Errorf("cannot parse into %T", parent)
+	}
+
+	defer p.snap()()
+	cp := contextParser{}
+	p.ctx = []parseNode{&cp}
+	p.parseErr = nil
+	p.pending, p.prev = nil, nil
+	p.next = p.beginSegment
+	if err := p.Parse(tr); err != nil {
+		return err
+	}
+
+	for _, child := range cp.children {
+		target.addChild(child)
+	}
+	if c := target.(commented).comments(); len(cp.dangling) > 0 {
+		c.Dangling = append(c.Dangling, cp.dangling...)
+	}
+	return nil
+}
 
 // pushContext pushes a new node-parsing context onto the parser stack.
 func (p *Parser) pushContext(node parseNode) {
@@ -179,21 +267,26 @@ func (p *Parser) closeError(tok Token) error {
 		return nil
 	case *Statement:
 		return unexpected(tok, "expected end of statement %q beginning at %v",
-			ctx.Name(), ctx.Token().Start)
+			ctx.Name(), ctx.Token().Start).
+			note(ctx.Token(), "statement %q begins here", ctx.Name())
 	case *Section:
 		return unexpected(tok, "expected end of section %q beginning at %v",
-			ctx.Name(), ctx.Token().Start)
+			ctx.Name(), ctx.Token().Start).
+			note(ctx.Token(), "section %q begins here", ctx.Name())
 	case *Array:
 		return unexpected(tok, "expected end of array beginning at %v",
-			ctx.Token().Start)
+			ctx.Token().Start).
+			note(ctx.Token(), "array begins here")
 	case *mapBuilder:
 		if ctx.k != nil {
 			return unexpected(tok, "expected value for key %q at %v",
-				ctx.k.Token().Value, ctx.k.Token().Start)
+				ctx.k.Token().Value, ctx.k.Token().Start).
+				note(ctx.k.Token(), "key %q is here", ctx.k.Token().Value)
 		}
 		return unexpected(tok, "expected end of map beginning at %v",
-			ctx.m.Token().Start)
-	case *Document:
+			ctx.m.Token().Start).
+			note(ctx.m.Token(), "map begins here")
+	case *Document, *contextParser:
 		if tok.Kind != TEOF {
 			return unexpected(tok, "expected statement, section, or EOF")
 		}
@@ -204,31 +297,52 @@ func (p *Parser) closeError(tok Token) error {
 
 func (p *Parser) beginSegment(tok Token) (tokenConsumer, error) {
 	switch tok.Kind {
-	case TSemicolon, TWhitespace, TComment:
+	case TSemicolon, TWhitespace:
+		return p.beginSegment, nil
+	case TComment:
+		p.addComment(tok)
 		return p.beginSegment, nil
 	case TCurlClose:
 		if sect, ok := p.context().(*Section); ok {
 			sect.EndTok = tok
 			p.popContext()
+			sect.Comments.Dangling = p.takeComments(sect.Comments.Dangling)
 			p.context().(parentNode).addChild(sect)
+			p.prev = sect
 			return p.beginSegment, nil
 		}
 		return nil, p.closeError(tok)
 	case TEOF:
+		switch ctx := p.context().(type) {
+		case *Document:
+			ctx.Comments.Dangling = p.takeComments(ctx.Comments.Dangling)
+		case *contextParser:
+			ctx.dangling = p.takeComments(ctx.dangling)
+		}
 		return nil, p.closeError(tok)
+	case TString, TRawString:
+		if p.Flags.none(ParseQuotedNames) {
+			break
+		}
+		fallthrough
 	case TWord:
 		// Start statement
 		stmt := &Statement{NameTok: &Literal{Tok: tok}}
+		stmt.Comments.Leading = p.takeComments(nil)
+		p.prev = nil
 		p.pushContext(stmt)
-		return skipWhitespace(p.parseStatement), nil
+		return p.skipWhitespace(p.parseStatement), nil
 	}
 	return nil, unexpected(tok, "expected statement or section name")
 }
 
-func skipWhitespace(next tokenConsumer) (consumer tokenConsumer) {
+func (p *Parser) skipWhitespace(next tokenConsumer) (consumer tokenConsumer) {
 	consumer = func(tok Token) (tokenConsumer, error) {
 		switch tok.Kind {
-		case TWhitespace, TComment:
+		case TWhitespace:
+			return consumer, nil
+		case TComment:
+			p.addComment(tok)
 			return consumer, nil
 		}
 		return next(tok)
@@ -236,6 +350,50 @@ func skipWhitespace(next tokenConsumer) (consumer tokenConsumer) {
 	return consumer
 }
 
+// addComment attaches a comment token to the node being parsed, or holds it as a leading comment
+// for the next statement or section. Comments are discarded unless ParseComments is set.
+func (p *Parser) addComment(tok Token) {
+	if p.Flags.none(ParseComments) {
+		return
+	}
+
+	c := &Comment{Tok: tok}
+	switch ctx := p.context().(type) {
+	case *Statement:
+		ctx.Comments.Leading = append(ctx.Comments.Leading, c)
+	case *Array:
+		ctx.Comments.Dangling = append(ctx.Comments.Dangling, c)
+	case *mapBuilder:
+		ctx.m.Comments.Dangling = append(ctx.m.Comments.Dangling, c)
+	case *Section, *Document, *contextParser:
+		if p.prev != nil && len(p.pending) == 0 && p.prev.comments().Trailing == nil &&
+			endLine(p.prev) == tok.Start.Line {
+			p.prev.comments().Trailing = c
+			return
+		}
+		p.pending = append(p.pending, c)
+	}
+}
+
+// takeComments returns comments with any pending comments appended to it and clears the pending
+// comments.
+func (p *Parser) takeComments(comments []*Comment) []*Comment {
+	comments = append(comments, p.pending...)
+	p.pending = nil
+	return comments
+}
+
+// endLine returns the line of the token ending the statement or section node.
+func endLine(node commented) int {
+	switch node := node.(type) {
+	case *Statement:
+		return node.EndTok.Start.Line
+	case *Section:
+		return node.EndTok.Start.Line
+	}
+	return -1
+}
+
 func (p *Parser) parseStatementSentinel(tok Token) (tokenConsumer, error) {
 	switch tok.Kind {
 	case TEOF:
@@ -246,6 +404,7 @@ func (p *Parser) parseStatementSentinel(tok Token) (tokenConsumer, error) {
 			p.popContext()
 			stmt.EndTok = tok
 			p.context().(parentNode).addChild(stmt)
+			p.prev = stmt
 			return p.beginSegment, nil
 		}
 		return nil, p.closeError(tok)
@@ -257,7 +416,7 @@ func (p *Parser) parseStatementSentinel(tok Token) (tokenConsumer, error) {
 			if err := p.context().(segmentNode).addExpr(ary); err != nil {
 				return nil, err
 			}
-			return skipWhitespace(p.parseStatement), nil
+			return p.skipWhitespace(p.parseStatement), nil
 		}
 		return nil, p.closeError(tok)
 
@@ -272,7 +431,7 @@ func (p *Parser) parseStatementSentinel(tok Token) (tokenConsumer, error) {
 			if err := p.context().(segmentNode).addExpr(m); err != nil {
 				return nil, err
 			}
-			return skipWhitespace(p.parseStatement), nil
+			return p.skipWhitespace(p.parseStatement), nil
 		}
 		return nil, p.closeError(tok)
 
@@ -294,14 +453,14 @@ func (p *Parser) beginArray(tok Token) (tokenConsumer, error) {
 		StartTok: tok,
 		Elems:    []ExprNode{},
 	})
-	return skipWhitespace(p.parseStatement), nil
+	return p.skipWhitespace(p.parseStatement), nil
 }
 
 func (p *Parser) beginMap(tok Token) (tokenConsumer, error) {
 	m := newMapBuilder()
 	m.m.StartTok = tok
 	p.pushContext(m)
-	return skipWhitespace(p.parseStatement), nil
+	return p.skipWhitespace(p.parseStatement), nil
 }
 
 func (p *Parser) parseStatement(tok Token) (tokenConsumer, error) {
@@ -318,8 +477,16 @@ func (p *Parser) parseStatement(tok Token) (tokenConsumer, error) {
 		TFloat,
 		TDuration,
 		TRational,
+		TByteSize,
+		TDate,
+		TTimestamp,
+		TPeriod,
+		TAddr,
+		TPrefix,
+		TAddrPort,
 		TString,
 		TRawString,
+		THeredoc,
 		TWord,
 		TBoolean,
 		TRegexp:
@@ -327,7 +494,7 @@ func (p *Parser) parseStatement(tok Token) (tokenConsumer, error) {
 		if err := p.context().(segmentNode).addExpr(&Literal{Tok: tok}); err != nil {
 			return nil, err
 		}
-		return skipWhitespace(p.parseStatement), nil
+		return p.skipWhitespace(p.parseStatement), nil
 	}
 
 	return p.parseStatementSentinel(tok)
@@ -340,6 +507,10 @@ type ExpectedError struct {
 
 	// Msg is a message describing the expected token(s).
 	Msg string
+
+	// Notes describe locations related to the error, such as the start of a section that was
+	// not closed.
+	Notes []Note
 }
 
 func unexpected(tok Token, msg string, args ...any) *ExpectedError {
@@ -349,6 +520,12 @@ func unexpected(tok Token, msg string, args ...any) *ExpectedError {
 	}
 }
 
+// note adds a Note about tok to the error and returns the error.
+func (e *ExpectedError) note(tok Token, msg string, args ...any) *ExpectedError {
+	e.Notes = append(e.Notes, Note{Start: tok.Start, End: tok.End, Msg: fmt.Sprintf(msg, args...)})
+	return e
+}
+
 // Error is an implementation of error.
 func (e *ExpectedError) Error() string {
 	return "[" + e.Tok.Start.String() + "] unexpected " + e.Tok.Kind.String() + ": " + e.Msg
@@ -412,3 +589,15 @@ func (e *exprParser) addExpr(expr ExprNode) error {
 	e.expr = expr
 	return nil
 }
+
+// contextParser collects the nodes parsed by ParseInContext until parsing has finished.
+type contextParser struct {
+	children []Node
+	dangling []*Comment
+}
+
+func (*contextParser) astparse() {}
+
+func (c *contextParser) addChild(node Node) {
+	c.children = append(c.children, node)
+}
@@ -194,7 +194,7 @@ func TestParseExample(t *testing.T) {
 			/* server */ /* proxy */ statement("strip-x-headers", true).
 			/* server */ /* proxy */ statement("log-access", false).
 			/* server */ up().
-			/* server */ section("cache", "memory", "64mb").
+			/* server */ section("cache", "memory", mkbytes(64e6)).
 			/* server */ /* cache */ statement("expire", time.Minute*10, 404).
 			/* server */ /* cache */ statement("expire", time.Hour, 301, 302).
 			/* server */ /* cache */ statement("expire", time.Minute*5, 200).
@@ -429,3 +429,120 @@ func TestParseExpr(t *testing.T) {
 		}
 	})
 }
+
+func TestParseNginx(t *testing.T) {
+	const src = `# nginx.conf
+user www-data;
+events { worker_connections 1024; }
+
+http {
+    log_format main '$remote_addr [$time_local] "$request"';
+
+    map $http_upgrade $connection_upgrade {
+        default upgrade;
+        '' close;
+    }
+    map $http_user_agent $is_bot {
+        default 0;
+        "~*bot" 1;
+    }
+
+    server {
+        listen 80;
+        listen [::]:80 default_server;
+        server_name example.com *.example.com;
+        set $root '${document_root}/app'; # trailing comment
+
+        location / {
+            try_files $uri $uri/ =404;
+        }
+        location ~* \.(gif|jpg|png)$ {
+            expires max;
+        }
+        location ~ "^/api/v(\d+)/" {
+            proxy_pass http://backend/$1;
+        }
+        if ($http_user_agent ~ MSIE) {
+            return 301 https://$host$request_uri;
+        }
+    }
+}
+`
+	parseTestCase{
+		Src: src,
+		Fun: func(t *testing.T, src string) *Document {
+			l := NewLexer(strings.NewReader(src))
+			l.Flags = LexNginx
+			p := NewParser()
+			p.Flags = ParseQuotedNames
+			if err := p.Parse(l); err != nil {
+				t.Fatalf("Parse(..) error = %v; want nil", err)
+			}
+			return p.Document()
+		},
+		Doc: doc().
+			statement("user", mkword("www-data")).
+			section("events").statement("worker_connections", 1024).up().
+			section("http").
+			statement("log_format", mkword("main"), mkstr(`$remote_addr [$time_local] "$request"`)).
+			section("map", mkword("$http_upgrade"), mkword("$connection_upgrade")).
+			statement("default", mkword("upgrade")).
+			statement("", mkword("close")).up().
+			section("map", mkword("$http_user_agent"), mkword("$is_bot")).
+			statement("default", 0).
+			statement("~*bot", 1).up().
+			section("server").
+			statement("listen", 80).
+			statement("listen", mkword("[::]:80"), mkword("default_server")).
+			statement("server_name", mkword("example.com"), mkword("*.example.com")).
+			statement("set", mkword("$root"), mkstr("${document_root}/app")).
+			section("location", mkword("/")).
+			statement("try_files", mkword("$uri"), mkword("$uri/"), mkword("=404")).up().
+			section("location", mkword("~*"), mkword(`\.(gif|jpg|png)$`)).
+			statement("expires", mkword("max")).up().
+			section("location", mkword("~"), mkstr(`^/api/v(\d+)/`)).
+			statement("proxy_pass", mkword("http://backend/$1")).up().
+			section("if", mkword("($http_user_agent"), mkword("~"), mkword("MSIE)")).
+			statement("return", 301, mkword("https://$host$request_uri")).up().
+			Doc(),
+	}.Run(t)
+
+	// Without the nginx flags, '#' comments and brackets in words are errors.
+	if _, err := parse(src); err == nil {
+		t.Fatal("Parse(..) without LexNginx error = nil; want error")
+	}
+
+	// Without ParseQuotedNames, map entries named by strings are errors.
+	l := NewLexer(strings.NewReader(`map $a $b { '' close; }`))
+	l.Flags = LexNginx
+	if err := NewParser().Parse(l); err == nil {
+		t.Fatal("Parse(..) without ParseQuotedNames error = nil; want error")
+	}
+}
+
+func TestParseQuotedNamesString(t *testing.T) {
+	const src = "'~^/a b' 1;\n'' x;\n\"k\" y {\n\t`r` 2;\n}"
+
+	parse := func(src string) *Document {
+		t.Helper()
+		l := NewLexer(strings.NewReader(src))
+		l.Flags = LexSingleQuotes
+		p := NewParser()
+		p.Flags = ParseQuotedNames
+		if err := p.Parse(l); err != nil {
+			t.Fatalf("Parse(%q) error = %v; want nil", src, err)
+		}
+		return p.Document()
+	}
+
+	doc := parse(src)
+	if got := doc.String(); got != src {
+		t.Errorf("String() =\n%s\nwant\n%s", got, src)
+	}
+	objectsEqual(t, "", parse(doc.String()), doc)
+
+	synth := &Statement{NameTok: &Literal{Tok: Token{Kind: TString, Value: "a b"}}}
+	if got, want := synth.String(), `"a b";`; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,333 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NodePath is the address of a node relative to a root node. Each step of a NodePath descends from
+// one node to one of its children, parameters, elements, or values. Unlike a pointer to a node, a
+// NodePath may be stored, written as text, and used to find the same node in a copy of a document
+// or in the document after it is parsed again.
+//
+// The text of a NodePath is a sequence of steps separated by slashes, such as
+// "http[0]/server[1]/proxy[0]/@1". It is valid Selector syntax and selects exactly the node the path
+// refers to. See PathStep for the syntax of each step.
+type NodePath []PathStep
+
+// PathStepKind is the kind of a PathStep.
+type PathStepKind int
+
+const (
+	// PathChild is a step from a document or section to a child statement or section.
+	PathChild PathStepKind = iota
+	// PathParam is a step from a statement or section to one of its parameters.
+	PathParam
+	// PathIndex is a step from an array to one of its elements.
+	PathIndex
+	// PathKey is a step from a map to one of its values.
+	PathKey
+)
+
+// PathStep is a single step of a NodePath. Its text depends on its Kind:
+//
+//	name[N]  PathChild: the Nth statement or section named name, counting from 0.
+//	name     PathChild: the only statement or section named name (Index is -1).
+//	@N       PathParam: the Nth parameter, counting from 0.
+//	N        PathIndex: the Nth element of an array, counting from 0.
+//	key      PathKey: the value of key in a map.
+//
+// Paths returned by Path always give the index of a PathChild step, even if only one statement or
+// section has its name, so that a path stays valid when a sibling with the same name is added
+// after it. The form without an index may be used in paths passed to Lookup.
+//
+// Names and keys that could be mistaken for other syntax, such as names containing slashes or
+// keys that are integers, are written as quoted strings.
+type PathStep struct {
+	Kind PathStepKind
+
+	// Name is the name of the statement or section of a PathChild step or the key of a PathKey
+	// step.
+	Name string
+
+	// Index is the index of the parameter or element of a PathParam or PathIndex step. For a
+	// PathChild step, it is the index of the statement or section among those with the same
+	// name, or -1 to refer to the only one.
+	Index int
+}
+
+func (s PathStep) String() string {
+	switch s.Kind {
+	case PathChild:
+		if s.Index < 0 {
+			return quotePathName(s.Name)
+		}
+		return quotePathName(s.Name) + "[" + strconv.Itoa(s.Index) + "]"
+	case PathParam:
+		return "@" + strconv.Itoa(s.Index)
+	case PathIndex:
+		return strconv.Itoa(s.Index)
+	case PathKey:
+		return quotePathName(s.Name)
+	}
+	return "<invalid step>"
+}
+
+// String returns the text of the path. The text of an empty path, which refers to the root node,
+// is the empty string.
+func (p NodePath) String() string {
+	steps := make([]string, len(p))
+	for i, s := range p {
+		steps[i] = s.String()
+	}
+	return strings.Join(steps, "/")
+}
+
+// quotePathName returns name quoted if it would otherwise be read as something other than a name
+// or key.
+func quotePathName(name string) string {
+	quote := name == "" || name == "*" || strings.HasPrefix(name, "@") ||
+		strings.ContainsAny(name, " \t\r\n\"/[]=") ||
+		strconv.Quote(name) != `"`+name+`"`
+	if _, err := strconv.Atoi(name); err == nil {
+		quote = true
+	}
+	if quote {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// Path returns the path of n relative to root. Each step to a statement or section includes its
+// index. If n is root, Path returns an empty, non-nil NodePath. If n is not a descendant of root, or is a document nested in root, Path returns nil.
+// Documents nested in root, such as included files, are treated as part of their parents, so
+// paths to their children are the same as if they were children of the nested document's parent.
+func Path(root ParentNode, n Node) NodePath {
+	if n == root {
+		return NodePath{}
+	}
+	return findPath(root, n, NodePath{})
+}
+
+func findPath(node, target Node, prefix NodePath) NodePath {
+	seen := map[string]int{}
+	for i, c := range queryChildren(node) {
+		var step PathStep
+		switch node.(type) {
+		case *Array:
+			step = PathStep{Kind: PathIndex, Index: i}
+		case *Map:
+			step = PathStep{Kind: PathKey, Name: c.name}
+		default:
+			step = PathStep{Kind: PathChild, Name: c.name, Index: seen[c.name]}
+			seen[c.name]++
+		}
+
+		path := append(prefix[:len(prefix):len(prefix)], step)
+		if c.node == target {
+			return path
+		}
+		if found := findPath(c.node, target, path); found != nil {
+			return found
+		}
+	}
+
+	for _, p := range queryParams(node) {
+		index, _ := strconv.Atoi(p.name)
+		path := append(prefix[:len(prefix):len(prefix)], PathStep{Kind: PathParam, Index: index})
+		if p.node == target {
+			return path
+		}
+		if found := findPath(p.node, target, path); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// ParseNodePath parses the text of a NodePath, as returned by its String method. If text is not a
+// valid path, it returns a *NodePathError.
+func ParseNodePath(text string) (NodePath, error) {
+	qp := queryParser{query: text}
+	path, err := qp.parsePath()
+	if se, ok := err.(*SelectorError); ok {
+		return nil, &NodePathError{Path: se.Query, Offset: se.Offset, Msg: se.Msg}
+	}
+	return path, err
+}
+
+// NodePathError is returned by ParseNodePath for text that is not a valid NodePath.
+type NodePathError struct {
+	// Path is the text that is not a valid path.
+	Path string
+
+	// Offset is the byte offset in Path at which the error occurred.
+	Offset int
+
+	// Msg is a message describing the error.
+	Msg string
+}
+
+func (e *NodePathError) Error() string {
+	return "invalid node path " + strconv.Quote(e.Path) + " at offset " + strconv.Itoa(e.Offset) + ": " + e.Msg
+}
+
+func (qp *queryParser) parsePath() (NodePath, error) {
+	path := NodePath{}
+	// inExpr is true once the path has stepped into a parameter, where the following steps are
+	// array indexes and map keys.
+	inExpr := false
+	for qp.pos < len(qp.query) {
+		if len(path) > 0 {
+			if !strings.HasPrefix(qp.query[qp.pos:], "/") {
+				return nil, qp.errorf(qp.pos, "expected '/'")
+			}
+			qp.pos++
+		}
+
+		start := qp.pos
+		if strings.HasPrefix(qp.query[qp.pos:], "@") {
+			qp.pos++
+			name, quoted, err := qp.parseName("/[")
+			if err != nil {
+				return nil, err
+			}
+			index, err := strconv.Atoi(name)
+			if err != nil || quoted || index < 0 {
+				return nil, qp.errorf(start, "expected a parameter index after '@'")
+			}
+			if inExpr {
+				return nil, qp.errorf(start, "parameter step follows a parameter")
+			}
+			path = append(path, PathStep{Kind: PathParam, Index: index})
+			inExpr = true
+			continue
+		}
+
+		name, quoted, err := qp.parseName("/[")
+		if err != nil {
+			return nil, err
+		}
+		if name == "" && !quoted {
+			return nil, qp.errorf(qp.pos, "expected a name")
+		}
+
+		if inExpr {
+			if index, err := strconv.Atoi(name); err == nil && !quoted && index >= 0 {
+				path = append(path, PathStep{Kind: PathIndex, Index: index})
+			} else {
+				path = append(path, PathStep{Kind: PathKey, Name: name})
+			}
+			continue
+		}
+
+		step := PathStep{Kind: PathChild, Name: name, Index: -1}
+		if strings.HasPrefix(qp.query[qp.pos:], "[") {
+			qp.pos++
+			start := qp.pos
+			digits, _, _ := qp.parseName("]")
+			index, err := strconv.Atoi(digits)
+			if err != nil || index < 0 {
+				return nil, qp.errorf(start, "expected an index")
+			}
+			if !strings.HasPrefix(qp.query[qp.pos:], "]") {
+				return nil, qp.errorf(qp.pos, "expected ']'")
+			}
+			qp.pos++
+			step.Index = index
+		}
+		path = append(path, step)
+	}
+	return path, nil
+}
+
+// Lookup returns the node that path refers to relative to root. If path does not refer to a node,
+// it returns a *LookupError.
+func Lookup(root ParentNode, path NodePath) (Node, error) {
+	var node Node = root
+	for i, step := range path {
+		next, msg := step.lookup(node)
+		if next == nil {
+			return nil, &LookupError{Path: path, Step: i, Msg: msg}
+		}
+		node = next
+	}
+	return node, nil
+}
+
+// lookup returns the node that the step refers to relative to node, or a message describing why
+// there is no such node.
+func (s PathStep) lookup(node Node) (Node, string) {
+	switch s.Kind {
+	case PathChild:
+		if _, ok := node.(ParentNode); !ok {
+			return nil, "not a section or document"
+		}
+		var matches []Node
+		for _, c := range queryChildren(node) {
+			if c.name == s.Name {
+				matches = append(matches, c.node)
+			}
+		}
+		switch {
+		case len(matches) == 0:
+			return nil, "not found"
+		case s.Index < 0 && len(matches) > 1:
+			return nil, "ambiguous: " + strconv.Itoa(len(matches)) + " statements or sections have this name"
+		case s.Index < 0:
+			return matches[0], ""
+		case s.Index >= len(matches):
+			return nil, "index out of range: " + strconv.Itoa(len(matches)) + " statements or sections have this name"
+		}
+		return matches[s.Index], ""
+
+	case PathParam:
+		pn, ok := node.(ParamNode)
+		if !ok {
+			return nil, "not a statement or section"
+		}
+		params := pn.Parameters()
+		if s.Index < 0 || s.Index >= len(params) {
+			return nil, "index out of range: " + pluralize(len(params), "parameter")
+		}
+		return params[s.Index], ""
+
+	case PathIndex:
+		arr, ok := node.(*Array)
+		if !ok {
+			return nil, "not an array"
+		}
+		if s.Index < 0 || s.Index >= len(arr.Elems) {
+			return nil, "index out of range: " + pluralize(len(arr.Elems), "element")
+		}
+		return arr.Elems[s.Index], ""
+
+	case PathKey:
+		m, ok := node.(*Map)
+		if !ok {
+			return nil, "not a map"
+		}
+		entry := m.Elems[s.Name]
+		if entry == nil {
+			return nil, "not found"
+		}
+		return entry.Val, ""
+	}
+	return nil, "invalid step kind"
+}
+
+// LookupError is returned by Lookup when a path does not refer to a node.
+type LookupError struct {
+	// Path is the path that was looked up.
+	Path NodePath
+
+	// Step is the index of the step in Path that does not refer to a node.
+	Step int
+
+	// Msg is a message describing why the step does not refer to a node.
+	Msg string
+}
+
+func (e *LookupError) Error() string {
+	return "lookup " + e.Path[:e.Step+1].String() + ": " + e.Msg
+}
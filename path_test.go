@@ -0,0 +1,193 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const pathSource = `
+user http;
+server a {
+	proxy /api http://localhost:8080;
+	proxy /web http://localhost:8081 {
+		strip-x-headers;
+	}
+	root [/srv/www /srv/alt];
+	headers #{ X-Frame-Options deny "0" zero "a/b" [c d] "x y" z };
+}
+server b {
+	server_name example.com;
+}
+`
+
+func TestPath(t *testing.T) {
+	doc := mustParse(t, pathSource)
+	// Append an included document to check that paths treat it as part of its parent.
+	doc.Children = append(doc.Children, mustParse(t, "server c;"))
+
+	cases := []struct {
+		path  string
+		query string
+	}{
+		{"user[0]", "user"},
+		{"user[0]/@0", "user/@0"},
+		{"server[0]", "server[0]"},
+		{"server[1]", "server[b]"},
+		{"server[2]", "server[c]"},
+		{"server[0]/proxy[1]/strip-x-headers[0]", "//strip-x-headers"},
+		{"server[0]/proxy[1]/@1", "//proxy[1]/@1"},
+		{"server[0]/root[0]/@0/1", "//root/@0/1"},
+		{"server[0]/headers[0]/@0/X-Frame-Options", "//headers/@0/X-Frame-Options"},
+		{`server[0]/headers[0]/@0/"0"`, `//headers/@0/"0"`},
+		{`server[0]/headers[0]/@0/"a/b"/0`, `//headers/@0/"a/b"/0`},
+		{`server[0]/headers[0]/@0/"x y"`, `//headers/@0/"x y"`},
+		{"server[1]/server_name[0]", "//server_name"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			nodes, err := Query(doc, c.query)
+			if err != nil || len(nodes) != 1 {
+				t.Fatalf("Query(%q) = %v, %v; want one node", c.query, nodes, err)
+			}
+			node := nodes[0]
+
+			path := Path(doc, node)
+			if got := path.String(); got != c.path {
+				t.Fatalf("Path() = %q; want %q", got, c.path)
+			}
+
+			parsed, err := ParseNodePath(c.path)
+			if err != nil {
+				t.Fatalf("ParseNodePath() error = %v", err)
+			}
+			if !reflect.DeepEqual(parsed, path) {
+				t.Errorf("ParseNodePath() = %#v; want %#v", parsed, path)
+			}
+
+			found, err := Lookup(doc, parsed)
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if found != node {
+				t.Errorf("Lookup() = %v; want %v", found, node)
+			}
+
+			// The path is also a selector for the node.
+			if sel, err := Query(doc, c.path); err != nil || len(sel) != 1 || sel[0] != node {
+				t.Errorf("Query(%q) = %v, %v; want %v", c.path, sel, err, node)
+			}
+		})
+	}
+
+	t.Run("Root", func(t *testing.T) {
+		path := Path(doc, doc)
+		if path == nil || len(path) != 0 {
+			t.Fatalf("Path() = %#v; want empty path", path)
+		}
+		if node, err := Lookup(doc, path); err != nil || node != doc {
+			t.Errorf("Lookup() = %v, %v; want root", node, err)
+		}
+	})
+
+	t.Run("Unindexed", func(t *testing.T) {
+		path, err := ParseNodePath("server[0]/proxy[1]/strip-x-headers")
+		if err != nil {
+			t.Fatalf("ParseNodePath() error = %v", err)
+		}
+		want, _ := Query(doc, "//strip-x-headers")
+		if node, err := Lookup(doc, path); err != nil || node != want[0] {
+			t.Errorf("Lookup() = %v, %v; want %v", node, err, want[0])
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if path := Path(doc, mustParse(t, "user http;").Children[0]); path != nil {
+			t.Errorf("Path() = %v; want nil", path)
+		}
+	})
+}
+
+func TestParseNodePathErrors(t *testing.T) {
+	cases := []struct {
+		path string
+		msg  string
+	}{
+		{"a//b", `invalid node path "a//b" at offset 2: expected a name`},
+		{"a b", `invalid node path "a b" at offset 1: expected '/'`},
+		{"a[x]", `invalid node path "a[x]" at offset 2: expected an index`},
+		{"a[1", `invalid node path "a[1" at offset 3: expected ']'`},
+		{"a/@b", `invalid node path "a/@b" at offset 2: expected a parameter index after '@'`},
+		{"a/@0/@1", `invalid node path "a/@0/@1" at offset 5: parameter step follows a parameter`},
+		{`a/"b`, `invalid node path "a/\"b" at offset 2: unterminated or invalid quoted string`},
+	}
+
+	for _, c := range cases {
+		_, err := ParseNodePath(c.path)
+		var pe *NodePathError
+		if !errors.As(err, &pe) {
+			t.Errorf("ParseNodePath(%q) = %v; want *NodePathError", c.path, err)
+			continue
+		}
+		if got := err.Error(); got != c.msg {
+			t.Errorf("ParseNodePath(%q) = %q; want %q", c.path, got, c.msg)
+		}
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	doc := mustParse(t, pathSource)
+
+	cases := []struct {
+		path string
+		msg  string
+	}{
+		{"missing", `lookup missing: not found`},
+		{"server", `lookup server: ambiguous: 2 statements or sections have this name`},
+		{"server[2]", `lookup server[2]: index out of range: 2 statements or sections have this name`},
+		{"user/x", `lookup user/x: not a section or document`},
+		{"user/@1", `lookup user/@1: index out of range: 1 parameter`},
+		{"user/@0/0", `lookup user/@0/0: not an array`},
+		{"user/@0/x", `lookup user/@0/x: not a map`},
+		{"server[0]/root/@0/2", `lookup server[0]/root/@0/2: index out of range: 2 elements`},
+		{"server[0]/headers/@0/x", `lookup server[0]/headers/@0/x: not found`},
+	}
+
+	for _, c := range cases {
+		path, err := ParseNodePath(c.path)
+		if err != nil {
+			t.Fatalf("ParseNodePath(%q) error = %v", c.path, err)
+		}
+		_, err = Lookup(doc, path)
+		var le *LookupError
+		if !errors.As(err, &le) {
+			t.Errorf("Lookup(%q) = %v; want *LookupError", c.path, err)
+			continue
+		}
+		if got := err.Error(); got != c.msg {
+			t.Errorf("Lookup(%q) = %q; want %q", c.path, got, c.msg)
+		}
+	}
+}
+
+func TestWalkErrorPath(t *testing.T) {
+	doc := mustParse(t, pathSource)
+	walker := sectionWalker("main", "user",
+		sectionWalker("server", "root", "headers", "server_name",
+			sectionWalker("proxy"),
+		),
+	)
+
+	err := Walk(doc, walker)
+	var we *WalkError
+	if !errors.As(err, &we) {
+		t.Fatalf("Walk() = %v; want *WalkError", err)
+	}
+	if got, want := we.Path().String(), "server[0]/proxy[0]"; got != want {
+		t.Errorf("Path() = %q; want %q", got, want)
+	}
+	if we.Root != doc {
+		t.Errorf("Root = %v; want document", we.Root)
+	}
+}
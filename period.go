@@ -0,0 +1,158 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/regexp"
+)
+
+// Period is a length of time made of calendar components, as written in an ISO 8601 duration such
+// as P1Y2M3DT4H. Unlike a time.Duration, a Period's years, months, and days do not have a fixed
+// length: a month may have 28 to 31 days, and a day may have 23 to 25 hours across a daylight
+// saving time change. A Period therefore only has a length relative to a time, using AddTo.
+//
+// Periods are the values of TPeriod tokens.
+type Period struct {
+	Years  int
+	Months int
+	// Days is the number of days in the period, including seven days for each week.
+	Days int
+	// Time is the hours, minutes, and seconds of the period.
+	Time time.Duration
+}
+
+// ParsePeriod parses an ISO 8601 duration, such as P1Y2M, P2W, or PT1H30M. Years, months, weeks,
+// and days must be integers, while hours, minutes, and seconds may have a fractional part. At
+// least one component must be given, and the time components must follow a 'T'.
+func ParsePeriod(text string) (Period, error) {
+	if !isPeriodText(text) {
+		return Period{}, errors.New("not an ISO 8601 duration")
+	}
+	m := periodPattern.FindStringSubmatch(text)
+
+	var p Period
+	for i, dst := range []*int{&p.Years, &p.Months, nil, &p.Days} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return Period{}, errors.New("component out of range")
+		}
+		if dst == nil {
+			// Weeks
+			if n > math.MaxInt/7 {
+				return Period{}, errors.New("component out of range")
+			}
+			p.Days += n * 7
+			continue
+		}
+		if *dst > math.MaxInt-n {
+			return Period{}, errors.New("component out of range")
+		}
+		*dst += n
+	}
+
+	var dur strings.Builder
+	for i, unit := range []string{"h", "m", "s"} {
+		if num := m[i+5]; num != "" {
+			dur.WriteString(num + unit)
+		}
+	}
+	if dur.Len() > 0 {
+		d, err := time.ParseDuration(dur.String())
+		if err != nil {
+			return Period{}, err
+		}
+		p.Time = d
+	}
+	return p, nil
+}
+
+// periodPattern matches an ISO 8601 duration. Its submatches are the years, months, weeks, days,
+// hours, minutes, and seconds of the duration.
+var periodPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// isPeriodText returns true if text is an ISO 8601 duration with at least one component.
+func isPeriodText(text string) bool {
+	return text != "P" && !strings.HasSuffix(text, "T") && periodPattern.MatchString(text)
+}
+
+// String returns p as an ISO 8601 duration. Weeks are written as days, and the time is written in
+// hours, minutes, and seconds. The zero Period is written as PT0S. Negative components are written
+// with a leading '-', such as PT-1H-30M, which is not an ISO 8601 duration and cannot be parsed.
+func (p Period) String() string {
+	var sb strings.Builder
+	sb.WriteByte('P')
+	for _, c := range []struct {
+		n      int
+		suffix byte
+	}{{p.Years, 'Y'}, {p.Months, 'M'}, {p.Days, 'D'}} {
+		if c.n != 0 {
+			sb.WriteString(strconv.Itoa(c.n))
+			sb.WriteByte(c.suffix)
+		}
+	}
+	if p.Time == 0 {
+		if sb.Len() == 1 {
+			return "PT0S"
+		}
+		return sb.String()
+	}
+
+	sb.WriteByte('T')
+	d := p.Time
+	if h := d / time.Hour; h != 0 {
+		sb.WriteString(strconv.FormatInt(int64(h), 10) + "H")
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m != 0 {
+		sb.WriteString(strconv.FormatInt(int64(m), 10) + "M")
+		d -= m * time.Minute
+	}
+	if d != 0 {
+		if d < 0 {
+			sb.WriteByte('-')
+			d = -d
+		}
+		sb.WriteString(strconv.FormatInt(int64(d/time.Second), 10))
+		if frac := d % time.Second; frac != 0 {
+			sb.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", frac), "0"))
+		}
+		sb.WriteByte('S')
+	}
+	return sb.String()
+}
+
+// IsNegative returns true if any of p's components are negative.
+func (p Period) IsNegative() bool {
+	return p.Years < 0 || p.Months < 0 || p.Days < 0 || p.Time < 0
+}
+
+// AddTo returns t plus p. The years, months, and days of p are added to t using time.AddDate, and
+// then p's Time is added.
+func (p Period) AddTo(t time.Time) time.Time {
+	return t.AddDate(p.Years, p.Months, p.Days).Add(p.Time)
+}
+
+// Duration returns p as a time.Duration and true if p has no years or months, treating each day as
+// 24 hours. If p has years or months, or does not fit in a time.Duration, it returns 0 and false.
+func (p Period) Duration() (time.Duration, bool) {
+	if p.Years != 0 || p.Months != 0 {
+		return 0, false
+	}
+	const maxDays = int(math.MaxInt64 / int64(24*time.Hour))
+	if p.Days > maxDays || p.Days < -maxDays {
+		return 0, false
+	}
+	days := time.Duration(p.Days) * 24 * time.Hour
+	if (p.Time > 0 && days > math.MaxInt64-p.Time) || (p.Time < 0 && days < math.MinInt64-p.Time) {
+		return 0, false
+	}
+	return days + p.Time, true
+}
@@ -0,0 +1,128 @@
+package codf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	day := 24 * time.Hour
+	cases := []struct {
+		text string
+		want Period
+		str  string
+	}{
+		{"P1Y", Period{Years: 1}, "P1Y"},
+		{"P1Y2M3D", Period{Years: 1, Months: 2, Days: 3}, "P1Y2M3D"},
+		{"P2W", Period{Days: 14}, "P14D"},
+		{"P1W1D", Period{Days: 8}, "P8D"},
+		{"PT36H", Period{Time: 36 * time.Hour}, "PT36H"},
+		{"PT90M", Period{Time: 90 * time.Minute}, "PT1H30M"},
+		{"PT1.5S", Period{Time: 1500 * time.Millisecond}, "PT1.5S"},
+		{"PT0.000000001S", Period{Time: 1}, "PT0.000000001S"},
+		{"P1DT0.5H", Period{Days: 1, Time: 30 * time.Minute}, "P1DT30M"},
+		{"P0D", Period{}, "PT0S"},
+		{"PT0S", Period{}, "PT0S"},
+	}
+	for _, c := range cases {
+		t.Run(c.text, func(t *testing.T) {
+			got, err := ParsePeriod(c.text)
+			if err != nil {
+				t.Fatalf("ParsePeriod(%q) error = %v", c.text, err)
+			}
+			if got != c.want {
+				t.Errorf("ParsePeriod(%q) = %#v; want %#v", c.text, got, c.want)
+			}
+			if s := got.String(); s != c.str {
+				t.Errorf("String() = %q; want %q", s, c.str)
+			}
+		})
+	}
+
+	negative := Period{Months: -1, Time: -(90*time.Minute + 1500*time.Millisecond)}
+	if s := negative.String(); s != "P-1MT-1H-30M-1.5S" {
+		t.Errorf("String() = %q; want %q", s, "P-1MT-1H-30M-1.5S")
+	}
+	if !negative.IsNegative() || (Period{Days: 1}).IsNegative() {
+		t.Errorf("IsNegative() is wrong for %#v or P1D", negative)
+	}
+
+	for _, text := range []string{"", "P", "PT", "P1", "1D", "P-1D", "P1.5D", "P1D2Y", "PT1D", "P1DT", "p1d", "PT9999999999H"} {
+		t.Run("Invalid/"+text, func(t *testing.T) {
+			if got, err := ParsePeriod(text); err == nil {
+				t.Errorf("ParsePeriod(%q) = %#v; want error", text, got)
+			}
+		})
+	}
+
+	if d, ok := (Period{Days: 2, Time: time.Hour}).Duration(); !ok || d != 2*day+time.Hour {
+		t.Errorf("Duration() = %v, %t; want %v, true", d, ok, 2*day+time.Hour)
+	}
+	if d, ok := (Period{Months: 1}).Duration(); ok {
+		t.Errorf("Duration() = %v, %t; want 0, false", d, ok)
+	}
+	if d, ok := (Period{Days: 200000}).Duration(); ok {
+		t.Errorf("Duration() = %v, %t; want 0, false", d, ok)
+	}
+	if d, ok := (Period{Days: -1, Time: time.Hour}).Duration(); !ok || d != -23*time.Hour {
+		t.Errorf("Duration() = %v, %t; want %v, true", d, ok, -23*time.Hour)
+	}
+	if d, ok := (Period{Days: -200000}).Duration(); ok {
+		t.Errorf("Duration() = %v, %t; want 0, false", d, ok)
+	}
+	if d, ok := (Period{Days: -106751, Time: -24 * time.Hour}).Duration(); ok {
+		t.Errorf("Duration() = %v, %t; want 0, false", d, ok)
+	}
+}
+
+func TestPeriodAddTo(t *testing.T) {
+	nyc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		period Period
+		start  time.Time
+		want   time.Time
+	}{
+		{
+			"EndOfMonth",
+			Period{Months: 1},
+			time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"LeapYear",
+			Period{Years: 1},
+			time.Date(2027, 2, 28, 12, 0, 0, 0, time.UTC),
+			time.Date(2028, 2, 28, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			// Across the start of daylight saving time, a day is 23 hours long.
+			"DaylightSaving",
+			Period{Days: 1},
+			time.Date(2026, 3, 7, 12, 0, 0, 0, nyc),
+			time.Date(2026, 3, 8, 12, 0, 0, 0, nyc),
+		},
+		{
+			"DaysAndTime",
+			Period{Days: 1, Time: 90 * time.Minute},
+			time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 18, 0, 30, 0, 0, time.UTC),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.period.AddTo(c.start); !got.Equal(c.want) {
+				t.Errorf("%v.AddTo(%v) = %v; want %v", c.period, c.start, got, c.want)
+			}
+		})
+	}
+
+	start := time.Date(2026, 3, 7, 12, 0, 0, 0, nyc)
+	if got := (Period{Days: 1}).AddTo(start).Sub(start); got != 23*time.Hour {
+		t.Errorf("P1D across daylight saving time = %v; want 23h", got)
+	}
+}
@@ -0,0 +1,527 @@
+// Package printer formats codf documents in a canonical style.
+//
+// Unlike Document.String, which is meant for debugging, the printer keeps comments attached by the
+// ParseComments parser flag, preserves single blank lines between statements and sections, keeps
+// short arrays and maps on one line, and can be configured to indent with spaces, align the
+// parameters of consecutive statements, and wrap long lines.
+package printer
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"net/netip"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/3JoB/codf"
+	"github.com/grafana/regexp"
+)
+
+// TabWidth is the width of a tab when measuring line widths for Config.MaxWidth.
+const TabWidth = 8
+
+// Config controls the output of the printer. The zero Config indents with one tab per level,
+// does not align parameters, and never wraps lines.
+type Config struct {
+	// Indent is the number of tabs, or spaces if UseSpaces is set, to indent each level by.
+	// If Indent is less than 1, it defaults to one tab or four spaces.
+	Indent int
+
+	// UseSpaces indents with spaces instead of tabs.
+	UseSpaces bool
+
+	// AlignParams pads the names of consecutive statements so that their parameters begin in the
+	// same column. A blank line, section, or comment ends a run of aligned statements.
+	AlignParams bool
+
+	// MaxWidth is the preferred maximum width of a line. Arrays and maps that would not fit are
+	// written with one element per line, and statement parameters that would not fit are
+	// continued on the following line. If MaxWidth is less than 1, lines are never wrapped.
+	MaxWidth int
+}
+
+// Fprint writes node to w using the zero Config.
+func Fprint(w io.Writer, node codf.Node) error {
+	return (&Config{}).Fprint(w, node)
+}
+
+// Source parses src as a codf document, with comments, and returns it formatted using the zero
+// Config.
+func Source(src []byte) ([]byte, error) {
+	return (&Config{}).Source(src)
+}
+
+// Source parses src as a codf document, with comments, and returns it formatted using c.
+func (c *Config) Source(src []byte) ([]byte, error) {
+	p := codf.NewParser()
+	p.Flags = codf.ParseComments
+	if err := p.Parse(codf.NewLexer(bytes.NewReader(src))); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := c.Fprint(&buf, p.Document()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Fprint writes node to w using c. Documents are followed by a newline if they are not empty.
+func (c *Config) Fprint(w io.Writer, node codf.Node) error {
+	p := &printer{cfg: *c}
+	if p.cfg.Indent < 1 {
+		p.cfg.Indent = 1
+		if p.cfg.UseSpaces {
+			p.cfg.Indent = 4
+		}
+	}
+
+	switch node := node.(type) {
+	case *codf.Document:
+		p.body(node.Children, node.Comments.Dangling, 0)
+		if p.buf.Len() > 0 {
+			p.newline()
+		}
+	case *codf.Statement, *codf.Section:
+		p.segment(node, 0, 0)
+	case codf.ExprNode:
+		p.expr(node, 0)
+	default:
+		p.write(node.Token().Raw)
+	}
+
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+type printer struct {
+	cfg Config
+	buf bytes.Buffer
+	col int // Width of the current line.
+}
+
+func (p *printer) writeString(s string) {
+	p.buf.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.col = 0
+		s = s[i+1:]
+	}
+	p.col += width(s)
+}
+
+func (p *printer) write(b []byte) {
+	p.writeString(string(b))
+}
+
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	p.col = 0
+}
+
+func (p *printer) indent(depth int) {
+	n := depth * p.cfg.Indent
+	if p.cfg.UseSpaces {
+		p.buf.WriteString(strings.Repeat(" ", n))
+		p.col += n
+	} else {
+		p.buf.WriteString(strings.Repeat("\t", n))
+		p.col += n * TabWidth
+	}
+}
+
+// fits returns true if a string of width n fits on the current line.
+func (p *printer) fits(n int) bool {
+	return p.cfg.MaxWidth < 1 || p.col+n <= p.cfg.MaxWidth
+}
+
+// body writes the children of a document or section at depth, followed by its dangling
+// comments.
+func (p *printer) body(children []codf.Node, dangling []*codf.Comment, depth int) {
+	children = flatten(children)
+	pads := p.alignment(children)
+
+	prevEnd := 0
+	for i, child := range children {
+		if i > 0 {
+			p.newline()
+			if blankBefore(child, prevEnd) {
+				p.newline()
+			}
+		}
+		p.segment(child, depth, pads[i])
+		prevEnd = endLine(child)
+	}
+
+	for i, c := range dangling {
+		if len(children) > 0 || i > 0 {
+			p.newline()
+			if prevEnd > 0 && c.Tok.Start.Line > prevEnd+1 {
+				p.newline()
+			}
+		}
+		p.indent(depth)
+		p.writeString(c.String())
+		prevEnd = c.Tok.Start.Line
+	}
+}
+
+// alignment returns the number of spaces to pad each child's name by when aligning parameters.
+func (p *printer) alignment(children []codf.Node) []int {
+	pads := make([]int, len(children))
+	if !p.cfg.AlignParams {
+		return pads
+	}
+
+	align := func(run []int) {
+		if len(run) < 2 {
+			return
+		}
+		longest := 0
+		for _, i := range run {
+			longest = max(longest, width(nameText(children[i])))
+		}
+		for _, i := range run {
+			pads[i] = longest - width(nameText(children[i]))
+		}
+	}
+
+	var run []int
+	prevEnd := 0
+	for i, child := range children {
+		stmt, ok := child.(*codf.Statement)
+		if !ok || len(stmt.Params) == 0 || len(stmt.Comments.Leading) > 0 ||
+			(len(run) > 0 && blankBefore(child, prevEnd)) {
+			align(run)
+			run = run[:0]
+		}
+		if ok && len(stmt.Params) > 0 {
+			run = append(run, i)
+		}
+		prevEnd = endLine(child)
+	}
+	align(run)
+	return pads
+}
+
+// segment writes a statement or section, with its comments, at depth. The name of a statement is
+// padded with pad spaces.
+func (p *printer) segment(node codf.Node, depth, pad int) {
+	var (
+		name     string
+		params   []codf.ExprNode
+		comments *codf.Comments
+	)
+	switch node := node.(type) {
+	case *codf.Statement:
+		name, params, comments = nameText(node), node.Params, &node.Comments
+	case *codf.Section:
+		name, params, comments = nameText(node), node.Params, &node.Comments
+	default:
+		return
+	}
+
+	for i, c := range comments.Leading {
+		p.indent(depth)
+		p.writeString(c.String())
+		p.newline()
+
+		// Keep a blank line between comments or between the comments and the node.
+		next := node.Token().Start.Line
+		if i < len(comments.Leading)-1 {
+			next = comments.Leading[i+1].Tok.Start.Line
+		}
+		if line := c.Tok.Start.Line; line > 0 && next > line+1 {
+			p.newline()
+		}
+	}
+
+	p.indent(depth)
+	p.writeString(name + strings.Repeat(" ", pad))
+	p.params(params, depth)
+
+	switch node := node.(type) {
+	case *codf.Statement:
+		p.writeString(";")
+	case *codf.Section:
+		if len(flatten(node.Children)) == 0 && len(node.Comments.Dangling) == 0 {
+			p.writeString(" {}")
+			break
+		}
+		p.writeString(" {")
+		p.newline()
+		p.body(node.Children, node.Comments.Dangling, depth+1)
+		p.newline()
+		p.indent(depth)
+		p.writeString("}")
+	}
+
+	if comments.Trailing != nil {
+		p.writeString(" " + comments.Trailing.String())
+	}
+}
+
+// params writes the parameters of a statement or section, continuing them on the next line at
+// depth+1 if they do not fit on the current line.
+func (p *printer) params(params []codf.ExprNode, depth int) {
+	for i, param := range params {
+		flat, ok := p.flat(param)
+		n := 1 + width(flat)
+		if i == len(params)-1 {
+			// Leave room for the closing semicolon.
+			n++
+		}
+		if i > 0 && (!ok || !p.fits(n)) {
+			p.newline()
+			p.indent(depth + 1)
+		} else {
+			p.writeString(" ")
+		}
+		p.expr(param, depth)
+	}
+}
+
+// expr writes an ExprNode at depth. Arrays and maps are written on one line if they fit and
+// contain no comments. Comments in arrays and maps are kept next to the elements and entries
+// they were written next to.
+func (p *printer) expr(node codf.ExprNode, depth int) {
+	if flat, ok := p.flat(node); ok && p.fits(width(flat)) {
+		p.writeString(flat)
+		return
+	}
+
+	switch node := node.(type) {
+	case *codf.Array:
+		p.writeString("[")
+		comments := node.Comments.Dangling
+		var prev codf.Node
+		for _, elem := range node.Elems {
+			comments = p.between(comments, prev, elem, depth+1)
+			p.newline()
+			p.indent(depth + 1)
+			p.expr(elem, depth+1)
+			prev = elem
+		}
+		comments = p.between(comments, prev, nil, depth+1)
+		p.dangling(comments, depth+1)
+		p.newline()
+		p.indent(depth)
+		p.writeString("]")
+
+	case *codf.Map:
+		p.writeString("#{")
+		comments := node.Comments.Dangling
+		var prev codf.Node
+		for _, entry := range node.Pairs() {
+			comments = p.between(comments, prev, entry.Key, depth+1)
+			p.newline()
+			p.indent(depth + 1)
+			p.expr(entry.Key, depth+1)
+			p.writeString(" ")
+			p.expr(entry.Val, depth+1)
+			prev = entry.Val
+		}
+		comments = p.between(comments, prev, nil, depth+1)
+		p.dangling(comments, depth+1)
+		p.newline()
+		p.indent(depth)
+		p.writeString("}")
+
+	default:
+		flat, _ := p.flat(node)
+		p.writeString(flat)
+	}
+}
+
+// between writes the comments that come before next, or all comments with locations if next is
+// nil, and returns the rest. A comment on the line that prev ends on is written after prev on the
+// same line, and other comments are written on their own lines at depth. Comments without
+// locations are left for dangling.
+func (p *printer) between(comments []*codf.Comment, prev, next codf.Node, depth int) []*codf.Comment {
+	for len(comments) > 0 {
+		start := comments[0].Tok.Start
+		if start.Line == 0 || (next != nil && start.Offset >= next.Token().Start.Offset) {
+			break
+		}
+		if prev != nil && start.Line == endLine(prev) {
+			p.writeString(" ")
+		} else {
+			p.newline()
+			p.indent(depth)
+		}
+		p.writeString(comments[0].String())
+		comments = comments[1:]
+	}
+	return comments
+}
+
+func (p *printer) dangling(comments []*codf.Comment, depth int) {
+	for _, c := range comments {
+		p.newline()
+		p.indent(depth)
+		p.writeString(c.String())
+	}
+}
+
+// flat returns node written on a single line. It returns false if node contains comments and
+// cannot be written on a single line.
+func (p *printer) flat(node codf.ExprNode) (string, bool) {
+	switch node := node.(type) {
+	case *codf.Literal:
+		return literalText(node), true
+
+	case *codf.Array:
+		if len(node.Comments.Dangling) > 0 {
+			return "", false
+		}
+		elems := make([]string, len(node.Elems))
+		for i, elem := range node.Elems {
+			str, ok := p.flat(elem)
+			if !ok {
+				return "", false
+			}
+			elems[i] = str
+		}
+		return "[" + strings.Join(elems, " ") + "]", true
+
+	case *codf.Map:
+		if len(node.Comments.Dangling) > 0 {
+			return "", false
+		}
+		pairs := node.Pairs()
+		elems := make([]string, 0, len(pairs)*2)
+		for _, entry := range pairs {
+			key, _ := p.flat(entry.Key)
+			val, ok := p.flat(entry.Val)
+			if !ok {
+				return "", false
+			}
+			elems = append(elems, key, val)
+		}
+		return "#{" + strings.Join(elems, " ") + "}", true
+	}
+	return string(node.Token().Raw), true
+}
+
+// nameText returns the text of a statement or section's name, which is a quoted string if it was
+// parsed with codf.ParseQuotedNames.
+func nameText(node codf.Node) string {
+	switch node := node.(type) {
+	case *codf.Statement:
+		return literalText(node.NameTok)
+	case *codf.Section:
+		return literalText(node.NameTok)
+	}
+	return ""
+}
+
+// literalText returns the text of a literal. Literals without Raw text, such as those built by
+// hand, are written using the codf constructors for their value.
+func literalText(lit *codf.Literal) string {
+	if len(lit.Tok.Raw) > 0 {
+		return string(lit.Tok.Raw)
+	}
+
+	var synth *codf.Literal
+	switch v := lit.Value().(type) {
+	case string:
+		if lit.Tok.Kind == codf.TWord {
+			synth = codf.NewWord(v)
+		} else {
+			synth = codf.NewString(v)
+		}
+	case bool:
+		synth = codf.NewBool(v)
+	case *big.Int:
+		if lit.Tok.Kind == codf.TByteSize {
+			synth = codf.NewByteSize(v)
+		} else {
+			synth = codf.NewBigInt(v)
+		}
+	case *big.Float:
+		synth = codf.NewBigFloat(v)
+	case *big.Rat:
+		synth = codf.NewRat(v)
+	case time.Duration:
+		synth = codf.NewDuration(v)
+	case codf.Period:
+		synth = codf.NewPeriod(v)
+	case netip.Addr:
+		synth = codf.NewAddr(v)
+	case netip.Prefix:
+		synth = codf.NewPrefix(v)
+	case netip.AddrPort:
+		synth = codf.NewAddrPort(v)
+	case time.Time:
+		if lit.Tok.Kind == codf.TDate {
+			synth = codf.NewDate(v)
+		} else {
+			synth = codf.NewTime(v)
+		}
+	case *regexp.Regexp:
+		synth = codf.NewRegexp(v)
+	}
+	if synth == nil {
+		return ""
+	}
+	return string(synth.Tok.Raw)
+}
+
+// flatten replaces documents in children (such as included files) with their own children.
+func flatten(children []codf.Node) []codf.Node {
+	flat := children[:0:0]
+	for _, child := range children {
+		switch child := child.(type) {
+		case nil:
+		case *codf.Document:
+			flat = append(flat, flatten(child.Children)...)
+		default:
+			flat = append(flat, child)
+		}
+	}
+	return flat
+}
+
+// startLine returns the first line of node or its leading comments, or 0 if it is not known.
+func startLine(node codf.Node) int {
+	var leading []*codf.Comment
+	switch node := node.(type) {
+	case *codf.Statement:
+		leading = node.Comments.Leading
+	case *codf.Section:
+		leading = node.Comments.Leading
+	}
+	if len(leading) > 0 && leading[0].Tok.Start.Line > 0 {
+		return leading[0].Tok.Start.Line
+	}
+	return node.Token().Start.Line
+}
+
+// endLine returns the last line of node or its trailing comment, or 0 if it is not known.
+func endLine(node codf.Node) int {
+	switch node := node.(type) {
+	case *codf.Statement:
+		return node.EndTok.Start.Line
+	case *codf.Section:
+		return node.EndTok.Start.Line
+	case *codf.Array:
+		return node.EndTok.Start.Line
+	case *codf.Map:
+		return node.EndTok.Start.Line
+	case *codf.Literal:
+		return node.Tok.End.Line
+	}
+	return 0
+}
+
+// blankBefore returns true if there were blank lines between node and a previous node ending on
+// the line prevEnd.
+func blankBefore(node codf.Node, prevEnd int) bool {
+	return prevEnd > 0 && startLine(node) > prevEnd+1
+}
+
+// width returns the number of columns s occupies.
+func width(s string) int {
+	n := utf8.RuneCountInString(s)
+	return n + strings.Count(s, "\t")*(TabWidth-1)
+}
@@ -0,0 +1,234 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/3JoB/codf"
+)
+
+func TestSource(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+		src  string
+		want string
+	}{
+		{
+			name: "Empty",
+			src:  "\n\n",
+			want: "",
+		},
+		{
+			name: "Basic",
+			src: `server   go.spiff.io {listen 0.0.0.0:80;
+
+
+    listen 0.0.0.0:443;
+  proxy   unix:///var/run/go-redirect.sock { strip-x-headers yes; }
+  empty {
+  }
+}`,
+			want: "server go.spiff.io {\n" +
+				"\tlisten 0.0.0.0:80;\n" +
+				"\n" +
+				"\tlisten 0.0.0.0:443;\n" +
+				"\tproxy unix:///var/run/go-redirect.sock {\n" +
+				"\t\tstrip-x-headers yes;\n" +
+				"\t}\n" +
+				"\tempty {}\n" +
+				"}\n",
+		},
+		{
+			name: "Comments",
+			src: `// header
+
+foo 1; // trailing
+// dangling 1
+
+  // dangling 2
+`,
+			want: "// header\n" +
+				"\n" +
+				"foo 1; // trailing\n" +
+				"// dangling 1\n" +
+				"\n" +
+				"// dangling 2\n",
+		},
+		{
+			name: "KeepRawText",
+			src:  `n 0x1F 1.50 "a\x41" ` + "`raw`" + ` #/a\/b/ 1h30m 3/6;`,
+			want: `n 0x1F 1.50 "a\x41" ` + "`raw`" + ` #/a\/b/ 1h30m 3/6;` + "\n",
+		},
+		{
+			name: "KeepHeredoc",
+			src:  "s {\n    query <<SQL\n        SELECT 1\n        SQL    ;\n}",
+			want: "s {\n\tquery <<SQL\n        SELECT 1\n        SQL;\n}\n",
+		},
+		{
+			name: "InlineCompounds",
+			src: `headers #{
+	x-foo bar
+	x-bar [1 2
+		3]
+} [];`,
+			want: "headers #{x-foo bar x-bar [1 2 3]} [];\n",
+		},
+		{
+			name: "CompoundComments",
+			src:  "ary [1 // one\n2];",
+			want: "ary [\n\t1 // one\n\t2\n];\n",
+		},
+		{
+			name: "ArrayComments",
+			src:  "ary [ // first\na\n// before b\nb [c // c\n] d\n// last\n];",
+			want: "ary [\n" +
+				"\t// first\n" +
+				"\ta\n" +
+				"\t// before b\n" +
+				"\tb\n" +
+				"\t[\n" +
+				"\t\tc // c\n" +
+				"\t]\n" +
+				"\td\n" +
+				"\t// last\n" +
+				"];\n",
+		},
+		{
+			name: "MapComments",
+			src:  "m #{a 1 // one\n// before b\nb 2};",
+			want: "m #{\n\ta 1 // one\n\t// before b\n\tb 2\n};\n",
+		},
+		{
+			name: "Spaces",
+			cfg:  Config{UseSpaces: true, Indent: 2},
+			src:  "a { b { c; } }",
+			want: "a {\n  b {\n    c;\n  }\n}\n",
+		},
+		{
+			name: "DefaultSpaces",
+			cfg:  Config{UseSpaces: true},
+			src:  "a { b; }",
+			want: "a {\n    b;\n}\n",
+		},
+		{
+			name: "AlignParams",
+			cfg:  Config{AlignParams: true},
+			src: `listen 80;
+control unix:///x.sock;
+x 1;
+noparams;
+a 1;
+long-name 2;
+
+after-blank 3;
+b 4;
+// comment
+cc 5;
+sect {}
+d 6;`,
+			want: "listen  80;\n" +
+				"control unix:///x.sock;\n" +
+				"x       1;\n" +
+				"noparams;\n" +
+				"a         1;\n" +
+				"long-name 2;\n" +
+				"\n" +
+				"after-blank 3;\n" +
+				"b           4;\n" +
+				"// comment\n" +
+				"cc 5;\n" +
+				"sect {}\n" +
+				"d 6;\n",
+		},
+		{
+			name: "MaxWidthCompounds",
+			cfg:  Config{MaxWidth: 20, UseSpaces: true},
+			src:  `s { m #{a 1 b [one two three]}; short [1 2]; }`,
+			want: "s {\n" +
+				"    m #{\n" +
+				"        a 1\n" +
+				"        b [\n" +
+				"            one\n" +
+				"            two\n" +
+				"            three\n" +
+				"        ]\n" +
+				"    };\n" +
+				"    short [1 2];\n" +
+				"}\n",
+		},
+		{
+			name: "MaxWidthParams",
+			cfg:  Config{MaxWidth: 16, UseSpaces: true, Indent: 2},
+			src:  `names alpha beta gamma delta;`,
+			want: "names alpha beta\n  gamma delta;\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.cfg.Source([]byte(c.src))
+			if err != nil {
+				t.Fatalf("Source() error = %v; want nil", err)
+			}
+			if string(got) != c.want {
+				t.Fatalf("Source() =\n%s\nwant\n%s", got, c.want)
+			}
+
+			again, err := c.cfg.Source(got)
+			if err != nil {
+				t.Fatalf("Source(Source()) error = %v; want nil", err)
+			}
+			if !bytes.Equal(again, got) {
+				t.Errorf("Source(Source()) =\n%s\nwant\n%s", again, got)
+			}
+		})
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	if _, err := Source([]byte("foo {")); err == nil {
+		t.Fatal("Source() error = nil; want error")
+	}
+}
+
+func TestFprintSynthesized(t *testing.T) {
+	doc := &codf.Document{
+		Children: []codf.Node{
+			codf.NewSection("server", []codf.ExprNode{codf.NewWord("example.com")},
+				codf.NewStatement("timeout", codf.NewDuration(5*time.Second)),
+			),
+			codf.NewStatement("bare", &codf.Literal{Tok: codf.Token{Kind: codf.TString, Value: "a b"}}),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Fprint(&buf, doc); err != nil {
+		t.Fatalf("Fprint() error = %v; want nil", err)
+	}
+	const want = "server example.com {\n\ttimeout 5s;\n}\nbare \"a b\";\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Fprint() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFprintQuotedNames(t *testing.T) {
+	l := codf.NewLexer(strings.NewReader("map $http_upgrade $c {\n  default upgrade;\n  '' close;\n  \"~*bot\" 1;\n}\n"))
+	l.Flags = codf.LexNginx
+	p := codf.NewParser()
+	p.Flags = codf.ParseQuotedNames
+	if err := p.Parse(l); err != nil {
+		t.Fatalf("Parse() error = %v; want nil", err)
+	}
+
+	var buf bytes.Buffer
+	if err := (&Config{AlignParams: true}).Fprint(&buf, p.Document()); err != nil {
+		t.Fatalf("Fprint() error = %v; want nil", err)
+	}
+	const want = "map $http_upgrade $c {\n\tdefault upgrade;\n\t''      close;\n\t\"~*bot\" 1;\n}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Fprint() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,388 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Selector is a compiled query that selects nodes from a document. A Selector is safe for
+// concurrent use.
+//
+// A query is a sequence of steps separated by slashes. Each step selects nodes relative to the
+// nodes selected by the previous step, beginning with the root node passed to Select:
+//
+//	name      Statements and sections named name. In a map, the value of the key name. In an
+//	          array, the element at index name.
+//	"name"    As above, for names containing spaces, slashes, or brackets.
+//	*         All statements and sections, map values, or array elements.
+//	@N        Parameter N of a statement or section, counting from 0.
+//	@*        All parameters of a statement or section.
+//
+// A step preceded by two slashes instead of one is a recursive step: it selects nodes from the
+// current nodes and all of their descendants. Parameters are not descendants of the statements
+// and sections they belong to, so a recursive step does not select parameters or their elements
+// unless the step is itself a parameter step.
+//
+// Each step may be followed by predicates in brackets that filter the nodes it selects from each
+// node:
+//
+//	[N]        The Nth node selected, counting from 0. Negative indexes count from the last node.
+//	[value]    Nodes whose first parameter is value.
+//	[N=value]  Nodes whose Nth parameter is value.
+//
+// A parameter is equal to a value if it is a string or word with that value or if its source
+// text is the value. Values that are integers are indexes unless given as [0=value], and values
+// containing spaces, brackets, or an equals sign must be quoted. Predicates are applied in order,
+// so "listen[0=80][0]" selects the first listen statement whose parameter is 80, while
+// "listen[0][0=80]" selects the first listen statement only if its parameter is 80. For example:
+//
+//	server[go.spiff.io]/proxy    // All proxy statements in the server go.spiff.io section.
+//	//location[0]                // The first location section in each section.
+//	upstream/*/@0                // The first parameter of each child of upstream sections.
+//	map/@0/key                   // The value of key in the map parameter of map statements.
+//
+// Nodes are selected in document order and each node is selected at most once. Documents
+// nested in other documents, such as included files, are treated as part of their parents.
+type Selector struct {
+	query string
+	steps []*queryStep
+}
+
+// Query compiles query and returns the nodes it selects from root. It is a shorthand for
+// CompileSelector followed by Select.
+func Query(root Node, query string) ([]Node, error) {
+	sel, err := CompileSelector(query)
+	if err != nil {
+		return nil, err
+	}
+	return sel.Select(root), nil
+}
+
+// CompileSelector compiles query to a Selector. If query is not valid, it returns a
+// *SelectorError. See Selector for the query syntax.
+func CompileSelector(query string) (*Selector, error) {
+	qp := queryParser{query: query}
+	steps, err := qp.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Selector{query: query, steps: steps}, nil
+}
+
+// MustCompileSelector is like CompileSelector but panics if query is not valid.
+func MustCompileSelector(query string) *Selector {
+	sel, err := CompileSelector(query)
+	if err != nil {
+		panic(err)
+	}
+	return sel
+}
+
+// String returns the query the Selector was compiled from.
+func (s *Selector) String() string {
+	return s.query
+}
+
+// Select returns the nodes selected from root, or nil if no nodes are selected.
+func (s *Selector) Select(root Node) []Node {
+	nodes := []Node{root}
+	for _, step := range s.steps {
+		nodes = step.selectFrom(nodes)
+		if len(nodes) == 0 {
+			return nil
+		}
+	}
+	return nodes
+}
+
+// SelectorError is returned by CompileSelector and Query for a query that is not valid.
+type SelectorError struct {
+	// Query is the query that is not valid.
+	Query string
+
+	// Offset is the byte offset in Query at which the error occurred.
+	Offset int
+
+	// Msg is a message describing the error.
+	Msg string
+}
+
+func (e *SelectorError) Error() string {
+	return "invalid selector " + strconv.Quote(e.Query) + " at offset " + strconv.Itoa(e.Offset) + ": " + e.Msg
+}
+
+// queryStep is a single step of a Selector.
+type queryStep struct {
+	recursive bool
+	param     bool // Select parameters instead of children.
+	name      string
+	any       bool // Select nodes of any name.
+	preds     []queryPred
+}
+
+// queryPred is a predicate in a queryStep. If param is negative, the predicate selects the node
+// at index; otherwise, it selects nodes whose parameter at index param is value.
+type queryPred struct {
+	param int
+	index int
+	value string
+}
+
+// namedNode is a node selected by a step along with the name a step matches against.
+type namedNode struct {
+	name string
+	node Node
+}
+
+func (q *queryStep) selectFrom(nodes []Node) []Node {
+	var out []Node
+	seen := map[Node]bool{}
+	var visit func(node Node)
+	visit = func(node Node) {
+		var candidates []namedNode
+		if q.param {
+			candidates = queryParams(node)
+		} else {
+			candidates = queryChildren(node)
+		}
+
+		var matched []Node
+		for _, c := range candidates {
+			if q.any || c.name == q.name {
+				matched = append(matched, c.node)
+			}
+		}
+		for _, pred := range q.preds {
+			matched = pred.filter(matched)
+		}
+		for _, node := range matched {
+			if !seen[node] {
+				seen[node] = true
+				out = append(out, node)
+			}
+		}
+
+		if q.recursive {
+			for _, c := range queryChildren(node) {
+				visit(c.node)
+			}
+		}
+	}
+	for _, node := range nodes {
+		visit(node)
+	}
+	return out
+}
+
+func (p queryPred) filter(nodes []Node) []Node {
+	if p.param < 0 {
+		i := p.index
+		if i < 0 {
+			i += len(nodes)
+		}
+		if i < 0 || i >= len(nodes) {
+			return nil
+		}
+		return nodes[i : i+1]
+	}
+
+	var out []Node
+	for _, node := range nodes {
+		pn, ok := node.(ParamNode)
+		if !ok {
+			continue
+		}
+		params := pn.Parameters()
+		if p.param < len(params) && queryValueEqual(params[p.param], p.value) {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// queryValueEqual returns whether expr is a string or word equal to value or has the source text
+// value.
+func queryValueEqual(expr ExprNode, value string) bool {
+	if s, ok := String(expr); ok {
+		return s == value
+	}
+	return string(expr.Token().Raw) == value
+}
+
+// queryChildren returns the children of node that a step may select.
+func queryChildren(node Node) []namedNode {
+	var children []namedNode
+	switch node := node.(type) {
+	case *Document, *Section:
+		var add func(parent ParentNode)
+		add = func(parent ParentNode) {
+			for _, child := range parent.Nodes() {
+				switch child := child.(type) {
+				case *Document:
+					add(child)
+				case ParamNode:
+					children = append(children, namedNode{child.Name(), child})
+				}
+			}
+		}
+		add(node.(ParentNode))
+	case *Array:
+		for i, elem := range node.Elems {
+			children = append(children, namedNode{strconv.Itoa(i), elem})
+		}
+	case *Map:
+		for _, pair := range node.Pairs() {
+			children = append(children, namedNode{pair.Name(), pair.Val})
+		}
+	}
+	return children
+}
+
+// queryParams returns the parameters of node if it is a statement or section.
+func queryParams(node Node) []namedNode {
+	pn, ok := node.(ParamNode)
+	if !ok {
+		return nil
+	}
+	params := pn.Parameters()
+	named := make([]namedNode, len(params))
+	for i, p := range params {
+		named[i] = namedNode{strconv.Itoa(i), p}
+	}
+	return named
+}
+
+// queryParser compiles a query to a sequence of steps.
+type queryParser struct {
+	query string
+	pos   int
+}
+
+func (qp *queryParser) errorf(offset int, msg string) error {
+	return &SelectorError{Query: qp.query, Offset: offset, Msg: msg}
+}
+
+func (qp *queryParser) parse() ([]*queryStep, error) {
+	if qp.query == "" {
+		return nil, qp.errorf(0, "empty query")
+	}
+
+	var steps []*queryStep
+	first := true
+	for qp.pos < len(qp.query) || first {
+		step := &queryStep{}
+		switch {
+		case strings.HasPrefix(qp.query[qp.pos:], "//"):
+			step.recursive = true
+			qp.pos += 2
+		case strings.HasPrefix(qp.query[qp.pos:], "/"):
+			qp.pos++
+		case !first:
+			return nil, qp.errorf(qp.pos, "expected '/'")
+		}
+		first = false
+
+		if err := qp.parseStep(step); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func (qp *queryParser) parseStep(step *queryStep) error {
+	if strings.HasPrefix(qp.query[qp.pos:], "@") {
+		qp.pos++
+		step.param = true
+	}
+
+	start := qp.pos
+	name, quoted, err := qp.parseName("/[")
+	if err != nil {
+		return err
+	}
+	switch {
+	case name == "" && !quoted:
+		if qp.pos == len(qp.query) {
+			return qp.errorf(qp.pos, "expected a name at end of query")
+		}
+		return qp.errorf(qp.pos, "expected a name")
+	case name == "*" && !quoted:
+		step.any = true
+	case step.param:
+		if _, err := strconv.Atoi(name); err != nil || quoted {
+			return qp.errorf(start, "expected a parameter index or '*' after '@'")
+		}
+	}
+	step.name = name
+
+	for strings.HasPrefix(qp.query[qp.pos:], "[") {
+		qp.pos++
+		pred, err := qp.parsePred()
+		if err != nil {
+			return err
+		}
+		step.preds = append(step.preds, pred)
+	}
+	return nil
+}
+
+func (qp *queryParser) parsePred() (queryPred, error) {
+	start := qp.pos
+	value, quoted, err := qp.parseName("]=")
+	if err != nil {
+		return queryPred{}, err
+	}
+
+	pred := queryPred{param: -1}
+	switch {
+	case strings.HasPrefix(qp.query[qp.pos:], "="):
+		qp.pos++
+		index, err := strconv.Atoi(value)
+		if err != nil || quoted || index < 0 {
+			return pred, qp.errorf(start, "expected a parameter index before '='")
+		}
+		pred.param = index
+		if pred.value, _, err = qp.parseName("]"); err != nil {
+			return pred, err
+		}
+	case value == "" && !quoted:
+		return pred, qp.errorf(qp.pos, "expected an index or value")
+	default:
+		if index, err := strconv.Atoi(value); err == nil && !quoted {
+			pred.index = index
+		} else {
+			pred.param, pred.value = 0, value
+		}
+	}
+
+	if !strings.HasPrefix(qp.query[qp.pos:], "]") {
+		return pred, qp.errorf(qp.pos, "expected ']'")
+	}
+	qp.pos++
+	return pred, nil
+}
+
+// parseName reads a name, which is either a double-quoted string or a run of characters other
+// than spaces and the characters in stop. quoted is true if the name was quoted.
+func (qp *queryParser) parseName(stop string) (name string, quoted bool, err error) {
+	rest := qp.query[qp.pos:]
+	if strings.HasPrefix(rest, `"`) {
+		quote, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return "", true, qp.errorf(qp.pos, "unterminated or invalid quoted string")
+		}
+		qp.pos += len(quote)
+		name, _ = strconv.Unquote(quote)
+		return name, true, nil
+	}
+
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '"' || strings.ContainsRune(stop, r)
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	qp.pos += end
+	return rest[:end], false, nil
+}
@@ -0,0 +1,167 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const querySource = `
+user http;
+http {
+	server go.spiff.io {
+		listen 80;
+		listen 443 ssl;
+		proxy /api http://localhost:8080;
+		proxy /static http://localhost:8081;
+		location / {
+			root [/srv/www /srv/alt];
+		}
+	}
+	server "example.com" {
+		listen 8080;
+		proxy / http://localhost:9090;
+		headers #{ X-Frame-Options deny "Cache Control" [no-store private] };
+	}
+}
+`
+
+func TestQuery(t *testing.T) {
+	doc := mustParse(t, querySource)
+
+	cases := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"Name", "user", []string{"user http;"}},
+		{"LeadingSlash", "/user/@0", []string{"http"}},
+		{"Nested", "http/server/listen", []string{
+			"listen 80;", "listen 443 ssl;", "listen 8080;",
+		}},
+		{"Param", "http/server[go.spiff.io]/proxy", []string{
+			"proxy /api http://localhost:8080;",
+			"proxy /static http://localhost:8081;",
+		}},
+		{"QuotedParam", `http/server["example.com"]/listen/@0`, []string{"8080"}},
+		{"NthParam", "//listen[1=ssl]", []string{"listen 443 ssl;"}},
+		{"NumericParam", "//listen[0=443]/@*", []string{"443", "ssl"}},
+		{"Index", "http/server/listen[0]", []string{"listen 80;", "listen 8080;"}},
+		{"NegativeIndex", "http/server/proxy[-1]/@1", []string{
+			"http://localhost:8081", "http://localhost:9090",
+		}},
+		{"IndexOutOfRange", "http/server/listen[2]", nil},
+		{"PredicateOrder", "http/server/listen[1][0=443]", []string{"listen 443 ssl;"}},
+		{"PredicateOrderReversed", "http/server/listen[0=443][1]", nil},
+		{"Wildcard", "http/*[go.spiff.io]/*", []string{
+			"listen 80;", "listen 443 ssl;",
+			"proxy /api http://localhost:8080;",
+			"proxy /static http://localhost:8081;",
+			"location / {\n\troot [/srv/www /srv/alt];\n}",
+		}},
+		{"Recursive", "//root", []string{"root [/srv/www /srv/alt];"}},
+		{"RecursiveIndex", "//proxy[0]/@0", []string{"/api", "/"}},
+		{"RecursiveFromStep", "http//location[/]/root", []string{"root [/srv/www /srv/alt];"}},
+		{"ArrayElement", "//root/@0/1", []string{"/srv/alt"}},
+		{"ArrayWildcard", "//root/@0/*", []string{"/srv/www", "/srv/alt"}},
+		{"MapValue", "//headers/@0/X-Frame-Options", []string{"deny"}},
+		{"QuotedMapKey", `//headers/@0/"Cache Control"/0`, []string{"no-store"}},
+		{"MapWildcard", "//headers/@0/*", []string{"deny", "[no-store private]"}},
+		{"NoParams", "user/@1", nil},
+		{"NoMatch", "http/server/missing", nil},
+		{"Dedup", "//server//listen", []string{
+			"listen 80;", "listen 443 ssl;", "listen 8080;",
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			nodes, err := Query(doc, c.query)
+			if err != nil {
+				t.Fatalf("Query(%q) error = %v", c.query, err)
+			}
+			var got []string
+			for _, node := range nodes {
+				got = append(got, node.format(""))
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Query(%q) =\n%s\nwant\n%s", c.query, joinLines(got), joinLines(c.want))
+			}
+		})
+	}
+}
+
+func TestQueryIncludes(t *testing.T) {
+	doc := mustParse(t, "a 1;")
+	doc.Children = append(doc.Children, mustParse(t, "a 2; b { a 3; }"))
+
+	nodes, err := Query(doc, "//a/@0")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	var got []int64
+	for _, node := range nodes {
+		n, _ := Int64(node)
+		got = append(got, n)
+	}
+	if want := []int64{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Query() = %v; want %v", got, want)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	sel := MustCompileSelector("//listen/@0")
+	if got, want := sel.String(), "//listen/@0"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+
+	for _, src := range []string{"listen 1;", "a { listen 1; } b { c { listen 2; } }"} {
+		doc := mustParse(t, src)
+		for _, node := range sel.Select(doc) {
+			if _, ok := Int64(node); !ok {
+				t.Errorf("Select() node %v is not an integer", node)
+			}
+		}
+	}
+
+	if got := sel.Select(mustParse(t, "")); got != nil {
+		t.Errorf("Select() = %v; want nil", got)
+	}
+}
+
+func TestCompileSelectorErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		msg   string
+	}{
+		{"", `invalid selector "" at offset 0: empty query`},
+		{"/", `invalid selector "/" at offset 1: expected a name at end of query`},
+		{"a/", `invalid selector "a/" at offset 2: expected a name at end of query`},
+		{"a//[0]", `invalid selector "a//[0]" at offset 3: expected a name`},
+		{"a b", `invalid selector "a b" at offset 1: expected '/'`},
+		{"a[0", `invalid selector "a[0" at offset 3: expected ']'`},
+		{"a[]", `invalid selector "a[]" at offset 2: expected an index or value`},
+		{"a[x=1]", `invalid selector "a[x=1]" at offset 2: expected a parameter index before '='`},
+		{"a/@x", `invalid selector "a/@x" at offset 3: expected a parameter index or '*' after '@'`},
+		{`a["b]`, `invalid selector "a[\"b]" at offset 2: unterminated or invalid quoted string`},
+	}
+
+	for _, c := range cases {
+		_, err := CompileSelector(c.query)
+		var se *SelectorError
+		if !errors.As(err, &se) {
+			t.Errorf("CompileSelector(%q) = %v; want *SelectorError", c.query, err)
+			continue
+		}
+		if got := err.Error(); got != c.msg {
+			t.Errorf("CompileSelector(%q) = %q; want %q", c.query, got, c.msg)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompileSelector() did not panic")
+		}
+	}()
+	MustCompileSelector("a[")
+}
@@ -0,0 +1,197 @@
+package codf // import "go.spiff.io/codf"
+
+import "strings"
+
+// ErrorList is a list of errors returned by Parse when parsing with ParseRecover. Its errors are in
+// the order they were encountered.
+type ErrorList []error
+
+// Error returns the messages of the errors in the list, one per line.
+func (e ErrorList) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors in the list, for use with errors.Is and errors.As.
+func (e ErrorList) Unwrap() []error {
+	return e
+}
+
+// parseRecover implements Parse for the ParseRecover flag.
+func (p *Parser) parseRecover(tr TokenReader) error {
+	p.errs = nil
+	var setDocName bool
+	if p.next == nil {
+		setDocName = p.doc.Name == ""
+		p.next = p.beginSegment
+	}
+
+	var readErr error
+	for p.next != nil {
+		tok, err := p.nextToken(tr)
+		if err != nil {
+			if readErr != nil {
+				// The TokenReader is not making progress, so the parser cannot resume. A reader
+				// that is stuck usually returns the same error again, which is only recorded once.
+				if err != readErr && err.Error() != readErr.Error() {
+					p.errs = append(p.errs, err)
+				}
+				p.parseErr = p.errs
+				return p.errs
+			}
+			// Discard the statement that the token would have been a part of.
+			p.next = p.recoverFrom(tok, err, true)
+			readErr = err
+			continue
+		}
+		readErr = nil
+
+		if setDocName {
+			p.doc.Name = tok.Start.Name
+			setDocName = false
+		}
+
+		depth := len(p.ctx)
+		if p.next, err = p.next(tok); err != nil {
+			p.next = p.recoverFrom(tok, err, len(p.ctx) < depth)
+		}
+	}
+
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return p.errs
+}
+
+// recoverFrom records err, returned when the parser consumed tok, and returns a tokenConsumer that
+// discards tokens until parsing can resume at the end of the statement or section in which the
+// error occurred. The statement being parsed is discarded, unless it is followed by '{', in which
+// case it is kept as a section. consumed is true if tok closed the array or map that caused err.
+func (p *Parser) recoverFrom(tok Token, err error, consumed bool) tokenConsumer {
+	p.errs = append(p.errs, err)
+	p.prev = nil
+	if tok.Kind == TEOF {
+		p.recoverEOF()
+		return nil
+	}
+
+	// Unwind to the nearest document or section, keeping the statement being parsed and the
+	// arrays and maps it was nested in at the time of the error.
+	var stmt *Statement
+	var open []TokenKind
+unwind:
+	for {
+		switch ctx := p.context().(type) {
+		case *Statement:
+			stmt = ctx
+		case *Array:
+			open = append([]TokenKind{TBracketOpen}, open...)
+		case *mapBuilder:
+			open = append([]TokenKind{TMapOpen}, open...)
+		default:
+			break unwind
+		}
+		p.popContext()
+	}
+
+	next := p.resync(stmt, open)
+	if consumed {
+		return next
+	}
+	next, err = next(tok)
+	if err != nil {
+		return p.recoverFrom(tok, err, true)
+	}
+	return next
+}
+
+// resync returns a tokenConsumer that discards tokens until the end of a statement or section. open
+// holds the kinds of the arrays, maps, and blocks that are open but discarded, innermost last.
+func (p *Parser) resync(stmt *Statement, open []TokenKind) (consumer tokenConsumer) {
+	// closeTo pops open to the innermost of kinds and returns the kind closed, or tEmpty if none
+	// of kinds are open.
+	closeTo := func(kinds ...TokenKind) TokenKind {
+		for i := len(open) - 1; i >= 0; i-- {
+			for _, k := range kinds {
+				if open[i] == k {
+					open = open[:i]
+					return k
+				}
+			}
+		}
+		return tEmpty
+	}
+	inBlock := func() bool {
+		for _, k := range open {
+			if k == TCurlOpen {
+				return true
+			}
+		}
+		return false
+	}
+
+	consumer = func(tok Token) (tokenConsumer, error) {
+		switch tok.Kind {
+		case TEOF:
+			return p.beginSegment(tok)
+		case TBracketOpen, TMapOpen:
+			open = append(open, tok.Kind)
+		case TBracketClose:
+			closeTo(TBracketOpen)
+		case TCurlOpen:
+			if stmt != nil && !inBlock() {
+				// The statement was a section: keep it and parse its body.
+				sect := stmt.promote()
+				sect.StartTok = tok
+				p.pushContext(sect)
+				return p.beginSegment, nil
+			}
+			open = append(open, TCurlOpen)
+		case TCurlClose:
+			switch closeTo(TMapOpen, TCurlOpen) {
+			case tEmpty:
+				// Nothing discarded is open, so this closes the current section, if there is one.
+				if _, ok := p.context().(*Section); ok {
+					return p.beginSegment(tok)
+				}
+				return p.beginSegment, nil
+			case TCurlOpen:
+				if !inBlock() {
+					return p.beginSegment, nil
+				}
+			}
+		case TSemicolon:
+			if !inBlock() {
+				return p.beginSegment, nil
+			}
+		}
+		return consumer, nil
+	}
+	return consumer
+}
+
+// recoverEOF ends parsing after an error at EOF. Sections that have not been closed are added to
+// their parents and anything else being parsed is discarded.
+func (p *Parser) recoverEOF() {
+	for {
+		switch ctx := p.context().(type) {
+		case *Document:
+			ctx.Comments.Dangling = p.takeComments(ctx.Comments.Dangling)
+			return
+		case *contextParser:
+			ctx.dangling = p.takeComments(ctx.dangling)
+			return
+		case *exprParser:
+			return
+		case *Section:
+			p.popContext()
+			ctx.Comments.Dangling = p.takeComments(ctx.Comments.Dangling)
+			p.context().(parentNode).addChild(ctx)
+		default:
+			p.popContext()
+		}
+	}
+}
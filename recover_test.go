@@ -0,0 +1,176 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func parseRecover(in string) (*Document, error) {
+	p := NewParser()
+	p.Flags = ParseRecover
+	err := p.Parse(NewLexer(strings.NewReader(in)))
+	return p.Document(), err
+}
+
+func TestParseRecover(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+		errs []string
+	}{
+		{
+			name: "NoErrors",
+			in:   "a 1; b { c; }",
+			want: "a 1; b { c; }",
+		},
+		{
+			name: "Statements",
+			in:   "a 1;\nb [1;\nc 2;\n}\nd #{1 2};\ne 3;",
+			want: "a 1; c 2; e 3;",
+			errs: []string{
+				"[2:5:9] unexpected semicolon: expected end of array beginning at 2:3:7",
+				"[4:1:16] unexpected close brace: expected statement, section, or EOF",
+				"[5:5:22] unexpected integer: bad key; expected word or string",
+			},
+		},
+		{
+			name: "Sections",
+			in:   "a {\n\tb ];\n\tc 1 }\nd { e; }\nf [ { g; }\nh;",
+			want: "a {} d { e; } f { g; } h;",
+			errs: []string{
+				"[2:4:7] unexpected close bracket: expected end of statement \"b\" beginning at 2:2:5",
+				"[3:6:15] unexpected close brace: expected end of statement \"c\" beginning at 3:2:11",
+				"[5:5:30] unexpected open brace: expected end of array beginning at 5:3:28",
+			},
+		},
+		{
+			name: "NestedMaps",
+			in:   "a #{ k #{ x } };\nb { #{k v} } c;\nd;",
+			want: "b {} c; d;",
+			errs: []string{
+				"[1:13:12] unexpected close brace: expected value for key \"x\" at 1:11:10",
+				"[2:5:21] unexpected map: expected statement or section name",
+			},
+		},
+		{
+			name: "ClosedMapAsKey",
+			in:   "a #{ #{} 1 } { b; }\nc;",
+			want: "a { b; } c;",
+			errs: []string{
+				"[1:6:5] unexpected map: bad key; expected word or string",
+			},
+		},
+		{
+			name: "Block",
+			in:   "\"x\" { a; b { c; } }\nd;",
+			want: "d;",
+			errs: []string{
+				"[1:1:0] unexpected string: expected statement or section name",
+			},
+		},
+		{
+			name: "EOF",
+			in:   "a { b { c 1; d [",
+			want: "a { b { c 1; } }",
+			errs: []string{
+				"[1:17:16] unexpected EOF: expected end of array beginning at 1:16:15",
+			},
+		},
+		{
+			name: "LexerError",
+			in:   "a 1;\nb 0x;\nc 2;\nd;",
+			want: "a 1; c 2; d;",
+			errs: []string{
+				"[2:5:9] unexpected character ';': expected hex digit",
+			},
+		},
+		{
+			name: "UnterminatedString",
+			in:   "a \"unterminated\nb 1;",
+			want: "",
+			errs: []string{
+				"[2:5:20] unexpected EOF: expected close of string",
+			},
+		},
+		{
+			name: "StringError",
+			in:   `a "\q"; b 2; c 0x; d 4;`,
+			want: "b 2; d 4;",
+			errs: []string{
+				"[1:5:4] invalid escape character 'q'",
+				"[1:18:17] unexpected character ';': expected hex digit",
+			},
+		},
+		{
+			name: "StringEscapeError",
+			in:   `a "\x" "\u12\"x"; b { c "\xZ\\" 1; } d;`,
+			want: "b {} d;",
+			errs: []string{
+				"[1:6:5] unexpected character '\"': expected hex digit",
+				"[1:13:12] unexpected character '\\\\': expected hex digit",
+				"[1:28:27] unexpected character 'Z': expected hex digit",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := parseRecover(c.in)
+			objectsEqual(t, "", doc, mustParse(t, c.want))
+
+			var got []string
+			if err != nil {
+				var list ErrorList
+				if !errors.As(err, &list) {
+					t.Fatalf("Parse() error = %v; want ErrorList", err)
+				}
+				for _, err := range list {
+					got = append(got, err.Error())
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(c.errs, "\n") {
+				t.Errorf("Parse() errors =\n%s\nwant\n%s", joinLines(got), joinLines(c.errs))
+			}
+		})
+	}
+}
+
+type errReader struct {
+	toks []Token
+	err  error
+}
+
+func (r *errReader) ReadToken() (Token, error) {
+	if len(r.toks) == 0 {
+		return Token{}, r.err
+	}
+	tok := r.toks[0]
+	r.toks = r.toks[1:]
+	return tok, nil
+}
+
+func TestParseRecoverReaderError(t *testing.T) {
+	readErr := errors.New("read error")
+	p := NewParser()
+	p.Flags = ParseRecover
+	tr := &errReader{
+		toks: []Token{{Kind: TWord, Value: "a"}, {Kind: TSemicolon}},
+		err:  readErr,
+	}
+
+	err := p.Parse(tr)
+	var list ErrorList
+	// The reader returns the same error twice, which is only recorded once.
+	if !errors.As(err, &list) || len(list) != 1 || !errors.Is(err, readErr) {
+		t.Fatalf("Parse() error = %v; want ErrorList of 1 read error", err)
+	}
+	if len(p.Document().Children) != 1 {
+		t.Errorf("Parse() children = %d; want 1", len(p.Document().Children))
+	}
+	if err2 := p.Parse(NewLexer(strings.NewReader("b;"))); err2 == nil || err2.Error() != err.Error() {
+		t.Errorf("Parse() after stopping error = %v; want %v", err2, err)
+	}
+}
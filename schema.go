@@ -0,0 +1,436 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Schema describes the statements and sections allowed in a document. A Schema may be declared in
+// Go or parsed from a codf document with ParseSchema, and is checked against a document by
+// Validate.
+type Schema struct {
+	// Children describes the statements and sections allowed at the top level of a document.
+	Children []*Rule
+}
+
+// Rule describes a statement or section.
+type Rule struct {
+	// Name is the name of the statement or section.
+	Name string
+
+	// Section is true if the rule describes a section and false if it describes a statement.
+	Section bool
+
+	// Params describes the parameters of the statement or section, in order.
+	Params []*Param
+
+	// Required is true if the statement or section must appear at least once in its parent.
+	Required bool
+
+	// Repeated is true if the statement or section may appear more than once in its parent.
+	Repeated bool
+
+	// Children describes the statements and sections allowed in a section.
+	Children []*Rule
+}
+
+// Param describes a parameter of a statement or section.
+type Param struct {
+	// Kinds is the set of token kinds the parameter may have. Arrays and maps have the kinds
+	// TBracketOpen and TMapOpen, respectively. If Kinds is empty, the parameter may be of any kind.
+	Kinds []TokenKind
+
+	// Words, if not empty, is the set of words the parameter may be. These words are allowed
+	// whether or not Kinds includes TWord, and other words are not.
+	Words []string
+
+	// Optional is true if the parameter may be omitted. Only the last parameters of a rule may be
+	// optional.
+	Optional bool
+
+	// Variadic is true if the parameter may be repeated. Only the last parameter of a rule may be
+	// variadic.
+	Variadic bool
+}
+
+// ValidationError is an error describing a node that does not conform to a Schema.
+type ValidationError struct {
+	// Node is the node that does not conform to the schema. It is a *Document if the error is
+	// not attributable to any node in the document, such as a missing required statement.
+	Node Node
+
+	// Msg is a message describing the error.
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	if doc, ok := e.Node.(*Document); ok {
+		if doc.Name == "" {
+			return e.Msg
+		}
+		return "[" + doc.Name + "] " + e.Msg
+	}
+	return "[" + e.Node.Token().Start.String() + "] " + e.Msg
+}
+
+// Validate checks doc against schema and returns an error for every node that does not conform to
+// it. All errors returned are of type *ValidationError. If doc conforms to schema, Validate returns
+// nil.
+func Validate(doc *Document, schema *Schema) []error {
+	var v validator
+	v.children(doc, schema.Children)
+	return v.errs
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) errorf(node Node, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{Node: node, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) children(parent ParentNode, rules []*Rule) {
+	seen := map[*Rule]int{}
+	_ = eachChild(parent, func(node ParamNode) error {
+		_, isSection := node.(*Section)
+		rule := findRule(rules, node.Name())
+		if rule == nil {
+			names := make([]string, len(rules))
+			for i, r := range rules {
+				names[i] = r.Name
+			}
+			v.errs = append(v.errs, &ValidationError{Node: node, Msg: UnknownName(node, names).Error()})
+			return nil
+		}
+
+		seen[rule]++
+		if seen[rule] > 1 && !rule.Repeated {
+			v.errorf(node, "%s %q may only appear once", rule.kind(), rule.Name)
+		}
+		if isSection != rule.Section {
+			v.errorf(node, "%q must be a %s", rule.Name, rule.kind())
+			return nil
+		}
+
+		v.params(node, rule)
+		if sect, ok := node.(*Section); ok {
+			v.children(sect, rule.Children)
+		}
+		return nil
+	})
+
+	for _, rule := range rules {
+		if rule.Required && seen[rule] == 0 {
+			v.errorf(parent, "missing required %s %q", rule.kind(), rule.Name)
+		}
+	}
+}
+
+func (v *validator) params(node ParamNode, rule *Rule) {
+	params := node.Parameters()
+	min, max := rule.arity()
+	switch {
+	case len(params) < min:
+		v.errorf(node, "%s %q expects at least %s; got %d", rule.kind(), rule.Name,
+			pluralize(min, "parameter"), len(params))
+		return
+	case max >= 0 && len(params) > max:
+		v.errorf(params[max], "%s %q expects at most %s; got %d", rule.kind(), rule.Name,
+			pluralize(max, "parameter"), len(params))
+		return
+	}
+
+	for i, param := range params {
+		p := rule.Params[len(rule.Params)-1]
+		if i < len(rule.Params) {
+			p = rule.Params[i]
+		}
+		if err := p.check(param); err != "" {
+			v.errorf(param, "parameter %d of %q %s", i+1, rule.Name, err)
+		}
+	}
+}
+
+// arity returns the minimum and maximum number of parameters allowed by the rule. If there is no
+// maximum, max is -1.
+func (r *Rule) arity() (min, max int) {
+	for _, p := range r.Params {
+		if !p.Optional {
+			min++
+		}
+	}
+	if n := len(r.Params); n > 0 && r.Params[n-1].Variadic {
+		return min, -1
+	}
+	return min, len(r.Params)
+}
+
+// check returns a description of why expr does not conform to the Param, or the empty string if
+// it does.
+func (p *Param) check(expr ExprNode) string {
+	if word, ok := Word(expr); ok && len(p.Words) > 0 {
+		for _, w := range p.Words {
+			if w == word {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be %s; got %q", p.describe(), word)
+	}
+	if kind := expr.Token().Kind; !p.allows(kind) {
+		return fmt.Sprintf("must be %s; got %v", p.describe(), kind)
+	}
+	return ""
+}
+
+func (p *Param) allows(kind TokenKind) bool {
+	if len(p.Kinds) == 0 && len(p.Words) == 0 {
+		return true
+	}
+	for _, k := range p.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// describe returns the allowed kinds and words of the Param, such as "integer or word".
+func (p *Param) describe() string {
+	var alts []string
+	for _, k := range p.Kinds {
+		alts = append(alts, schemaKindName(k))
+	}
+	for _, w := range p.Words {
+		alts = append(alts, fmt.Sprintf("%q", w))
+	}
+	if len(alts) == 1 {
+		return alts[0]
+	}
+	return strings.Join(alts[:len(alts)-1], ", ") + " or " + alts[len(alts)-1]
+}
+
+func findRule(rules []*Rule, name string) *Rule {
+	for _, rule := range rules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+// kind returns "section" or "statement", depending on what the rule describes.
+func (r *Rule) kind() string {
+	if r.Section {
+		return "section"
+	}
+	return "statement"
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// schemaKinds maps the names of kinds in a schema document to token kinds.
+var schemaKinds = map[string]TokenKind{
+	"word":           TWord,
+	"string":         TString,
+	"raw string":     TRawString,
+	"heredoc":        THeredoc,
+	"bool":           TBoolean,
+	"boolean":        TBoolean,
+	"integer":        TInteger,
+	"float":          TFloat,
+	"hex integer":    THex,
+	"octal integer":  TOctal,
+	"binary integer": TBinary,
+	"base integer":   TBaseInt,
+	"duration":       TDuration,
+	"rational":       TRational,
+	"byte size":      TByteSize,
+	"date":           TDate,
+	"timestamp":      TTimestamp,
+	"period":         TPeriod,
+	"address":        TAddr,
+	"prefix":         TPrefix,
+	"address port":   TAddrPort,
+	"regexp":         TRegexp,
+	"array":          TBracketOpen,
+	"map":            TMapOpen,
+}
+
+func schemaKindName(kind TokenKind) string {
+	switch kind {
+	case TBracketOpen:
+		return "array"
+	case TMapOpen:
+		return "map"
+	}
+	return kind.String()
+}
+
+// ParseSchema reads a Schema from a document. The document declares the statements and sections
+// allowed at its top level with statements and sections of its own:
+//
+//	statement NAME;          // A statement without parameters.
+//	statement NAME { ... }   // A statement with the given parameters.
+//	section NAME { ... }     // A section with the given parameters and children.
+//
+// The bodies of these may contain the following:
+//
+//	param KIND... [WORD...] optional variadic;
+//	required;
+//	repeated;
+//
+// A param statement declares the next parameter. Each KIND is the name of an allowed kind, such as
+// word, string, integer, duration, array, or map, and kinds with spaces in their names, such as
+// "raw string", must be quoted. The bool kind may also be written boolean. If no kinds are given, or the kind is any, the parameter may be of
+// any kind. An array of words restricts the words allowed and the optional and variadic flags
+// mark the parameter as optional or variadic. Nested statement and section declarations in a
+// section declare its children. For example:
+//
+//	section server {
+//	    required;
+//	    param word string;
+//	    statement listen {
+//	        repeated;
+//	        param word string;
+//	        param integer optional;
+//	    }
+//	    statement mode { param word [fast slow]; }
+//	}
+func ParseSchema(doc *Document) (*Schema, error) {
+	rules, err := parseRules(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Children: rules}, nil
+}
+
+func parseRules(parent ParentNode) ([]*Rule, error) {
+	var rules []*Rule
+	err := eachChild(parent, func(node ParamNode) error {
+		switch node.Name() {
+		case "statement", "section":
+		case "param", "required", "repeated":
+			if _, ok := parent.(*Section); ok {
+				return nil
+			}
+			fallthrough
+		default:
+			return schemaErrorf(node, "unexpected %q: expected statement or section", node.Name())
+		}
+
+		rule, err := parseRule(node)
+		if err != nil {
+			return err
+		}
+		if findRule(rules, rule.Name) != nil {
+			return schemaErrorf(node, "duplicate rule for %q", rule.Name)
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	return rules, err
+}
+
+func parseRule(node ParamNode) (*Rule, error) {
+	params := node.Parameters()
+	if len(params) != 1 {
+		return nil, schemaErrorf(node, "%s expects a name; got %d parameters", node.Name(), len(params))
+	}
+	name, ok := String(params[0])
+	if !ok {
+		return nil, schemaErrorf(params[0], "%s name must be a word or string; got %v", node.Name(), params[0].Token().Kind)
+	}
+
+	rule := &Rule{Name: name, Section: node.Name() == "section"}
+	sect, ok := node.(*Section)
+	if !ok {
+		return rule, nil
+	}
+
+	err := eachChild(sect, func(child ParamNode) error {
+		switch child.Name() {
+		case "required", "repeated":
+			if _, ok := child.(*Statement); !ok || len(child.Parameters()) > 0 {
+				return schemaErrorf(child, "%s expects no parameters", child.Name())
+			}
+			if child.Name() == "required" {
+				rule.Required = true
+			} else {
+				rule.Repeated = true
+			}
+		case "param":
+			if n := len(rule.Params); n > 0 && rule.Params[n-1].Variadic {
+				return schemaErrorf(child, "param follows a variadic param")
+			}
+			p, err := parseParam(child)
+			if err != nil {
+				return err
+			}
+			if n := len(rule.Params); n > 0 && rule.Params[n-1].Optional && !p.Optional {
+				return schemaErrorf(child, "required param follows an optional param")
+			}
+			rule.Params = append(rule.Params, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Children, err = parseRules(sect); err != nil {
+		return nil, err
+	}
+	if !rule.Section && len(rule.Children) > 0 {
+		return nil, schemaErrorf(node, "statement %q cannot have children", rule.Name)
+	}
+	return rule, nil
+}
+
+func parseParam(node ParamNode) (*Param, error) {
+	if _, ok := node.(*Statement); !ok {
+		return nil, schemaErrorf(node, "param must be a statement")
+	}
+
+	p := &Param{}
+	for _, expr := range node.Parameters() {
+		if arr, ok := expr.(*Array); ok {
+			for _, elem := range arr.Elems {
+				word, ok := String(elem)
+				if !ok {
+					return nil, schemaErrorf(elem, "expected a word; got %v", elem.Token().Kind)
+				}
+				p.Words = append(p.Words, word)
+			}
+			continue
+		}
+
+		name, ok := String(expr)
+		if !ok {
+			return nil, schemaErrorf(expr, "expected a kind name; got %v", expr.Token().Kind)
+		}
+		switch name {
+		case "optional":
+			p.Optional = true
+		case "variadic":
+			p.Variadic = true
+		case "any":
+		default:
+			kind, ok := schemaKinds[name]
+			if !ok {
+				return nil, schemaErrorf(expr, "unknown kind %q", name)
+			}
+			p.Kinds = append(p.Kinds, kind)
+		}
+	}
+	return p, nil
+}
+
+func schemaErrorf(node Node, format string, args ...any) error {
+	return &ValidationError{Node: node, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,198 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSchemaSource = `
+statement workers { required; param integer; }
+statement mode { param [fast slow]; }
+statement tags { param word string variadic; }
+section http {
+	required;
+	section server {
+		repeated;
+		param word string;
+		statement listen {
+			repeated;
+			param word string;
+			param integer optional;
+		}
+		statement timeout { param duration integer [off]; }
+		statement enabled;
+	}
+}
+`
+
+var testSchema = &Schema{
+	Children: []*Rule{
+		{Name: "workers", Required: true, Params: []*Param{{Kinds: []TokenKind{TInteger}}}},
+		{Name: "mode", Params: []*Param{{Words: []string{"fast", "slow"}}}},
+		{Name: "tags", Params: []*Param{{Kinds: []TokenKind{TWord, TString}, Variadic: true}}},
+		{
+			Name: "http", Section: true, Required: true,
+			Children: []*Rule{
+				{
+					Name: "server", Section: true, Repeated: true,
+					Params: []*Param{{Kinds: []TokenKind{TWord, TString}}},
+					Children: []*Rule{
+						{
+							Name: "listen", Repeated: true,
+							Params: []*Param{
+								{Kinds: []TokenKind{TWord, TString}},
+								{Kinds: []TokenKind{TInteger}, Optional: true},
+							},
+						},
+						{
+							Name: "timeout",
+							Params: []*Param{{
+								Kinds: []TokenKind{TDuration, TInteger},
+								Words: []string{"off"},
+							}},
+						},
+						{Name: "enabled"},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestParseSchema(t *testing.T) {
+	schema, err := ParseSchema(mustParse(t, testSchemaSource))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(schema, testSchema) {
+		t.Errorf("ParseSchema() = %#v; want %#v", schema, testSchema)
+	}
+}
+
+func TestParseSchemaBoolean(t *testing.T) {
+	schema, err := ParseSchema(mustParse(t, "statement a { param bool; } statement b { param boolean; }"))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v; want nil", err)
+	}
+	for _, rule := range schema.Children {
+		if kinds := rule.Params[0].Kinds; !reflect.DeepEqual(kinds, []TokenKind{TBoolean}) {
+			t.Errorf("rule %q kinds = %v; want [bool]", rule.Name, kinds)
+		}
+	}
+
+	if errs := Validate(mustParse(t, "a true;\nb false;"), schema); len(errs) > 0 {
+		t.Errorf("Validate() = %v; want no errors", errs)
+	}
+
+	want := []string{
+		`[1:3:2] parameter 1 of "a" must be bool; got word`,
+		`[2:3:7] parameter 1 of "b" must be bool; got word`,
+	}
+	var got []string
+	for _, err := range Validate(mustParse(t, "a x;\nb y;"), schema) {
+		got = append(got, err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() =\n%s\nwant\n%s", joinLines(got), joinLines(want))
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	cases := []struct {
+		in  string
+		msg string
+	}{
+		{"param word;", `[1:1:0] unexpected "param": expected statement or section`},
+		{"statement;", "[1:1:0] statement expects a name; got 0 parameters"},
+		{"statement 1;", "[1:11:10] statement name must be a word or string; got integer"},
+		{"statement a; section a {}", `[1:14:13] duplicate rule for "a"`},
+		{"statement a { param number; }", `[1:21:20] unknown kind "number"`},
+		{"statement a { param word optional; param word; }", "[1:36:35] required param follows an optional param"},
+		{"statement a { param word variadic; param word; }", "[1:36:35] param follows a variadic param"},
+		{"statement a { required 1; }", "[1:15:14] required expects no parameters"},
+		{"statement a { statement b; }", `[1:1:0] statement "a" cannot have children`},
+		{"section a { foo; }", `[1:13:12] unexpected "foo": expected statement or section`},
+		{"section a { param [1]; }", "[1:20:19] expected a word; got integer"},
+	}
+
+	for _, c := range cases {
+		_, err := ParseSchema(mustParse(t, c.in))
+		if err == nil || err.Error() != c.msg {
+			t.Errorf("ParseSchema(%q) error = %v; want %q", c.in, err, c.msg)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := `
+workers 4;
+mode fast;
+tags a "b c" d;
+http {
+	server example.com {
+		listen localhost 8080;
+		listen "[::1]";
+		timeout off;
+		enabled;
+	}
+	server example.org { timeout 5s; }
+}
+`
+	if errs := Validate(mustParse(t, valid), testSchema); len(errs) > 0 {
+		t.Errorf("Validate() = %v; want no errors", errs)
+	}
+
+	invalid := `mode medium;
+tags;
+tags 1;
+http {
+	server { listen; }
+	server a b;
+	server c {
+		listen a 1 2;
+		listen a b;
+		timeout 5s;
+		timeout 1.5;
+		enabled {}
+		unknown;
+	}
+}
+http {}
+`
+	want := []string{
+		`[1:6:5] parameter 1 of "mode" must be "fast" or "slow"; got "medium"`,
+		`[2:1:13] statement "tags" expects at least 1 parameter; got 0`,
+		`[3:1:19] statement "tags" may only appear once`,
+		`[3:6:24] parameter 1 of "tags" must be word or string; got integer`,
+		`[5:2:35] section "server" expects at least 1 parameter; got 0`,
+		`[5:11:44] statement "listen" expects at least 1 parameter; got 0`,
+		`[6:2:55] "server" must be a section`,
+		`[8:14:92] statement "listen" expects at most 2 parameters; got 3`,
+		`[9:12:106] parameter 2 of "listen" must be integer; got word`,
+		`[11:3:125] statement "timeout" may only appear once`,
+		`[11:11:133] parameter 1 of "timeout" must be duration, integer or "off"; got float`,
+		`[12:3:140] "enabled" must be a statement`,
+		`[13:3:153] unknown statement "unknown"`,
+		`[16:1:167] section "http" may only appear once`,
+		`missing required statement "workers"`,
+	}
+
+	var got []string
+	for _, err := range Validate(mustParse(t, invalid), testSchema) {
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("Validate() error %v is %T; want *ValidationError", err, err)
+		}
+		got = append(got, err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() =\n%s\nwant\n%s", joinLines(got), joinLines(want))
+	}
+}
+
+func joinLines(lines []string) string {
+	s := ""
+	for _, line := range lines {
+		s += "\t" + line + "\n"
+	}
+	return s
+}
@@ -0,0 +1,123 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSuggestions is the largest number of names suggested by an UnknownNameError.
+const maxSuggestions = 3
+
+// UnknownNameError is an error for a statement or section whose name is not one of the names
+// accepted where it occurs. It suggests the accepted names closest to the node's name, if any are
+// close enough to be likely misspellings of it.
+type UnknownNameError struct {
+	// Node is the statement or section with the unknown name.
+	Node ParamNode
+
+	// Suggestions holds the accepted names closest to the node's name, closest first.
+	Suggestions []string
+}
+
+// UnknownName returns an *UnknownNameError for node, suggesting the names in accepted closest to
+// the name of node. It is intended for use in Walkers and other code that rejects unknown names:
+//
+//	func (w *walker) Statement(stmt *codf.Statement) error {
+//		switch stmt.Name() {
+//		case "access_log", "error_log":
+//			return nil
+//		}
+//		return codf.UnknownName(stmt, []string{"access_log", "error_log"})
+//	}
+func UnknownName(node ParamNode, accepted []string) *UnknownNameError {
+	return &UnknownNameError{Node: node, Suggestions: Suggest(node.Name(), accepted)}
+}
+
+func (e *UnknownNameError) Error() string {
+	msg := "unknown " + nodeKind(e.Node) + " " + strconv.Quote(e.Node.Name())
+	switch n := len(e.Suggestions); n {
+	case 0:
+		return msg
+	case 1:
+		return msg + "; did you mean " + strconv.Quote(e.Suggestions[0]) + "?"
+	default:
+		quoted := make([]string, n)
+		for i, s := range e.Suggestions {
+			quoted[i] = strconv.Quote(s)
+		}
+		sep := ", "
+		if n == 2 {
+			sep = " "
+		}
+		return msg + "; did you mean " + strings.Join(quoted[:n-1], sep) + sep + "or " + quoted[n-1] + "?"
+	}
+}
+
+// Suggest returns up to three names from candidates that are close to name, closest first. A
+// candidate is close if its edit distance from name, ignoring case, is no more than a third of
+// the length of name, rounded up (and at least one). For example, names of four to six
+// characters may be two edits away. The edit distance counts the insertions, deletions,
+// substitutions, and transpositions of adjacent characters needed to turn one name into the
+// other. Candidates equal to name are not suggested.
+func Suggest(name string, candidates []string) []string {
+	limit := max(1, (len([]rune(name))+2)/3)
+	type match struct {
+		name string
+		dist int
+	}
+	var matches []match
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if c == name || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if d := editDistance(strings.ToLower(name), strings.ToLower(c)); d <= limit {
+			matches = append(matches, match{c, d})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].dist < matches[j].dist
+	})
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.name
+	}
+	return names
+}
+
+// editDistance returns the optimal string alignment distance between a and b: the number of
+// rune insertions, deletions, substitutions, and adjacent transpositions needed to turn a into b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// d[i][j] is the distance between s[:i] and t[:j].
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
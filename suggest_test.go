@@ -0,0 +1,160 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"access_log", "access_log", 0},
+		{"acces_log", "access_log", 1},
+		{"access_log", "acess_log", 1},
+		{"error_log", "eror_log", 1},
+		{"listen", "lsiten", 1},
+		{"server", "sever", 1},
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3},
+		{"héllo", "hello", 1},
+	}
+
+	for _, c := range cases {
+		if got := editDistance(c.a, c.b); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d; want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	names := []string{"access_log", "error_log", "listen", "server_name", "server", "user"}
+	cases := []struct {
+		name string
+		want []string
+	}{
+		{"acces_log", []string{"access_log"}},
+		{"ACCESS_LOG", []string{"access_log"}},
+		{"lsiten", []string{"listen"}},
+		{"servr", []string{"server"}},
+		{"server_nam", []string{"server_name", "server"}},
+		{"usr", []string{"user"}},
+		{"access_log", nil},
+		{"proxy_pass", nil},
+		{"x", nil},
+	}
+
+	for _, c := range cases {
+		if got := Suggest(c.name, names); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Suggest(%q) = %q; want %q", c.name, got, c.want)
+		}
+	}
+
+	t.Run("Order", func(t *testing.T) {
+		got := Suggest("log", []string{"logs", "dog", "log2", "lg", "blog", "fog"})
+		want := []string{"logs", "dog", "log2"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Suggest() = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		got := Suggest("lisen", []string{"listen", "listen"})
+		want := []string{"listen"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Suggest() = %q; want %q", got, want)
+		}
+	})
+}
+
+func TestUnknownNameError(t *testing.T) {
+	doc := mustParse(t, "lg; blog {}")
+	stmt, sect := doc.Children[0].(ParamNode), doc.Children[1].(ParamNode)
+
+	cases := []struct {
+		name     string
+		node     ParamNode
+		accepted []string
+		want     string
+	}{
+		{"None", stmt, []string{"access_log"}, `unknown statement "lg"`},
+		{"One", stmt, []string{"log", "access_log"}, `unknown statement "lg"; did you mean "log"?`},
+		{"Two", stmt, []string{"log", "lag"}, `unknown statement "lg"; did you mean "log" or "lag"?`},
+		{"Three", sect, []string{"blob", "log", "blogs", "bog"},
+			`unknown section "blog"; did you mean "blob", "log", or "blogs"?`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := UnknownName(c.node, c.accepted).Error(); got != c.want {
+				t.Errorf("Error() = %q; want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// nameWalker is a Walker that accepts sections of any name and statements with the names it holds.
+type nameWalker []string
+
+func (w nameWalker) Statement(stmt *Statement) error {
+	for _, name := range w {
+		if stmt.Name() == name {
+			return nil
+		}
+	}
+	return UnknownName(stmt, w)
+}
+
+func (w nameWalker) EnterSection(*Section) (Walker, error) { return w, nil }
+
+func TestUnknownNameWalker(t *testing.T) {
+	doc := mustParse(t, "server {\n  acces_log off;\n}")
+	err := Walk(doc, nameWalker{"access_log", "error_log"})
+
+	var une *UnknownNameError
+	if !errors.As(err, &une) {
+		t.Fatalf("Walk() = %v; want *UnknownNameError", err)
+	}
+	want := []string{"access_log"}
+	if !reflect.DeepEqual(une.Suggestions, want) {
+		t.Errorf("Suggestions = %q; want %q", une.Suggestions, want)
+	}
+
+	diag, ok := DiagnosticOf(une)
+	if !ok {
+		t.Fatal("DiagnosticOf() ok = false; want true")
+	}
+	if msg := `unknown statement "acces_log"; did you mean "access_log"?`; diag.Msg != msg {
+		t.Errorf("Diagnostic.Msg = %q; want %q", diag.Msg, msg)
+	}
+	if loc := "2:3:11"; diag.Start.String() != loc {
+		t.Errorf("Diagnostic.Start = %v; want %v", diag.Start, loc)
+	}
+}
+
+func TestValidateSuggestions(t *testing.T) {
+	schema := &Schema{Children: []*Rule{
+		{Name: "access_log", Repeated: true},
+		{Name: "error_log"},
+		{Name: "server", Section: true},
+	}}
+	doc := mustParse(t, "acces_log off;\nservers {}\nmisc;")
+	want := []string{
+		`[1:1:0] unknown statement "acces_log"; did you mean "access_log"?`,
+		`[2:1:15] unknown section "servers"; did you mean "server"?`,
+		`[3:1:26] unknown statement "misc"`,
+	}
+
+	var got []string
+	for _, err := range Validate(doc, schema) {
+		got = append(got, err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() =\n%s\nwant\n%s", joinLines(got), joinLines(want))
+	}
+}
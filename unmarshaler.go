@@ -0,0 +1,135 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"encoding"
+	"reflect"
+)
+
+// StatementUnmarshaler is implemented by types that can decode themselves from a statement.
+//
+// When decoding a statement into a value whose type (or a pointer to it) implements
+// StatementUnmarshaler, Unmarshal calls UnmarshalCodfStatement with the statement instead of
+// converting its parameters.
+type StatementUnmarshaler interface {
+	UnmarshalCodfStatement(*Statement) error
+}
+
+// SectionUnmarshaler is implemented by types that can decode themselves from a section.
+//
+// When decoding a section into a value whose type (or a pointer to it) implements
+// SectionUnmarshaler, Unmarshal calls UnmarshalCodfSection with the section instead of decoding its
+// parameters and children.
+type SectionUnmarshaler interface {
+	UnmarshalCodfSection(*Section) error
+}
+
+// ExprUnmarshaler is implemented by types that can decode themselves from an ExprNode, such as
+// a statement's parameter or an element of an array or map.
+//
+// When decoding an ExprNode into a value whose type (or a pointer to it) implements ExprUnmarshaler,
+// Unmarshal calls UnmarshalCodfExpr with the node instead of converting its value. A statement
+// decoded into such a type must have exactly one parameter, which is passed to UnmarshalCodfExpr.
+//
+// Types that implement neither ExprUnmarshaler nor one of the built-in conversions may instead
+// implement encoding.TextUnmarshaler to decode string and word values.
+type ExprUnmarshaler interface {
+	UnmarshalCodfExpr(ExprNode) error
+}
+
+var (
+	statementUnmarshalerType = reflect.TypeOf((*StatementUnmarshaler)(nil)).Elem()
+	sectionUnmarshalerType   = reflect.TypeOf((*SectionUnmarshaler)(nil)).Elem()
+	exprUnmarshalerType      = reflect.TypeOf((*ExprUnmarshaler)(nil)).Elem()
+	textUnmarshalerType      = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// implements returns true if t, a pointer to t, or a type t points to implements iface.
+func implements(t, iface reflect.Type) bool {
+	for {
+		if t.Implements(iface) || reflect.PointerTo(t).Implements(iface) {
+			return true
+		} else if t.Kind() != reflect.Pointer {
+			return false
+		}
+		t = t.Elem()
+	}
+}
+
+// unmarshaler returns v, or a pointer to v, as an implementation of iface. Nil pointers are
+// allocated as needed to reach a value implementing iface. If v does not implement iface, it
+// returns nil.
+func unmarshaler(v reflect.Value, iface reflect.Type) any {
+	if !implements(v.Type(), iface) {
+		return nil
+	}
+	for {
+		if v.Kind() == reflect.Pointer && v.Type().Implements(iface) {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			return v.Interface()
+		} else if v.CanAddr() && v.Addr().Type().Implements(iface) {
+			return v.Addr().Interface()
+		} else if v.Kind() != reflect.Pointer {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+}
+
+// isExprType returns true if a statement decoded into t is decoded from its single parameter.
+func isExprType(t reflect.Type) bool {
+	return isLiteralType(t) ||
+		implements(t, exprUnmarshalerType) ||
+		implements(t, textUnmarshalerType)
+}
+
+// decodeUnmarshaler calls the StatementUnmarshaler or SectionUnmarshaler implemented by v, if any,
+// for node. It returns false if v does not implement the unmarshaler for node's type.
+func (d *decodeState) decodeUnmarshaler(node ParamNode, v reflect.Value) (bool, error) {
+	var err error
+	switch node := node.(type) {
+	case *Statement:
+		u, ok := unmarshaler(v, statementUnmarshalerType).(StatementUnmarshaler)
+		if !ok {
+			return false, nil
+		}
+		err = u.UnmarshalCodfStatement(node)
+	case *Section:
+		u, ok := unmarshaler(v, sectionUnmarshalerType).(SectionUnmarshaler)
+		if !ok {
+			return false, nil
+		}
+		err = u.UnmarshalCodfSection(node)
+	default:
+		return false, nil
+	}
+	return true, d.error(node, v.Type(), err)
+}
+
+// decodeExprUnmarshaler calls the ExprUnmarshaler implemented by v, if any, for expr. If v does not
+// implement ExprUnmarshaler but does implement encoding.TextUnmarshaler, and expr is a string, it
+// calls UnmarshalText with the string instead. It returns false if neither is implemented.
+func (d *decodeState) decodeExprUnmarshaler(expr ExprNode, v reflect.Value) (bool, error) {
+	if u, ok := unmarshaler(v, exprUnmarshalerType).(ExprUnmarshaler); ok {
+		return true, d.error(expr, v.Type(), u.UnmarshalCodfExpr(expr))
+	}
+
+	switch v.Type() {
+	case bigIntType, bigFloatType, bigRatType, regexpType:
+		// These implement TextUnmarshaler but are converted from literals.
+		return false, nil
+	}
+
+	str, ok := String(expr)
+	if !ok {
+		return false, nil
+	}
+	if u, ok := unmarshaler(v, textUnmarshalerType).(encoding.TextUnmarshaler); ok {
+		return true, d.error(expr, v.Type(), u.UnmarshalText([]byte(str)))
+	}
+	return false, nil
+}
@@ -0,0 +1,138 @@
+package codf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type listenAddr struct {
+	Addr  string
+	Flags []string
+}
+
+func (l *listenAddr) UnmarshalCodfStatement(stmt *Statement) error {
+	if len(stmt.Params) == 0 {
+		return errors.New("listen requires an address")
+	}
+	addr, ok := String(stmt.Params[0])
+	if !ok {
+		return fmt.Errorf("invalid listen address: %v", stmt.Params[0])
+	}
+	l.Addr = addr
+	for _, p := range stmt.Params[1:] {
+		flag, _ := Word(p)
+		l.Flags = append(l.Flags, flag)
+	}
+	return nil
+}
+
+type retryPolicy struct {
+	Attempts int
+	Backoff  []time.Duration
+}
+
+func (r *retryPolicy) UnmarshalCodfSection(sect *Section) error {
+	if len(sect.Params) != 1 {
+		return errors.New("retry requires an attempt count")
+	}
+	n, ok := Int64(sect.Params[0])
+	if !ok {
+		return errors.New("retry attempts must be an integer")
+	}
+	r.Attempts = int(n)
+	return Walk(sect, r)
+}
+
+func (r *retryPolicy) Statement(stmt *Statement) error {
+	for _, p := range stmt.Params {
+		d, ok := Duration(p)
+		if !ok {
+			return errors.New("backoff must be a duration")
+		}
+		r.Backoff = append(r.Backoff, d)
+	}
+	return nil
+}
+
+func (r *retryPolicy) EnterSection(*Section) (Walker, error) {
+	return nil, errors.New("unexpected section")
+}
+
+type logLevel int
+
+func (l *logLevel) UnmarshalCodfExpr(expr ExprNode) error {
+	switch s, _ := String(expr); s {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("invalid log level %q", s)
+	}
+	return nil
+}
+
+func TestUnmarshalCustom(t *testing.T) {
+	const src = `
+	listen 0.0.0.0:80 http2 ssl;
+	listen 0.0.0.0:8080;
+	retry 3 { backoff 1s 5s; }
+	level info;
+	levels [debug info];
+	allow 10.0.0.1;
+	`
+
+	var got struct {
+		Listen []listenAddr
+		Retry  *retryPolicy
+		Level  logLevel
+		Levels []logLevel
+		Allow  netip.Addr
+	}
+	if err := Unmarshal(mustParse(t, src), &got); err != nil {
+		t.Fatalf("Unmarshal() = %v; want nil", err)
+	}
+
+	wantListen := []listenAddr{
+		{Addr: "0.0.0.0:80", Flags: []string{"http2", "ssl"}},
+		{Addr: "0.0.0.0:8080"},
+	}
+	if !reflect.DeepEqual(got.Listen, wantListen) {
+		t.Errorf("Listen = %#v; want %#v", got.Listen, wantListen)
+	}
+
+	wantRetry := &retryPolicy{Attempts: 3, Backoff: []time.Duration{time.Second, 5 * time.Second}}
+	if !reflect.DeepEqual(got.Retry, wantRetry) {
+		t.Errorf("Retry = %#v; want %#v", got.Retry, wantRetry)
+	}
+
+	if got.Level != 1 {
+		t.Errorf("Level = %d; want 1", got.Level)
+	}
+	if want := []logLevel{0, 1}; !reflect.DeepEqual(got.Levels, want) {
+		t.Errorf("Levels = %v; want %v", got.Levels, want)
+	}
+	if want := netip.MustParseAddr("10.0.0.1"); got.Allow != want {
+		t.Errorf("Allow = %v; want %v", got.Allow, want)
+	}
+}
+
+func TestUnmarshalCustomError(t *testing.T) {
+	var got struct {
+		Level logLevel
+	}
+	err := Unmarshal(mustParse(t, "\nlevel trace;"), &got)
+
+	const want = `[2:7:7] invalid log level "trace"`
+	if err == nil || err.Error() != want {
+		t.Fatalf("Unmarshal() = %v; want %s", err, want)
+	}
+	var ue *UnmarshalError
+	if !errors.As(err, &ue) {
+		t.Fatalf("Unmarshal() = %T; want *UnmarshalError", err)
+	}
+}
@@ -0,0 +1,322 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultSetName is the name of the statements that define variables for an Expander if its Name
+// is empty.
+const DefaultSetName = "set"
+
+// Expander defines and substitutes variables in a Document. A variable is defined by a statement
+// such as
+//
+//	set $root /srv/app;
+//
+// and referenced as $root or ${root} in barewords and double-quoted strings that follow it,
+// including those in the parameters of sections, in arrays, and in map values. Raw strings are
+// never expanded. To write a literal '$' in a word or string, write "$$".
+//
+// Variables are scoped to the section they are defined in: a definition is visible to the
+// statements that follow it in its section and in sections nested within it, and a definition in
+// a nested section shadows one of the same name in its parent until the end of the nested section.
+// Documents nested in other documents, such as those added by an Includer, share the scope of
+// their parent.
+//
+// Definitions are removed from the Document once expanded. A word that is expanded is lexed
+// again, so that a word such as $port, where port is defined as 8080, becomes an integer. If the
+// expanded word is not a single literal, it becomes a string.
+type Expander struct {
+	// Name is the name of variable definitions. If empty, it defaults to DefaultSetName.
+	Name string
+
+	// Vars holds variables that are defined before the Document is expanded. Variables defined by
+	// the Document shadow these.
+	Vars map[string]string
+
+	// Lookup, if not nil, is called to look up variables that are not defined by the Document or
+	// Vars. For example, setting Lookup to os.LookupEnv allows variables to be taken from the
+	// process environment.
+	Lookup func(name string) (string, bool)
+
+	// LexerFlags are the flags used to lex expanded words.
+	LexerFlags LexerFlag
+}
+
+// VarError is returned by an Expander when a variable cannot be defined or expanded.
+type VarError struct {
+	// Tok is the token that defines or references the variable.
+	Tok Token
+	// Name is the name of the variable. It may be empty if the name could not be read.
+	Name string
+	// Err is the error that occurred.
+	Err error
+}
+
+func (e *VarError) Error() string {
+	if e.Name == "" {
+		return "[" + e.Tok.Start.String() + "] " + e.Err.Error()
+	}
+	return "[" + e.Tok.Start.String() + "] $" + e.Name + ": " + e.Err.Error()
+}
+
+func (e *VarError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUndefinedVar is returned, wrapped in a VarError, when a variable is referenced but not defined.
+var ErrUndefinedVar = errors.New("undefined variable")
+
+// varScope holds the variables defined by a section and refers to the scope of its parent.
+type varScope struct {
+	vars   map[string]string
+	parent *varScope
+}
+
+// Expand defines and expands the variables in doc. Expand stops at the first error.
+func (e *Expander) Expand(doc *Document) error {
+	return e.expand(doc, &varScope{vars: map[string]string{}})
+}
+
+func (e *Expander) expand(parent ParentNode, scope *varScope) error {
+	children := make([]Node, 0, len(parent.Nodes()))
+	for _, child := range parent.Nodes() {
+		switch node := child.(type) {
+		case *Document:
+			if err := e.expand(node, scope); err != nil {
+				return err
+			}
+		case *Statement:
+			if node.Name() == e.name() {
+				if err := e.define(node, scope); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.expandParams(node.Params, scope); err != nil {
+				return err
+			}
+		case *Section:
+			if err := e.expandParams(node.Params, scope); err != nil {
+				return err
+			}
+			inner := &varScope{vars: map[string]string{}, parent: scope}
+			if err := e.expand(node, inner); err != nil {
+				return err
+			}
+		}
+		children = append(children, child)
+	}
+
+	switch parent := parent.(type) {
+	case *Document:
+		parent.Children = children
+	case *Section:
+		parent.Children = children
+	}
+	return nil
+}
+
+// define adds the variable defined by stmt to scope.
+func (e *Expander) define(stmt *Statement, scope *varScope) error {
+	if len(stmt.Params) != 2 {
+		return &VarError{Tok: stmt.Token(), Err: fmt.Errorf("expected a variable and a value; got %d parameters", len(stmt.Params))}
+	}
+
+	ref, ok := stmt.Params[0].(*Literal)
+	if !ok || ref.Tok.Kind != TWord {
+		return &VarError{Tok: stmt.Params[0].Token(), Err: errors.New("expected a variable name")}
+	}
+	raw := ref.Tok.Value.(string)
+	name := strings.TrimPrefix(raw, "$")
+	if strings.HasPrefix(name, "{") && strings.HasSuffix(name, "}") {
+		name = name[1 : len(name)-1]
+	}
+	if name == raw || !isVarName(name) {
+		return &VarError{Tok: ref.Tok, Err: fmt.Errorf("invalid variable name %q", raw)}
+	}
+
+	if err := e.expandParams(stmt.Params[1:], scope); err != nil {
+		return err
+	}
+	val, ok := stmt.Params[1].(*Literal)
+	if !ok {
+		return &VarError{Tok: stmt.Params[1].Token(), Name: name, Err: fmt.Errorf("expected a literal value; got %v", stmt.Params[1].Token().Kind)}
+	}
+	if str, ok := Quote(val); ok {
+		scope.vars[name] = str
+	} else {
+		scope.vars[name] = string(val.Tok.Raw)
+	}
+	return nil
+}
+
+// expandParams expands the variables in exprs in place.
+func (e *Expander) expandParams(exprs []ExprNode, scope *varScope) error {
+	for i, expr := range exprs {
+		expanded, err := e.expandExpr(expr, scope)
+		if err != nil {
+			return err
+		}
+		exprs[i] = expanded
+	}
+	return nil
+}
+
+func (e *Expander) expandExpr(expr ExprNode, scope *varScope) (ExprNode, error) {
+	switch expr := expr.(type) {
+	case *Array:
+		return expr, e.expandParams(expr.Elems, scope)
+	case *Map:
+		for _, ent := range expr.Elems {
+			val, err := e.expandExpr(ent.Val, scope)
+			if err != nil {
+				return nil, err
+			}
+			ent.Val = val
+		}
+		return expr, nil
+	case *Literal:
+		return e.expandLiteral(expr, scope)
+	}
+	return expr, nil
+}
+
+func (e *Expander) expandLiteral(lit *Literal, scope *varScope) (*Literal, error) {
+	if lit.Tok.Kind != TWord && lit.Tok.Kind != TString {
+		return lit, nil
+	}
+	text := lit.Tok.Value.(string)
+	if !strings.Contains(text, "$") {
+		return lit, nil
+	}
+	expanded, err := e.interpolate(lit.Tok, text, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var out *Literal
+	if lit.Tok.Kind == TWord {
+		out = e.relex(expanded)
+	}
+	if out == nil {
+		out = NewString(expanded)
+	}
+	out.Tok.Start, out.Tok.End = lit.Tok.Start, lit.Tok.End
+	return out, nil
+}
+
+// relex returns the literal that text is lexed as, or nil if text is not a single literal.
+func (e *Expander) relex(text string) *Literal {
+	lex := NewLexer(strings.NewReader(text))
+	lex.Flags = e.LexerFlags
+	tok, err := lex.ReadToken()
+	if err != nil {
+		return nil
+	}
+	switch tok.Kind {
+	case TWhitespace, TComment, TEOF, TSemicolon, TCurlOpen, TCurlClose,
+		TBracketOpen, TBracketClose, TMapOpen:
+		return nil
+	}
+	if next, err := lex.ReadToken(); err != nil || next.Kind != TEOF {
+		return nil
+	}
+	return &Literal{Tok: tok}
+}
+
+// interpolate replaces the variable references in text, read from tok, with their values.
+func (e *Expander) interpolate(tok Token, text string, scope *varScope) (string, error) {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(text, '$')
+		if i < 0 {
+			sb.WriteString(text)
+			return sb.String(), nil
+		}
+		sb.WriteString(text[:i])
+		text = text[i+1:]
+
+		var name string
+		switch {
+		case strings.HasPrefix(text, "$"):
+			sb.WriteByte('$')
+			text = text[1:]
+			continue
+		case strings.HasPrefix(text, "{"):
+			end := strings.IndexByte(text, '}')
+			if end < 0 {
+				return "", &VarError{Tok: tok, Err: errors.New("unterminated variable reference: expected '}'")}
+			}
+			name, text = text[1:end], text[end+1:]
+			if !isVarName(name) {
+				return "", &VarError{Tok: tok, Err: fmt.Errorf("invalid variable name %q", name)}
+			}
+		default:
+			n := 0
+			for n < len(text) {
+				r, size := utf8.DecodeRuneInString(text[n:])
+				if !isVarRune(r, n == 0) {
+					break
+				}
+				n += size
+			}
+			if n == 0 {
+				// A '$' that does not begin a reference is kept as-is.
+				sb.WriteByte('$')
+				continue
+			}
+			name, text = text[:n], text[n:]
+		}
+
+		val, ok := e.lookup(name, scope)
+		if !ok {
+			return "", &VarError{Tok: tok, Name: name, Err: ErrUndefinedVar}
+		}
+		sb.WriteString(val)
+	}
+}
+
+func (e *Expander) lookup(name string, scope *varScope) (string, bool) {
+	for ; scope != nil; scope = scope.parent {
+		if val, ok := scope.vars[name]; ok {
+			return val, true
+		}
+	}
+	if val, ok := e.Vars[name]; ok {
+		return val, true
+	}
+	if e.Lookup != nil {
+		return e.Lookup(name)
+	}
+	return "", false
+}
+
+func (e *Expander) name() string {
+	if e.Name == "" {
+		return DefaultSetName
+	}
+	return e.Name
+}
+
+// isVarName returns true if name is a valid variable name: a letter or underscore followed by
+// letters, digits, and underscores.
+func isVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !isVarRune(r, i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isVarRune(r rune, initial bool) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+		!initial && r >= '0' && r <= '9'
+}
@@ -0,0 +1,103 @@
+package codf // import "go.spiff.io/codf"
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpander(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"Word", "set $root /srv/app; root $root/www;", "root /srv/app/www;"},
+		{"Braces", "set $name app; root /srv/${name}1;", "root /srv/app1;"},
+		{"String", `set $host example.com; url "https://$host/${host}";`, `url "https://example.com/example.com";`},
+		{"RawString", "set $host example.com; url `$host`;", "url `$host`;"},
+		{"StringValue", `set $greeting "hello world"; say $greeting;`, `say "hello world";`},
+		{"Relex", "set $port 8080; set $on yes; listen $port $on; port ${port}0;", "listen 8080 yes;\nport 80800;"},
+		{"Escape", `set $x 1; cost $$x "$$${x}";`, `cost $x "$1";`},
+		{"Dollar", `cost 5$ US$ "$ 1";`, `cost 5$ US$ "$ 1";`},
+		{"DefineFromVar", "set $a x; set $b ${a}y; v $b;", "v xy;"},
+		{"Redefine", "set $a 1; v $a; set $a 2; v $a;", "v 1;\nv 2;"},
+		{"Compound", "set $a 1; v [$a #{k $a}];", "v [1 #{\n\t\tk 1\n\t}];"},
+		{
+			"Scoping",
+			"set $a outer; set $b outer;\nserver $a { set $a inner; v $a $b; sub { v $a; } }\nv $a;",
+			"server outer {\n\tv inner outer;\n\tsub {\n\t\tv inner;\n\t}\n}\nv outer;",
+		},
+		{"Vars", "v $seeded $env;", "v seed env-value;"},
+		{"ShadowVars", "set $seeded doc; v $seeded;", "v doc;"},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			doc := mustParse(t, c.in)
+			e := &Expander{
+				Vars: map[string]string{"seeded": "seed"},
+				Lookup: func(name string) (string, bool) {
+					if name == "env" {
+						return "env-value", true
+					}
+					return "", false
+				},
+			}
+			if err := e.Expand(doc); err != nil {
+				t.Fatalf("Expand() error = %v; want nil", err)
+			}
+			if got := doc.String(); got != c.want {
+				t.Errorf("String() =\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestExpanderLocations(t *testing.T) {
+	doc := mustParse(t, "set $a 1;\nv  $a;")
+	if err := (&Expander{}).Expand(doc); err != nil {
+		t.Fatalf("Expand() error = %v; want nil", err)
+	}
+	tok := doc.Children[0].(*Statement).Params[0].Token()
+	if got, want := tok.Start.String(), "2:4:13"; got != want {
+		t.Errorf("Start = %s; want %s", got, want)
+	}
+	if got, want := string(tok.Raw), "1"; got != want {
+		t.Errorf("Raw = %q; want %q", got, want)
+	}
+	if got, want := tok.Kind, TInteger; got != want {
+		t.Errorf("Kind = %v; want %v", got, want)
+	}
+}
+
+func TestExpanderErrors(t *testing.T) {
+	cases := []struct {
+		in  string
+		msg string
+	}{
+		{"v $a;", "[1:3:2] $a: undefined variable"},
+		{"v $a; set $a 1;", "[1:3:2] $a: undefined variable"},
+		{"a { set $x 1; } v \"${x}\";", "[1:19:18] $x: undefined variable"},
+		{"v ${a;", "[1:3:2] unterminated variable reference: expected '}'"},
+		{"v ${1};", `[1:3:2] invalid variable name "1"`},
+		{"set $a;", "[1:1:0] expected a variable and a value; got 1 parameters"},
+		{"set a 1;", `[1:5:4] invalid variable name "a"`},
+		{"set \"$a\" 1;", "[1:5:4] expected a variable name"},
+		{"set $a [1];", "[1:8:7] $a: expected a literal value; got open bracket"},
+		{"set $a $b;", "[1:8:7] $b: undefined variable"},
+	}
+
+	for _, c := range cases {
+		doc := mustParse(t, c.in)
+		err := (&Expander{}).Expand(doc)
+		var ve *VarError
+		if !errors.As(err, &ve) {
+			t.Errorf("Expand(%q) error = %v; want *VarError", c.in, err)
+			continue
+		}
+		if err.Error() != c.msg {
+			t.Errorf("Expand(%q) error = %q; want %q", c.in, err, c.msg)
+		}
+	}
+}
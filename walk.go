@@ -43,7 +43,8 @@ type WalkExiter interface {
 // and the returned Walker.
 //
 // Walk will return a *WalkError if any error occurs during a walk. The WalkError will contain both
-// the parent and child node that the error occurred for.
+// the parent and child node that the error occurred for, and its Path method returns the path of
+// the child node relative to parent.
 //
 // If the walker is a WalkMapper, any error in attempting to map a node will return a WalkError with
 // the original node, not any resulting node. If the mapping is to a nil node without error, the
@@ -51,7 +52,11 @@ type WalkExiter interface {
 //
 // Nil child nodes are skipped.
 func Walk(parent ParentNode, walker Walker) (err error) {
-	return walkInContext(parent, parent, walker)
+	err = walkInContext(parent, parent, walker)
+	if we, ok := err.(*WalkError); ok && we.Root == nil {
+		we.Root = parent
+	}
+	return err
 }
 
 func walkInContext(context, parent ParentNode, walker Walker) (err error) {
@@ -145,6 +150,18 @@ type WalkError struct {
 
 	// Err is the error that a Walker returned.
 	Err error
+
+	// Root is the ParentNode that Walk was called with.
+	Root ParentNode
+}
+
+// Path returns the path of Node relative to Root. It returns nil if Root is nil or Node is not
+// a descendant of Root, such as when Node was removed by a WalkMapper.
+func (e *WalkError) Path() NodePath {
+	if e.Root == nil {
+		return nil
+	}
+	return Path(e.Root, e.Node)
 }
 
 func walkErr(owner ParentNode, ctx ParentNode, node Node, err error) *WalkError {
@@ -173,6 +190,11 @@ func (e *WalkError) Error() string {
 	return prefix + suffix
 }
 
+// Unwrap returns the error that the Walker returned.
+func (e *WalkError) Unwrap() error {
+	return e.Err
+}
+
 func contextName(node Node) string {
 	switch node := node.(type) {
 	case *Document: